@@ -91,8 +91,8 @@ func (p Pos) String() string {
 	return fmt.Sprintf("(%d, %d)", p.X, p.Y)
 }
 
-func RandomPos(maxX, maxY int) Pos {
-	return Pos{rand.IntN(maxX), rand.IntN(maxY)}
+func RandomPos(rng *rand.Rand, maxX, maxY int) Pos {
+	return Pos{rng.IntN(maxX), rng.IntN(maxY)}
 }
 
 func (p Pos) Add(p2 Pos) Pos {
@@ -198,6 +198,18 @@ const (
 
 var Directions = []Direction{DirNorth, DirSouth, DirEast, DirWest}
 
+// DirectionTo returns the direction to a neighbouring position, false if it's not a cardinal neighbour
+func (p Pos) DirectionTo(p2 Pos) (Direction, bool) {
+	delta := p2.Sub(p)
+	for _, d := range Directions {
+		if d.Pos() == delta {
+			return d, true
+		}
+	}
+
+	return -1, false
+}
+
 func (d Direction) Pos() Pos {
 	switch d {
 	case DirNorth:
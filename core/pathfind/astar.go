@@ -0,0 +1,90 @@
+package pathfind
+
+// ====================================================================================================================
+// A* search for the shortest path between two points
+// ====================================================================================================================
+
+import (
+	"roguelike/core"
+)
+
+// AStar finds the cheapest path from start to goal, the path excludes the start but includes the goal
+// The goal is always treated as enterable, so paths can be found to blocked things like creatures
+// Returns nil if there is no path
+func (g *Grid) AStar(start, goal core.Pos) []core.Pos {
+	if !g.inBounds(start) || !g.inBounds(goal) || start == goal {
+		return nil
+	}
+
+	area := g.Size.Area()
+	costSoFar := make([]int, area)
+	cameFrom := make([]int, area)
+	for i := range costSoFar {
+		costSoFar[i] = Unreachable
+		cameFrom[i] = -1
+	}
+
+	open := &priorityQueue{}
+	costSoFar[g.index(start)] = 0
+	open.push(start, g.heuristic(start, goal))
+
+	for open.Len() > 0 {
+		item := open.pop()
+		current := item.pos
+		if current == goal {
+			return g.buildPath(cameFrom, start, goal)
+		}
+
+		// Skip stale queue entries, a cheaper route has already been found
+		currentCost := costSoFar[g.index(current)]
+		if item.priority > currentCost+g.heuristic(current, goal) {
+			continue
+		}
+
+		for _, next := range g.neighbours(current) {
+			if !g.inBounds(next) || (next != goal && g.Blocked(next)) {
+				continue
+			}
+
+			newCost := currentCost + g.cost(current, next)
+			nextIndex := g.index(next)
+			if newCost < costSoFar[nextIndex] {
+				costSoFar[nextIndex] = newCost
+				cameFrom[nextIndex] = g.index(current)
+				open.push(next, newCost+g.heuristic(next, goal))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Estimated cost to reach the goal, never overestimates as moves cost at least 1
+func (g *Grid) heuristic(a, b core.Pos) int {
+	dx := core.AbsInt(a.X - b.X)
+	dy := core.AbsInt(a.Y - b.Y)
+
+	if g.Diagonal {
+		return core.MaxInt(dx, dy)
+	}
+
+	return dx + dy
+}
+
+// Walk back from the goal to the start to build the path
+func (g *Grid) buildPath(cameFrom []int, start, goal core.Pos) []core.Pos {
+	path := make([]core.Pos, 0)
+
+	for p := goal; p != start; {
+		path = append(path, p)
+		i := cameFrom[g.index(p)]
+		p = core.Pos{X: i % g.Size.Width, Y: i / g.Size.Width}
+	}
+
+	// Reverse so it runs from start to goal
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
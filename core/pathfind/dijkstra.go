@@ -0,0 +1,123 @@
+package pathfind
+
+// ====================================================================================================================
+// Dijkstra maps (also called flow maps) hold the cost to reach the nearest of many sources from every point
+// Rolling downhill on the map moves towards the nearest source, e.g. to the player or the closest item.
+// A flee map is made by inverting and rescanning a map, rolling downhill on that moves away intelligently
+// See https://www.roguebasin.com/index.php/The_Incredible_Power_of_Dijkstra_Maps
+// ====================================================================================================================
+
+import (
+	"roguelike/core"
+)
+
+// Unreachable is the value for any position that can't be reached from a source
+const Unreachable = 1 << 30
+
+// How strongly a flee map prefers getting further away over avoiding dead ends, as a percentage
+// Values over 100 make fleeing things run past the source to reach open space, rather than be cornered
+const fleeFactor = 120
+
+// DijkstraMap holds the cost to reach the nearest source from every position in a grid
+type DijkstraMap struct {
+	grid  *Grid
+	costs []int
+}
+
+// DijkstraMap builds a map from one or more sources, sources are always treated as enterable
+func (g *Grid) DijkstraMap(sources ...core.Pos) *DijkstraMap {
+	costs := make([]int, g.Size.Area())
+	for i := range costs {
+		costs[i] = Unreachable
+	}
+
+	for _, s := range sources {
+		if g.inBounds(s) {
+			costs[g.index(s)] = 0
+		}
+	}
+
+	dm := &DijkstraMap{grid: g, costs: costs}
+	dm.scan()
+
+	return dm
+}
+
+// Flee creates a new map from this one, rolling downhill on it moves away from the sources
+func (dm *DijkstraMap) Flee() *DijkstraMap {
+	costs := make([]int, len(dm.costs))
+	for i, c := range dm.costs {
+		costs[i] = Unreachable
+		if c != Unreachable {
+			costs[i] = -c * fleeFactor / 100
+		}
+	}
+
+	flee := &DijkstraMap{grid: dm.grid, costs: costs}
+	flee.scan()
+
+	return flee
+}
+
+// Cost returns the cost at a position, which is Unreachable if out of bounds or not reachable
+func (dm *DijkstraMap) Cost(p core.Pos) int {
+	if !dm.grid.inBounds(p) {
+		return Unreachable
+	}
+
+	return dm.costs[dm.grid.index(p)]
+}
+
+// NextStep returns the neighbour of a position with the lowest cost, i.e. the next step downhill
+// Returns false when there is nowhere lower to go, e.g. already at a source
+func (dm *DijkstraMap) NextStep(p core.Pos) (core.Pos, bool) {
+	best := dm.Cost(p)
+	bestPos := p
+
+	for _, next := range dm.grid.neighbours(p) {
+		if !dm.grid.inBounds(next) || dm.grid.Blocked(next) {
+			continue
+		}
+
+		if c := dm.Cost(next); c < best {
+			best = c
+			bestPos = next
+		}
+	}
+
+	return bestPos, bestPos != p
+}
+
+// Relax the costs outwards from every reachable position until nothing changes
+func (dm *DijkstraMap) scan() {
+	g := dm.grid
+	open := &priorityQueue{}
+
+	for i, c := range dm.costs {
+		if c != Unreachable {
+			open.push(core.Pos{X: i % g.Size.Width, Y: i / g.Size.Width}, c)
+		}
+	}
+
+	for open.Len() > 0 {
+		item := open.pop()
+		current := item.pos
+
+		// Skip stale queue entries, a cheaper route has already been found
+		if item.priority > dm.costs[g.index(current)] {
+			continue
+		}
+
+		for _, next := range g.neighbours(current) {
+			if !g.inBounds(next) || g.Blocked(next) {
+				continue
+			}
+
+			newCost := item.priority + g.cost(current, next)
+			if newCost < dm.costs[g.index(next)] {
+				dm.costs[g.index(next)] = newCost
+				open.push(next, newCost)
+			}
+		}
+	}
+}
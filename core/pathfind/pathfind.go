@@ -0,0 +1,109 @@
+package pathfind
+
+// ====================================================================================================================
+// Pathfinding over 2D grids, A* for point to point paths and Dijkstra maps for many sources
+// The grid is described by callbacks, so this works with any map type, e.g. engine.GameMap
+// All results are deterministic, ties are always broken in the same order
+// ====================================================================================================================
+
+import (
+	"container/heap"
+	"roguelike/core"
+)
+
+// BlockedFunc returns true if a position can not be entered
+type BlockedFunc func(p core.Pos) bool
+
+// CostFunc returns the cost of moving between two neighbouring positions, must be 1 or more
+type CostFunc func(from, to core.Pos) int
+
+// Grid describes the area to find paths over
+type Grid struct {
+	Size     core.Size
+	Blocked  BlockedFunc // Required, positions that can't be entered
+	Cost     CostFunc    // Optional, when nil every move costs 1
+	Diagonal bool        // Allow diagonal moves as well as cardinal ones
+}
+
+// NewGrid creates a grid with cardinal only moves, that all have the same cost
+func NewGrid(size core.Size, blocked BlockedFunc) *Grid {
+	return &Grid{
+		Size:    size,
+		Blocked: blocked,
+	}
+}
+
+func (g *Grid) neighbours(p core.Pos) []core.Pos {
+	if g.Diagonal {
+		return p.NeighboursAll()
+	}
+
+	return p.NeighboursCardinal()
+}
+
+func (g *Grid) cost(from, to core.Pos) int {
+	if g.Cost == nil {
+		return 1
+	}
+
+	return max(g.Cost(from, to), 1)
+}
+
+func (g *Grid) index(p core.Pos) int {
+	return p.Y*g.Size.Width + p.X
+}
+
+func (g *Grid) inBounds(p core.Pos) bool {
+	return p.InBounds(g.Size.Width, g.Size.Height)
+}
+
+// ===== Priority queue ===============================================================================================
+
+type queueItem struct {
+	pos      core.Pos
+	priority int
+	seq      int // Insertion order, used to break ties so results are deterministic
+}
+
+type priorityQueue struct {
+	items []queueItem
+	seq   int
+}
+
+func (pq *priorityQueue) Len() int {
+	return len(pq.items)
+}
+
+func (pq *priorityQueue) Less(i, j int) bool {
+	if pq.items[i].priority == pq.items[j].priority {
+		return pq.items[i].seq < pq.items[j].seq
+	}
+
+	return pq.items[i].priority < pq.items[j].priority
+}
+
+func (pq *priorityQueue) Swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+}
+
+func (pq *priorityQueue) Push(x any) {
+	pq.items = append(pq.items, x.(queueItem))
+}
+
+func (pq *priorityQueue) Pop() any {
+	old := pq.items
+	n := len(old)
+	item := old[n-1]
+	pq.items = old[:n-1]
+
+	return item
+}
+
+func (pq *priorityQueue) push(p core.Pos, priority int) {
+	pq.seq++
+	heap.Push(pq, queueItem{pos: p, priority: priority, seq: pq.seq})
+}
+
+func (pq *priorityQueue) pop() queueItem {
+	return heap.Pop(pq).(queueItem)
+}
@@ -0,0 +1,202 @@
+package pathfind
+
+// ============================================================================
+// Tests & benchmarks for A* and Dijkstra maps
+// ============================================================================
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"roguelike/core"
+	"strings"
+	"testing"
+)
+
+// Build a grid from a text picture, '#' is a wall anything else is open
+func gridFromText(text string) *Grid {
+	rows := strings.Split(strings.TrimSpace(text), "\n")
+	walls := make(map[core.Pos]bool)
+
+	for y, row := range rows {
+		for x, ch := range strings.TrimSpace(row) {
+			if ch == '#' {
+				walls[core.Pos{X: x, Y: y}] = true
+			}
+		}
+	}
+
+	size := core.Size{Width: len(strings.TrimSpace(rows[0])), Height: len(rows)}
+	return NewGrid(size, func(p core.Pos) bool {
+		return walls[p]
+	})
+}
+
+// A 64x64 grid with scattered walls, always the same for a given seed
+func randomGrid(seed uint64) *Grid {
+	r := rand.New(rand.NewPCG(seed, seed))
+	size := core.Size{Width: 64, Height: 64}
+	walls := make([]bool, size.Area())
+
+	for i := range walls {
+		walls[i] = r.IntN(100) < 20
+	}
+
+	// Keep the corners clear so there are start & end points
+	walls[0] = false
+	walls[len(walls)-1] = false
+
+	return NewGrid(size, func(p core.Pos) bool {
+		return walls[p.Y*size.Width+p.X]
+	})
+}
+
+func TestAStar(t *testing.T) {
+	g := gridFromText(`
+		.....
+		.###.
+		...#.
+		.#...`)
+
+	path := g.AStar(core.Pos{X: 0, Y: 0}, core.Pos{X: 4, Y: 3})
+	if len(path) != 7 {
+		t.Fatalf("AStar path length = %d; want 7, path %v", len(path), path)
+	}
+
+	if path[len(path)-1] != (core.Pos{X: 4, Y: 3}) {
+		t.Errorf("AStar path ends at %v; want (4, 3)", path[len(path)-1])
+	}
+
+	for i := 1; i < len(path); i++ {
+		if !path[i-1].IsNeighbour(path[i]) || g.Blocked(path[i]) {
+			t.Errorf("AStar path has bad step %v → %v", path[i-1], path[i])
+		}
+	}
+}
+
+func TestAStarNoPath(t *testing.T) {
+	g := gridFromText(`
+		..#..
+		..#..
+		..#..`)
+
+	if path := g.AStar(core.Pos{X: 0, Y: 0}, core.Pos{X: 4, Y: 0}); path != nil {
+		t.Errorf("AStar through a wall = %v; want nil", path)
+	}
+}
+
+func TestAStarBlockedGoal(t *testing.T) {
+	g := gridFromText(`
+		....#`)
+
+	path := g.AStar(core.Pos{X: 0, Y: 0}, core.Pos{X: 4, Y: 0})
+	if len(path) != 4 {
+		t.Errorf("AStar to a blocked goal length = %d; want 4", len(path))
+	}
+}
+
+func TestAStarDeterministic(t *testing.T) {
+	start, goal := core.Pos{X: 0, Y: 0}, core.Pos{X: 63, Y: 63}
+	first := randomGrid(7).AStar(start, goal)
+	if first == nil {
+		t.Fatalf("AStar found no path across the random grid")
+	}
+
+	for i := 0; i < 10; i++ {
+		if path := randomGrid(7).AStar(start, goal); !reflect.DeepEqual(path, first) {
+			t.Fatalf("AStar gave a different path on run %d", i)
+		}
+	}
+}
+
+func TestDijkstraMap(t *testing.T) {
+	g := gridFromText(`
+		.....
+		.###.
+		.....`)
+
+	dm := g.DijkstraMap(core.Pos{X: 0, Y: 0}, core.Pos{X: 4, Y: 2})
+
+	tests := []struct {
+		pos  core.Pos
+		cost int
+	}{
+		{core.Pos{X: 0, Y: 0}, 0},
+		{core.Pos{X: 4, Y: 2}, 0},
+		{core.Pos{X: 2, Y: 0}, 2},
+		{core.Pos{X: 2, Y: 2}, 2},
+		{core.Pos{X: 2, Y: 1}, Unreachable},
+		{core.Pos{X: 9, Y: 9}, Unreachable},
+	}
+
+	for _, test := range tests {
+		if cost := dm.Cost(test.pos); cost != test.cost {
+			t.Errorf("DijkstraMap.Cost(%v) = %d; want %d", test.pos, cost, test.cost)
+		}
+	}
+
+	next, ok := dm.NextStep(core.Pos{X: 1, Y: 2})
+	if !ok || next != (core.Pos{X: 0, Y: 2}) {
+		t.Errorf("DijkstraMap.NextStep = %v, %v; want (0, 2), true", next, ok)
+	}
+
+	if _, ok := dm.NextStep(core.Pos{X: 0, Y: 0}); ok {
+		t.Errorf("DijkstraMap.NextStep at a source should return false")
+	}
+}
+
+func TestDijkstraFlee(t *testing.T) {
+	g := gridFromText(`
+		.........`)
+
+	threat := core.Pos{X: 3, Y: 0}
+	flee := g.DijkstraMap(threat).Flee()
+
+	// Should run away to the right, which is the direction with more room
+	p := core.Pos{X: 4, Y: 0}
+	for i := 0; i < 4; i++ {
+		next, ok := flee.NextStep(p)
+		if !ok {
+			break
+		}
+
+		if next.Distance(threat) < p.Distance(threat) {
+			t.Fatalf("Flee moved towards the threat %v → %v", p, next)
+		}
+
+		p = next
+	}
+
+	if p != (core.Pos{X: 8, Y: 0}) {
+		t.Errorf("Flee ended at %v; want (8, 0)", p)
+	}
+}
+
+func BenchmarkAStar64x64(b *testing.B) {
+	g := randomGrid(7)
+	start, goal := core.Pos{X: 0, Y: 0}, core.Pos{X: 63, Y: 63}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.AStar(start, goal)
+	}
+}
+
+func BenchmarkDijkstraMap64x64(b *testing.B) {
+	g := randomGrid(7)
+	sources := []core.Pos{{X: 0, Y: 0}, {X: 32, Y: 32}, {X: 63, Y: 63}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.DijkstraMap(sources...)
+	}
+}
+
+func BenchmarkDijkstraFlee64x64(b *testing.B) {
+	g := randomGrid(7)
+	dm := g.DijkstraMap(core.Pos{X: 32, Y: 32})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dm.Flee()
+	}
+}
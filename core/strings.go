@@ -1,14 +1,14 @@
 package core
 
-import "math/rand"
+import "math/rand/v2"
 
-// Simple ID generator 8 characters long
-func RandId(idLen int) string {
+// Simple ID generator, the rng is passed in so IDs are repeatable with a seed
+func RandId(rng *rand.Rand, idLen int) string {
 	// generate a random string
 	chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	id := make([]byte, idLen)
 	for i := range id {
-		id[i] = chars[rand.Intn(len(chars))]
+		id[i] = chars[rng.IntN(len(chars))]
 	}
 	return string(id)
 }
@@ -7,10 +7,11 @@ package engine
 import (
 	"fmt"
 	"roguelike/core"
+	"slices"
 )
 
 type Action interface {
-	Execute(g Game) ActionResult
+	Execute(g *Game) ActionResult
 }
 
 type ActionResult struct {
@@ -18,36 +19,160 @@ type ActionResult struct {
 	EnergySpent int
 }
 
+// Actors are anything that can carry out actions, i.e. the player and creatures
+type actor interface {
+	Name() string
+	Tile() *tile
+	moveToTile(t *tile)
+	activeStatuses() statusList
+}
+
 type MoveAction struct {
 	direction
+	actor actor // Who is moving, nil means the player
 }
 
 type AttackAction struct {
-	target *creature
+	attacker actor // Who is attacking, nil means the player
+	target   actor
+}
+
+type WaitAction struct {
+	actor actor
+}
+
+type SearchAction struct{}
+
+type StairsAction struct {
+	down bool
 }
 
+// Actions on a piece of furniture next to the player
+type FurnitureAction struct {
+	direction
+	kind furnitureActionKind
+}
+
+type furnitureActionKind int
+
+const (
+	furnitureInteract furnitureActionKind = iota
+	furnitureOpen
+	furnitureClose
+	furnitureBash
+)
+
 type PickupAction struct {
 	item *Item
 }
 
+// Dropping some of a stack, or all of it when count is zero
 type DropAction struct {
-	item *Item
+	item  *Item
+	count int
 }
 
 type UseAction struct {
 	item *Item
 }
 
+// Picking a perk after levelling up, this doesn't take any time
+type PerkAction struct {
+	perk string
+}
+
+// Equipping or taking off an item, the slot only matters for items which can go in more than one
 type EquipAction struct {
 	item *Item
+	slot equipSlot
+}
+
+// Firing the launcher in the missile slot at a creature
+type FireAction struct {
+	target *creature
+}
+
+// Throwing an item from the backpack at a creature
+type ThrowAction struct {
+	item   *Item
+	target *creature
+}
+
+// Casting a known spell using mana, the target is only needed by spells aimed at a creature
+type CastAction struct {
+	spell  string
+	target *creature
+}
+
+// Zapping a wand from the backpack, which casts its spell using up a charge
+type ZapAction struct {
+	item   *Item
+	target *creature
 }
 
 func NewMoveAction(d core.Direction) *MoveAction {
-	return &MoveAction{d}
+	return &MoveAction{direction: d}
+}
+
+func (a *MoveAction) Direction() core.Direction {
+	return a.direction
+}
+
+func newMoveActionFor(a actor, d core.Direction) *MoveAction {
+	return &MoveAction{direction: d, actor: a}
 }
 
 func NewAttackAction(target *creature) *AttackAction {
-	return &AttackAction{target}
+	return &AttackAction{target: target}
+}
+
+func newAttackActionFor(attacker actor, target actor) *AttackAction {
+	return &AttackAction{attacker: attacker, target: target}
+}
+
+func NewWaitAction() *WaitAction {
+	return &WaitAction{}
+}
+
+func newWaitAction(a actor) *WaitAction {
+	return &WaitAction{a}
+}
+
+func NewSearchAction() *SearchAction {
+	return &SearchAction{}
+}
+
+func NewDescendAction() *StairsAction {
+	return &StairsAction{down: true}
+}
+
+func NewAscendAction() *StairsAction {
+	return &StairsAction{down: false}
+}
+
+func NewInteractAction(d core.Direction) *FurnitureAction {
+	return &FurnitureAction{d, furnitureInteract}
+}
+
+func NewOpenAction(d core.Direction) *FurnitureAction {
+	return &FurnitureAction{d, furnitureOpen}
+}
+
+func NewCloseAction(d core.Direction) *FurnitureAction {
+	return &FurnitureAction{d, furnitureClose}
+}
+
+func NewBashAction(d core.Direction) *FurnitureAction {
+	return &FurnitureAction{d, furnitureBash}
+}
+
+// Returns the actor carrying out an action, which defaults to the player
+func (g *Game) actorOrPlayer(a actor) actor {
+	if a == nil {
+		return g.player
+	}
+
+	return a
 }
 
 func NewPickupAction(item *Item) *PickupAction {
@@ -55,29 +180,101 @@ func NewPickupAction(item *Item) *PickupAction {
 }
 
 func NewDropAction(item *Item) *DropAction {
-	return &DropAction{item}
+	return &DropAction{item, 0}
+}
+
+// Drop just some of a stack, leaving the rest in the backpack
+func NewDropCountAction(item *Item, count int) *DropAction {
+	return &DropAction{item, count}
 }
 
 func NewUseAction(item *Item) *UseAction {
 	return &UseAction{item}
 }
 
+// Equip an item in a free slot, or take it off if it's already equipped
 func NewEquipAction(item *Item) *EquipAction {
-	return &EquipAction{item}
+	return &EquipAction{item, slotAny}
+}
+
+// Equip an item in a particular slot, from Player.EquipSlotsFor
+func NewEquipSlotAction(item *Item, slot equipSlot) *EquipAction {
+	return &EquipAction{item, slot}
+}
+
+func NewPerkAction(perk *Perk) *PerkAction {
+	return &PerkAction{perk.id}
+}
+
+func NewFireAction(target *creature) *FireAction {
+	return &FireAction{target}
 }
 
-func (a *MoveAction) Execute(g Game) ActionResult {
+func NewThrowAction(item *Item, target *creature) *ThrowAction {
+	return &ThrowAction{item, target}
+}
+
+func NewCastAction(spell *Spell, target *creature) *CastAction {
+	return &CastAction{spell.id, target}
+}
+
+func NewZapAction(item *Item, target *creature) *ZapAction {
+	return &ZapAction{item, target}
+}
+
+func (a *MoveAction) Execute(g *Game) ActionResult {
 	p := g.Player()
 	m := g.Map()
+	mover := g.actorOrPlayer(a.actor)
+
+	// Confused actors stagger off in a random direction
+	dir := a.direction
+	if mover.activeStatuses().has(statusConfusion) && g.rng.Chance(confusionChance) {
+		dir = core.Directions[g.rng.IntN(len(core.Directions))]
+		if mover == actor(p) && dir != a.direction {
+			events.new(EventMiscMessage, nil, "You stumble about in confusion")
+		}
+	}
+
+	destTile := m.AdjacentTile(mover.Tile(), dir)
+
+	// Walking into a closed door opens it
+	if f := destTile.Furniture(); f != nil && f.isDoor() && !f.open {
+		if mover != actor(p) || !g.runFurnitureHook(f, furnitureActionNames[furnitureOpen]) {
+			f.openUp(g, mover)
+		}
 
-	destTile := m.AdjacentTile(p.currentTile, a.direction)
+		if mover == actor(p) {
+			g.updateFOV()
+		}
+
+		return ActionResult{true, energyPerTurn}
+	}
 
-	if destTile == nil || destTile.BlocksMove() {
+	if destTile == nil || destTile.BlocksMove() || destTile == p.currentTile {
 		return ActionResult{false, 0}
 	}
 
-	energy := 4
-	p.moveToTile(destTile)
+	energy := energyPerTurn
+	if mover == actor(p) {
+		energy = p.moveEnergy()
+	}
+
+	mover.moveToTile(destTile)
+
+	// Everything else is only relevant to the player
+	if mover != actor(p) {
+		return ActionResult{true, energy}
+	}
+
+	g.updateFOV()
+
+	// Traps go off, and might move the player somewhere else entirely
+	if destTile.trap != nil {
+		if moved := destTile.trap.trigger(g); moved || g.IsOver() {
+			return ActionResult{true, energy}
+		}
+	}
 
 	// Check for items and auto pick them up
 	items := destTile.items
@@ -89,64 +286,204 @@ func (a *MoveAction) Execute(g Game) ActionResult {
 
 		if item.dropped {
 			events.new(EventItemSkipped, item, fmt.Sprintf("You see a %s you previously dropped", item.Name()))
-			return ActionResult{true, 40}
+			return ActionResult{true, energy}
 		}
 
-		pickupAction := NewPickupAction(item)
-		return pickupAction.Execute(g)
+		// The move still happened even if the pickup failed, so always a success
+		pickupResult := NewPickupAction(item).Execute(g)
+		return ActionResult{true, energy + pickupResult.EnergySpent}
 	} else if len(items) > 1 {
 		events.new(EventItemMultiple, nil, fmt.Sprintf("You stand over a pile of %d items", len(items)))
 	}
 
-	g.updateFOV()
 	return ActionResult{true, energy}
 }
 
-func (a *AttackAction) Execute(g Game) ActionResult {
-	p := g.Player()
+func (a *AttackAction) Execute(g *Game) ActionResult {
+	attacker := g.actorOrPlayer(a.attacker)
 
-	// Check adjacent
-	// TODO: Maybe remove this for ranged attacks
-	if !p.IsNeighbour(*a.target.pos) {
+	// Target might have died or left the map
+	if a.target == nil || a.target.Tile() == nil || attacker.Tile() == nil {
 		return ActionResult{false, 0}
 	}
 
-	// Attack the target
-	// TODO: Add a combat system here :)
-	hitRoll := d100.Roll()
-	events.new(EventMiscMessage, a.target, fmt.Sprintf("Hit roll: %d need < %d", hitRoll, p.attackChance))
-	if hitRoll > p.attackChance {
-		events.new(EventCombatMissed, a.target, "You missed!")
+	// Melee attacks need to be next to the target, see FireAction & ThrowAction for ranged
+	if !attacker.Tile().IsNeighbour(a.target.Tile().pos) {
 		return ActionResult{false, 0}
 	}
 
-	rollResult := p.attackRoll.Roll() + p.attackDamage
-	events.new(EventCombatHit, nil, fmt.Sprintf("Attack: %s+%d → %d", p.attackRoll, p.attackDamage, rollResult))
+	switch target := a.target.(type) {
+	case *creature:
+		return a.attackCreature(g, target)
+	case *Player:
+		return a.attackPlayer(g, attacker, target)
+	}
+
+	return ActionResult{false, 0}
+}
+
+// The player attacking a creature
+func (a *AttackAction) attackCreature(g *Game, target *creature) ActionResult {
+	res := resolveAttack(g.rng, g.combatRules, g.player, target)
+	g.playerAttacked(target, res)
+
+	return ActionResult{true, energyPerTurn}
+}
+
+// Deal with the result of the player attacking a creature, up close or with a missile
+func (g *Game) playerAttacked(target *creature, res CombatResult) {
+	if !res.Hit {
+		events.new(EventCombatMissed, target, res.describe(true))
+		return
+	}
+
+	// The creature's onHit hook can undo the hit, e.g. for something immune to damage
+	if g.runCreatureHook(target, "onHit", target.hooks.onHit, map[string]any{"damage": res.Damage}) {
+		target.hp = core.MinInt(target.hp+res.Damage, target.maxHP)
+		return
+	}
+
+	events.new(EventCombatHit, target, res.describe(true))
+
+	if target.hp > 0 {
+		if wound := woundDescription(target.hp, target.maxHP); wound != "" {
+			events.new(EventCreatureWounded, target, fmt.Sprintf("The %s %s", target.Name(), wound))
+		}
+
+		return
+	}
 
-	a.target.currentTile.creature = nil
-	a.target.currentTile = nil
 	message := fmt.Sprintf("You %s a %s",
-		randString("killed", "defeated", "felled", "vanquished", "slayed", "destroyed", "murdered"),
-		a.target.Name())
-	events.new(EventCreatureKilled, a.target, message)
-	p.exp += a.target.xp
+		g.rng.pick("killed", "defeated", "felled", "vanquished", "slayed", "destroyed", "murdered"),
+		target.Name())
+
+	g.killCreature(target, true, message)
+}
+
+// A creature attacking the player
+func (a *AttackAction) attackPlayer(g *Game, attacker actor, p *Player) ActionResult {
+	c, ok := attacker.(*creature)
+	if !ok || p.IsDead() {
+		return ActionResult{false, 0}
+	}
+
+	hpBefore := p.hp
+	res := resolveAttack(g.rng, g.combatRules, c, p)
+	if !res.Hit || res.Damage == 0 {
+		events.new(EventCombatMissed, c, res.describe(false))
+		return ActionResult{true, energyPerTurn}
+	}
+
+	events.new(EventPlayerHit, c, res.describe(false))
+
+	if res.Killed {
+		g.killPlayer(c, "a "+c.Name())
+		return ActionResult{true, energyPerTurn}
+	}
+
+	// Only warn once, when the player first drops to near death
+	if woundDescription(p.hp, p.maxHP) == "is nearly dead" && woundDescription(hpBefore, p.maxHP) != "is nearly dead" {
+		events.new(EventPlayerNearDeath, nil, "You are close to death!")
+	}
+
+	return ActionResult{true, energyPerTurn}
+}
 
-	return ActionResult{true, 60}
+func (a *WaitAction) Execute(g *Game) ActionResult {
+	return ActionResult{true, energyPerTurn}
 }
 
-func (a *PickupAction) Execute(g Game) ActionResult {
+func (a *SearchAction) Execute(g *Game) ActionResult {
+	if g.searchForTraps() == 0 {
+		events.new(EventMiscMessage, nil, "You search but find nothing")
+	}
+
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *StairsAction) Execute(g *Game) ActionResult {
+	t := g.player.currentTile
+
+	// The stairs are used even if the level's onEnter hook turns the player back
+	if a.down && t.tileType == tileTypeStairsDown {
+		g.changeLevel(g.gameMap.depth + 1)
+		return ActionResult{true, energyPerTurn}
+	}
+
+	if !a.down && t.tileType == tileTypeStairsUp {
+		g.changeLevel(g.gameMap.depth - 1)
+		return ActionResult{true, energyPerTurn}
+	}
+
+	if a.down {
+		events.new(EventMiscMessage, nil, "There are no stairs down here")
+	} else {
+		events.new(EventMiscMessage, nil, "There are no stairs up here")
+	}
+
+	return ActionResult{false, 0}
+}
+
+func (a *FurnitureAction) Execute(g *Game) ActionResult {
+	t := g.gameMap.AdjacentTile(g.player.currentTile, a.direction)
+	f := t.Furniture()
+	if f == nil {
+		events.new(EventMiscMessage, nil, "There is nothing there")
+		return ActionResult{false, 0}
+	}
+
+	// The furniture's hook can take over from what would normally happen
+	if g.runFurnitureHook(f, furnitureActionNames[a.kind]) {
+		g.updateFOV()
+		return ActionResult{true, energyPerTurn}
+	}
+
+	done := false
+	switch a.kind {
+	case furnitureInteract:
+		done = f.interact(g)
+	case furnitureOpen:
+		done = f.openUp(g, g.player)
+		if !done {
+			events.new(EventMiscMessage, f, fmt.Sprintf("You can't open the %s", f.name))
+		}
+	case furnitureClose:
+		done = f.shut(g)
+		if !done {
+			events.new(EventMiscMessage, f, fmt.Sprintf("You can't close the %s", f.name))
+		}
+	case furnitureBash:
+		done = f.bash(g)
+	}
+
+	if !done {
+		return ActionResult{false, 0}
+	}
+
+	// Opening or closing doors changes what can be seen
+	g.updateFOV()
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *PickupAction) Execute(g *Game) ActionResult {
 	p := g.Player()
 
-	if p.PickupItem(a.item) {
-		events.new(EventItemPickup, a.item, "Picked up "+a.item.Name())
-		return ActionResult{true, 40}
+	picked := a.item.NameQuantity()
+	if carried := p.PickupItem(a.item); carried != nil {
+		msg := "Picked up " + picked
+		if carried != a.item {
+			msg = fmt.Sprintf("Picked up %s, you now have %d", picked, carried.quantity)
+		}
+
+		events.new(EventItemPickup, carried, msg)
+		return ActionResult{true, energyPerTurn / 2}
 	}
 
 	events.new(EventPackFull, a.item, "Carrying too much!")
 	return ActionResult{false, 0}
 }
 
-func (a *DropAction) Execute(g Game) ActionResult {
+func (a *DropAction) Execute(g *Game) ActionResult {
 	p := g.Player()
 
 	if a.item.IsEquipped() {
@@ -154,57 +491,192 @@ func (a *DropAction) Execute(g Game) ActionResult {
 		return ActionResult{false, 0}
 	}
 
-	if p.DropItem(a.item) {
-		events.new(EventItemDropped, a.item, fmt.Sprintf("Dropped the %s", a.item.Name()))
-		return ActionResult{true, 40}
+	if dropped := p.DropItem(g.rng, a.item, a.count); dropped != nil {
+		events.new(EventItemDropped, dropped, fmt.Sprintf("Dropped the %s", dropped.NameQuantity()))
+		return ActionResult{true, energyPerTurn / 2}
 	}
 
 	events.new(EventItemDropped, a.item, fmt.Sprintf("Can't drop the %s here", a.item.Name()))
 	return ActionResult{false, 0}
 }
 
-func (a *UseAction) Execute(g Game) ActionResult {
+func (a *UseAction) Execute(g *Game) ActionResult {
 	// Call the item's use method
 	if a.item.use(g) {
-		return ActionResult{true, 40}
+		// Some items can be deadly
+		if g.player.hp <= 0 {
+			g.killPlayer(a.item, "a "+a.item.Name())
+		}
+
+		return ActionResult{true, energyPerTurn}
 	}
 
 	return ActionResult{false, 0}
 }
 
-func (a *EquipAction) Execute(g Game) ActionResult {
+func (a *EquipAction) Execute(g *Game) ActionResult {
 	p := g.Player()
 
 	if !a.item.IsEquipment() {
 		return ActionResult{false, 0}
 	}
 
-	slot := a.item.EquipLocation()
+	if slot, ok := p.EquippedSlot(a.item); ok {
+		if !p.unequip(slot) {
+			return ActionResult{false, 0}
+		}
+
+		return ActionResult{true, energyPerTurn}
+	}
+
+	slot := a.slot
+	if slot == slotAny {
+		slot = p.freeSlotFor(a.item)
+	}
+
+	if !p.canEquip(a.item, slot) {
+		return ActionResult{false, 0}
+	}
+
+	// Whatever is in the slot comes off, and the shield for a two-handed weapon
+	displaced := p.displacedBy(a.item, slot)
+	if !p.EquipItem(a.item, slot) {
+		events.new(EventPackFull, a.item, fmt.Sprintf("No room in your pack to take anything off for the %s", a.item.Name()))
+		return ActionResult{false, 0}
+	}
 
-	if p.IsEquipped(a.item) {
-		p.UnequipItem(slot)
+	for _, i := range displaced {
+		unequippedMessage(i, slot)
+	}
 
-		msg := fmt.Sprintf("The %s was taken off", a.item.Name())
-		if slot == equipLocationWeapon || slot == equipLocationShield || slot == equipLocationMissile {
-			msg = fmt.Sprintf("No longer wielding a %s", a.item.Name())
-		}
+	// Putting on a ring shows what it is
+	g.identify(a.item)
 
-		events.new(EventItemUnequipped, a.item, msg)
-	} else {
-		// Is there an existing item in the slot?
-		if i, ok := p.equipSlots[slot]; ok {
-			p.UnequipItem(i.EquipLocation())
-		}
+	msg := fmt.Sprintf("Now wearing a %s", a.item.Name())
+	if slot.wielded() {
+		msg = fmt.Sprintf("Wielding a %s", a.item.Name())
+	}
+
+	if len(p.EquipSlotsFor(a.item)) > 1 {
+		msg += fmt.Sprintf(" (%s)", slot)
+	}
+
+	events.new(EventItemEquipped, a.item, msg)
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *PerkAction) Execute(g *Game) ActionResult {
+	p := g.player
 
-		p.EquipItem(a.item, slot)
+	perk, ok := g.progression.perks[a.perk]
+	if !ok || p.pendingPerks <= 0 || slices.Contains(p.perks, a.perk) {
+		return ActionResult{false, 0}
+	}
 
-		msg := fmt.Sprintf("Now wearing a %s", a.item.Name())
-		if slot == equipLocationWeapon || slot == equipLocationShield || slot == equipLocationMissile {
-			msg = fmt.Sprintf("Wielding a %s", a.item.Name())
+	p.improveBase(func(s *playerStats) {
+		for _, e := range perk.effects {
+			e.apply(s)
 		}
+	})
+
+	p.pendingPerks--
+	p.perks = append(p.perks, a.perk)
+	events.new(EventPerkChosen, nil, fmt.Sprintf("You gain the %s perk", perk.name))
+
+	return ActionResult{true, 0}
+}
+
+func (a *FireAction) Execute(g *Game) ActionResult {
+	p := g.player
+
+	launcher := p.launcher()
+	if launcher == nil {
+		events.new(EventMiscMessage, nil, "You have nothing to fire")
+		return ActionResult{false, 0}
+	}
+
+	ammo := p.ammoFor(launcher)
+	if ammo == nil {
+		events.new(EventMiscMessage, launcher, fmt.Sprintf("You have nothing to fire from your %s", launcher.Name()))
+		return ActionResult{false, 0}
+	}
+
+	if !g.canTarget(a.target, launcher.missileRange) {
+		return ActionResult{false, 0}
+	}
+
+	ammo = p.takeFromBackpack(g.rng, ammo, 1)
+	events.new(EventMissileFired, ammo, fmt.Sprintf("You fire your %s at the %s", launcher.Name(), a.target.Name()))
+	g.launchMissile(ammo, launcher.rangedDamage, a.target, launcher.missileRange, ammoBreakChance)
+
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *ThrowAction) Execute(g *Game) ActionResult {
+	p := g.player
+
+	if !p.backpack.Contains(a.item) || !a.item.Throwable() {
+		events.new(EventMiscMessage, a.item, fmt.Sprintf("You can't throw the %s", a.item.Name()))
+		return ActionResult{false, 0}
+	}
+
+	if !g.canTarget(a.target, a.item.missileRange) {
+		return ActionResult{false, 0}
+	}
+
+	thrown := p.takeFromBackpack(g.rng, a.item, 1)
+	events.new(EventMissileFired, thrown, fmt.Sprintf("You throw the %s at the %s", thrown.Name(), a.target.Name()))
+	g.launchMissile(thrown, thrown.rangedDamage, a.target, thrown.missileRange, 0)
+
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *CastAction) Execute(g *Game) ActionResult {
+	p := g.player
+
+	spell, ok := g.spells[a.spell]
+	if !ok || !slices.Contains(p.spells, a.spell) {
+		events.new(EventMiscMessage, nil, "You don't know that spell")
+		return ActionResult{false, 0}
+	}
+
+	if p.mana < spell.mana {
+		events.new(EventMiscMessage, nil, fmt.Sprintf("You don't have enough mana to cast %s", spell.name))
+		return ActionResult{false, 0}
+	}
+
+	if !g.castSpell(spell, a.target) {
+		return ActionResult{false, 0}
+	}
+
+	p.mana -= spell.mana
+	if p.hp <= 0 {
+		g.killPlayer(nil, "their own magic")
+	}
+
+	return ActionResult{true, energyPerTurn}
+}
+
+func (a *ZapAction) Execute(g *Game) ActionResult {
+	spell := g.WandSpell(a.item)
+	if !g.player.backpack.Contains(a.item) || spell == nil {
+		events.new(EventMiscMessage, a.item, fmt.Sprintf("You can't zap the %s", a.item.Name()))
+		return ActionResult{false, 0}
+	}
+
+	if a.item.charges <= 0 {
+		events.new(EventMiscMessage, a.item, fmt.Sprintf("The %s is out of charges", a.item.Name()))
+		return ActionResult{false, 0}
+	}
+
+	if !g.castSpell(spell, a.target) {
+		return ActionResult{false, 0}
+	}
 
-		events.new(EventItemEquipped, a.item, msg)
+	a.item.charges--
+	if g.player.hp <= 0 {
+		g.killPlayer(a.item, "a "+a.item.Name())
 	}
 
-	return ActionResult{true, 40}
+	return ActionResult{true, energyPerTurn}
 }
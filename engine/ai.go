@@ -0,0 +1,192 @@
+package engine
+
+// ============================================================================
+// Creature AI is built from simple pluggable behaviours, each creature has a
+// list of them in priority order which is set in the creatures.yaml datafile
+// On its turn the creature tries each behaviour in turn, the first one to
+// decide on an action wins, creatures act using the same actions as the player
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+)
+
+// A behaviour decides what a creature should do, returning nil means it has
+// nothing to do and the next behaviour in the list gets a chance to decide
+type behaviour func(g *Game, c *creature) Action
+
+// All behaviours that can be used in the datafiles, keyed by name
+var behaviours = map[string]behaviour{
+	"idle":     behaviourIdle,
+	"wander":   behaviourWander,
+	"hunt":     behaviourHunt,
+	"flee":     behaviourFlee,
+	"distance": behaviourKeepDistance,
+}
+
+const (
+	defaultSight      = 6  // Default distance creatures can see
+	defaultFleeHealth = 25 // Default % of max HP at which creatures with the flee behaviour run
+	defaultDistance   = 3  // Default distance creatures with the distance behaviour keep from the player
+	wanderChance      = 50 // Chance a wandering creature moves rather than stands still
+)
+
+// Called by the scheduler when the creature has enough energy to act
+// Returns the energy spent, if no behaviour decides to do anything the creature waits
+func (c *creature) takeTurn(g *Game) int {
+	// The onTurn hook can stop the AI, or the creature may have been killed by it
+	if g.runCreatureHook(c, "onTurn", c.hooks.onTurn, nil) || c.currentTile == nil {
+		return energyPerTurn
+	}
+
+	for _, name := range c.ai {
+		b, ok := behaviours[name]
+		if !ok {
+			continue
+		}
+
+		action := b(g, c)
+		if action == nil {
+			continue
+		}
+
+		if result := action.Execute(g); result.Success {
+			return result.EnergySpent
+		}
+	}
+
+	return energyPerTurn
+}
+
+// Check if the creature can see the player, and if so remember where they were
+func (c *creature) canSeePlayer(g *Game) bool {
+	if c.statuses.has(statusBlindness) {
+		return false
+	}
+
+	if g.gameMap.canSee(*c.pos, g.player.pos, c.sight) {
+		lastSeen := g.player.pos
+		c.lastSeenPlayer = &lastSeen
+		return true
+	}
+
+	return false
+}
+
+// Work out the direction of the next step along the shortest path to a target
+func (c *creature) stepTowards(g *Game, target core.Pos) (core.Direction, bool) {
+	path := g.gameMap.pathGrid(c).AStar(*c.pos, target)
+	if len(path) == 0 || path[0] == g.player.pos {
+		return -1, false
+	}
+
+	return c.pos.DirectionTo(path[0])
+}
+
+// Work out the direction of the next step away from a threat, using a flee map
+// so creatures head for open space rather than backing into dead ends
+func (c *creature) stepAwayFrom(g *Game, threat core.Pos) (core.Direction, bool) {
+	flee := g.gameMap.pathGrid(c).DijkstraMap(threat).Flee()
+
+	next, ok := flee.NextStep(*c.pos)
+	if !ok || next == g.player.pos {
+		return -1, false
+	}
+
+	return c.pos.DirectionTo(next)
+}
+
+// ===== Behaviours ===========================================================
+
+// Does nothing, the creature stays where it is
+func behaviourIdle(g *Game, c *creature) Action {
+	return newWaitAction(c)
+}
+
+// Randomly shuffles around the map
+func behaviourWander(g *Game, c *creature) Action {
+	if !g.rng.Chance(wanderChance) {
+		return newWaitAction(c)
+	}
+
+	dir := core.Directions[g.rng.IntN(len(core.Directions))]
+	t := g.gameMap.AdjacentTile(c.currentTile, dir)
+	if t == nil || t.BlocksMove() || t.pos == g.player.pos {
+		return newWaitAction(c)
+	}
+
+	return newMoveActionFor(c, dir)
+}
+
+// Chases the player when they are seen and attacks when next to them
+// When the player is lost sight of, heads to the place they were last seen
+func behaviourHunt(g *Game, c *creature) Action {
+	if !c.hostile {
+		return nil
+	}
+
+	if c.canSeePlayer(g) && c.pos.IsNeighbour(g.player.pos) {
+		return newAttackActionFor(c, g.player)
+	}
+
+	if c.lastSeenPlayer == nil {
+		return nil
+	}
+
+	// Reached the place the player was last seen, so give up the chase
+	if *c.pos == *c.lastSeenPlayer {
+		c.lastSeenPlayer = nil
+		return nil
+	}
+
+	if dir, ok := c.stepTowards(g, *c.lastSeenPlayer); ok {
+		return newMoveActionFor(c, dir)
+	}
+
+	return nil
+}
+
+// Runs away from the player when health is low
+func behaviourFlee(g *Game, c *creature) Action {
+	if c.hp*100 > c.maxHP*c.fleeHealth {
+		c.fleeing = false
+		return nil
+	}
+
+	if !c.canSeePlayer(g) {
+		return nil
+	}
+
+	if dir, ok := c.stepAwayFrom(g, g.player.pos); ok {
+		if !c.fleeing {
+			c.fleeing = true
+			events.new(EventCreatureFlee, c, fmt.Sprintf("The %s turns to flee!", c.Name()))
+		}
+
+		return newMoveActionFor(c, dir)
+	}
+
+	// Cornered, so let the other behaviours decide what to do
+	return nil
+}
+
+// Stays a set distance away from the player, backing off when they get too close
+func behaviourKeepDistance(g *Game, c *creature) Action {
+	if c.keepDistance <= 0 || !c.canSeePlayer(g) {
+		return nil
+	}
+
+	dist := c.pos.Distance(g.player.pos)
+	if dist > float64(c.keepDistance) {
+		return nil
+	}
+
+	if dist < float64(c.keepDistance) {
+		if dir, ok := c.stepAwayFrom(g, g.player.pos); ok {
+			return newMoveActionFor(c, dir)
+		}
+	}
+
+	return newWaitAction(c)
+}
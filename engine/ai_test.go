@@ -0,0 +1,57 @@
+package engine
+
+// ============================================================================
+// Tests for creature AI behaviours
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+func TestBehaviours(t *testing.T) {
+	tests := []struct {
+		name  string
+		ai    string
+		start core.Pos
+		setup func(c *creature)
+		check func(before, after float64) bool
+		want  string
+	}{
+		{"hunt", "hunt", core.Pos{X: 0, Y: 4}, nil,
+			func(before, after float64) bool { return after < before }, "closer to the player"},
+		{"flee", "flee", core.Pos{X: 3, Y: 4}, func(c *creature) { c.hp = c.maxHP*c.fleeHealth/100 - 1 },
+			func(before, after float64) bool { return after > before }, "further from the player"},
+		{"flee when healthy", "flee", core.Pos{X: 3, Y: 4}, nil,
+			func(before, after float64) bool { return after == before }, "where it was"},
+		{"distance too close", "distance", core.Pos{X: 2, Y: 4}, nil,
+			func(before, after float64) bool { return after > before }, "further from the player"},
+		{"distance held", "distance", core.Pos{X: 1, Y: 4}, nil,
+			func(before, after float64) bool { return after == before }, "where it was"},
+		{"idle", "idle", core.Pos{X: 1, Y: 4}, nil,
+			func(before, after float64) bool { return after == before }, "where it was"},
+	}
+
+	for _, test := range tests {
+		g := testGame(t)
+		c := targetRat(g, test.start.X, test.start.Y)
+		c.ai = []string{test.ai}
+		c.hp, c.maxHP = 100, 100
+		c.hostile = true
+		c.sight = 8
+		c.fleeHealth = 25
+		c.keepDistance = 3
+
+		if test.setup != nil {
+			test.setup(c)
+		}
+
+		before := c.pos.Distance(g.player.pos)
+		c.takeTurn(g)
+		after := c.pos.Distance(g.player.pos)
+
+		if !test.check(before, after) {
+			t.Errorf("%s: creature should end up %s, distance went from %.1f to %.1f", test.name, test.want, before, after)
+		}
+	}
+}
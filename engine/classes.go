@@ -0,0 +1,155 @@
+package engine
+
+// ============================================================================
+// Character classes the player picks from when starting a new game
+// Each class sets the player's base stats and starting kit, see classes.yaml
+// ============================================================================
+
+import (
+	"fmt"
+	"maps"
+	"roguelike/core"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Class is a type of character the player can start as
+type Class struct {
+	id          string
+	name        string
+	description string
+
+	hp          int
+	toHit       int
+	damage      int
+	defence     int
+	speed       int
+	fovDistance int
+	mana        int
+	items       []string // Ids of the items in the starting kit
+	spells      []string // Ids of the spells the class starts knowing
+}
+
+type yamlClass struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	HP          int      `yaml:"hp"`
+	ToHit       int      `yaml:"toHit"`
+	Damage      int      `yaml:"damage"`
+	Defence     int      `yaml:"defence"`
+	Speed       int      `yaml:"speed"`
+	FOVDistance int      `yaml:"fovDistance"`
+	Mana        int      `yaml:"mana"`
+	Items       []string `yaml:"items"`
+	Spells      []string `yaml:"spells"`
+}
+
+type yamlClassesFile struct {
+	DefaultClass string               `yaml:"defaultClass"`
+	Classes      map[string]yamlClass `yaml:"classes"`
+}
+
+type classList struct {
+	classes      map[string]*Class
+	keys         []string
+	defaultClass string // Used when no class is picked
+}
+
+func loadClasses(dataFile string) (*classList, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlClassesFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &classList{classes: make(map[string]*Class), defaultClass: file.DefaultClass}
+	for id, entry := range file.Classes {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("class '%s' %s", id, problems[0])
+		}
+
+		cl.classes[id] = &Class{
+			id:          id,
+			name:        entry.Name,
+			description: entry.Description,
+			hp:          entry.HP,
+			toHit:       entry.ToHit,
+			damage:      entry.Damage,
+			defence:     entry.Defence,
+			speed:       entry.Speed,
+			fovDistance: entry.FOVDistance,
+			mana:        entry.Mana,
+			items:       entry.Items,
+			spells:      entry.Spells,
+		}
+	}
+
+	if _, ok := cl.classes[cl.defaultClass]; !ok {
+		return nil, fmt.Errorf("default class '%s' is not one of the classes", cl.defaultClass)
+	}
+
+	cl.keys = slices.Sorted(maps.Keys(cl.classes))
+	return cl, nil
+}
+
+// Find a class by id, an empty id is the default class
+func (cl *classList) get(id string) (*Class, error) {
+	if id == "" {
+		id = cl.defaultClass
+	}
+
+	class, ok := cl.classes[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown class '%s'", id)
+	}
+
+	return class, nil
+}
+
+// LoadClasses lists the classes a new game can be started with, the default class is first
+// Pass the ID of the one picked to NewGame
+func LoadClasses(dataFileDir string) ([]*Class, error) {
+	cl, err := loadClasses(dataFileDir + "/classes.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	classes := []*Class{cl.classes[cl.defaultClass]}
+	for _, id := range cl.keys {
+		if id != cl.defaultClass {
+			classes = append(classes, cl.classes[id])
+		}
+	}
+
+	return classes, nil
+}
+
+func (c Class) ID() string {
+	return c.id
+}
+
+func (c Class) Name() string {
+	return c.name
+}
+
+func (c Class) Description() string {
+	return c.description
+}
+
+// Give the player the base stats of their class
+func (c *Class) applyStats(p *Player) {
+	p.class = c
+	p.hp = c.hp
+	p.base = playerStats{maxHP: c.hp, attackChance: c.toHit, attackDamage: c.damage, defence: c.defence}
+	p.speed = c.speed
+	p.fovDistance = c.fovDistance
+	p.mana = c.mana
+	p.maxMana = c.mana
+	p.spells = slices.Clone(c.spells)
+}
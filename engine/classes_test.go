@@ -0,0 +1,43 @@
+package engine
+
+// ============================================================================
+// Tests for character classes & starting kits
+// ============================================================================
+
+import "testing"
+
+func TestClassStartingKit(t *testing.T) {
+	g := NewGame("../assets/datafiles", 12, "rogue")
+	p := g.player
+	rogue := g.classes.classes["rogue"]
+
+	if p.ClassName() != "Rogue" || p.speed != rogue.speed || p.fovDistance != rogue.fovDistance || p.maxHP != rogue.hp {
+		t.Errorf("player should have the rogue's stats, got speed %d, fov %d, max HP %d", p.speed, p.fovDistance, p.maxHP)
+	}
+
+	equipped := map[string]bool{}
+	for _, i := range p.equipped() {
+		equipped[i.id] = true
+	}
+
+	if !equipped["dagger"] || !equipped["leather_armour"] || !equipped["bow"] || len(p.backpack) != 3 {
+		t.Errorf("rogue should wear their dagger, bow & armour and carry a stack of arrows & 2 potions, equipped %v, backpack %d", equipped, len(p.backpack))
+	}
+
+	if arrows := p.ammoFor(p.launcher()); arrows == nil || arrows.quantity != 4 {
+		t.Errorf("the rogue's 4 arrows should be in a single stack")
+	}
+
+	// The dagger's bonus to hit goes on top of the class's base chance
+	if p.attackChance != rogue.toHit+15 {
+		t.Errorf("equipped dagger should add to the rogue's hit chance, got %d%%", p.attackChance)
+	}
+
+	if r := g.Replay(); r.Class != "rogue" {
+		t.Errorf("replay should record the class, got '%s'", r.Class)
+	}
+
+	if def := NewGame("../assets/datafiles", 12, ""); def.player.class.id != g.classes.defaultClass {
+		t.Errorf("an empty class should give the default class, got '%s'", def.player.class.id)
+	}
+}
@@ -0,0 +1,197 @@
+package engine
+
+// ============================================================================
+// Combat resolution, works out the result of one thing attacking another
+// The rules are loaded from combat.yaml, and every attack gives back a
+// CombatResult so the outcome can be checked by tests or a balance simulator
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Base chance a creature's attack hits, before the defender's defence
+const creatureHitChance = 60
+
+// CombatRules control how attacks are resolved, all chances are percentages
+type CombatRules struct {
+	CritChance        int `yaml:"critChance"`        // Chance any attack is a critical hit
+	CritMultiplier    int `yaml:"critMultiplier"`    // Damage is multiplied by this on a critical hit
+	FumbleChance      int `yaml:"fumbleChance"`      // Chance any attack is fumbled and misses
+	DefenceHitPenalty int `yaml:"defenceHitPenalty"` // Each point of defence reduces the chance to be hit by this
+	DefenceAbsorb     int `yaml:"defenceAbsorb"`     // Percentage of defence taken off the damage of every hit
+}
+
+// CombatResult is the outcome of a single attack
+type CombatResult struct {
+	Attacker  string
+	Defender  string
+	HitChance int  // Chance the attack had to hit, after the defender's defence
+	HitRoll   int  // The d100 roll, rolling equal or under the hit chance is a hit
+	Hit       bool // Did the attack hit, this includes critical hits
+	Critical  bool
+	Fumble    bool
+	Damage    int  // Damage done after any absorbed by defence
+	Absorbed  int  // Damage soaked up by the defender's defence
+	HPLeft    int  // Defender's HP after the attack
+	Killed    bool // Did the attack kill the defender
+}
+
+// Stats used when something attacks or is attacked
+type combatStats struct {
+	hitChance   int
+	damage      DiceRoll
+	damageBonus int
+	defence     int
+}
+
+// Anything that can fight, the player or creatures
+type combatant interface {
+	Name() string
+	HP() int
+	combatStats() combatStats
+	applyDamage(amount int)
+}
+
+type yamlCombatFile struct {
+	Combat CombatRules `yaml:"combat"`
+}
+
+func loadCombatRules(dataFile string) (CombatRules, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return CombatRules{}, err
+	}
+
+	var file yamlCombatFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return CombatRules{}, err
+	}
+
+	file.Combat.CritMultiplier = max(file.Combat.CritMultiplier, 1)
+	return file.Combat, nil
+}
+
+// Resolve a single attack, the damage is applied to the defender
+func resolveAttack(r *gameRNG, rules CombatRules, attacker, defender combatant) CombatResult {
+	att := attacker.combatStats()
+	def := defender.combatStats()
+
+	res := CombatResult{
+		Attacker:  attacker.Name(),
+		Defender:  defender.Name(),
+		HitChance: att.hitChance - def.defence*rules.DefenceHitPenalty,
+		HitRoll:   d100.Roll(r),
+	}
+
+	// Rolling low is good, the lowest rolls are criticals and the highest are fumbles
+	switch {
+	case res.HitRoll <= rules.CritChance:
+		res.Hit = true
+		res.Critical = true
+	case res.HitRoll > 100-rules.FumbleChance:
+		res.Fumble = true
+	default:
+		res.Hit = res.HitRoll <= res.HitChance
+	}
+
+	if !res.Hit {
+		res.HPLeft = defender.HP()
+		return res
+	}
+
+	damage := att.damage.Roll(r) + att.damageBonus
+	if res.Critical {
+		damage *= rules.CritMultiplier
+	}
+
+	res.Absorbed = core.MinInt(def.defence*rules.DefenceAbsorb/100, max(damage, 0))
+	res.Damage = max(damage-res.Absorbed, 0)
+	defender.applyDamage(res.Damage)
+	res.HPLeft = defender.HP()
+	res.Killed = res.HPLeft <= 0
+
+	return res
+}
+
+// Describe how hurt something is, used for wounded messages, blank if not badly hurt
+func woundDescription(hp, maxHP int) string {
+	switch {
+	case hp*100 <= maxHP*25:
+		return "is nearly dead"
+	case hp*100 <= maxHP*50:
+		return "is badly wounded"
+	}
+
+	return ""
+}
+
+// ===== Combatants ===========================================================
+
+func (p *Player) combatStats() combatStats {
+	return combatStats{
+		hitChance:   p.attackChance,
+		damage:      p.attackRoll,
+		damageBonus: p.attackDamage,
+		defence:     p.defence,
+	}
+}
+
+func (p *Player) applyDamage(amount int) {
+	p.hp -= amount
+}
+
+func (p *Player) heal(amount int) {
+	p.hp = min(p.hp+amount, p.maxHP)
+}
+
+func (c *creature) combatStats() combatStats {
+	return combatStats{
+		hitChance: creatureHitChance,
+		damage:    c.attack,
+		defence:   c.defence,
+	}
+}
+
+func (c *creature) applyDamage(amount int) {
+	c.hp -= amount
+}
+
+func (c *creature) heal(amount int) {
+	c.hp = min(c.hp+amount, c.maxHP)
+}
+
+// Sentence describing an attack result for the event log, from the player's point of view
+func (res CombatResult) describe(playerAttacking bool) string {
+	if playerAttacking {
+		switch {
+		case res.Fumble:
+			return "You fumble your attack!"
+		case !res.Hit:
+			return fmt.Sprintf("You miss the %s", res.Defender)
+		case res.Damage == 0:
+			return fmt.Sprintf("You hit the %s, but do no harm", res.Defender)
+		case res.Critical:
+			return fmt.Sprintf("A critical hit! You strike the %s for %d damage", res.Defender, res.Damage)
+		}
+
+		return fmt.Sprintf("You hit the %s for %d damage", res.Defender, res.Damage)
+	}
+
+	switch {
+	case res.Fumble:
+		return fmt.Sprintf("The %s stumbles", res.Attacker)
+	case !res.Hit:
+		return fmt.Sprintf("The %s misses you", res.Attacker)
+	case res.Damage == 0:
+		return fmt.Sprintf("The %s hits you, but your armour absorbs the blow", res.Attacker)
+	case res.Critical:
+		return fmt.Sprintf("The %s lands a critical hit for %d damage!", res.Attacker, res.Damage)
+	}
+
+	return fmt.Sprintf("The %s hits you for %d damage", res.Attacker, res.Damage)
+}
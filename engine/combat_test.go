@@ -0,0 +1,191 @@
+package engine
+
+// ============================================================================
+// Tests for combat resolution, plus a simple balance simulation
+// ============================================================================
+
+import (
+	"strings"
+	"testing"
+)
+
+// Simple combatant with fixed stats for testing
+type testFighter struct {
+	hp    int
+	stats combatStats
+}
+
+func (f *testFighter) Name() string {
+	return "tester"
+}
+
+func (f *testFighter) HP() int {
+	return f.hp
+}
+
+func (f *testFighter) combatStats() combatStats {
+	return f.stats
+}
+
+func (f *testFighter) applyDamage(amount int) {
+	f.hp -= amount
+}
+
+func TestResolveAttackCritical(t *testing.T) {
+	r := newRNG(1)
+	rules := CombatRules{CritChance: 100, CritMultiplier: 3}
+	attacker := &testFighter{hp: 10, stats: combatStats{hitChance: 0, damage: DiceRoll{0, 0, 2}, damageBonus: 1}}
+	defender := &testFighter{hp: 20}
+
+	res := resolveAttack(r, rules, attacker, defender)
+	if !res.Hit || !res.Critical || res.Damage != 9 || res.HPLeft != 11 {
+		t.Errorf("resolveAttack critical = %+v; want hit, critical, 9 damage, 11 HP left", res)
+	}
+}
+
+func TestResolveAttackFumble(t *testing.T) {
+	r := newRNG(1)
+	rules := CombatRules{FumbleChance: 100, CritMultiplier: 1}
+	attacker := &testFighter{hp: 10, stats: combatStats{hitChance: 100, damage: DiceRoll{1, 6, 0}}}
+	defender := &testFighter{hp: 20}
+
+	for i := 0; i < 100; i++ {
+		res := resolveAttack(r, rules, attacker, defender)
+		if res.Hit || !res.Fumble || res.HPLeft != 20 {
+			t.Fatalf("resolveAttack fumble = %+v; want a fumble with no damage", res)
+		}
+	}
+}
+
+func TestResolveAttackDefence(t *testing.T) {
+	r := newRNG(1)
+	rules := CombatRules{DefenceHitPenalty: 5, DefenceAbsorb: 50, CritMultiplier: 1}
+	attacker := &testFighter{hp: 10, stats: combatStats{hitChance: 150, damage: DiceRoll{0, 0, 5}}}
+	defender := &testFighter{hp: 100, stats: combatStats{defence: 4}}
+
+	res := resolveAttack(r, rules, attacker, defender)
+	if res.HitChance != 130 {
+		t.Errorf("resolveAttack HitChance = %d; want 130", res.HitChance)
+	}
+
+	if !res.Hit || res.Absorbed != 2 || res.Damage != 3 {
+		t.Errorf("resolveAttack = %+v; want hit, 2 absorbed, 3 damage", res)
+	}
+
+	// Enough defence should make it impossible to hit, without criticals
+	defender.stats.defence = 40
+	for i := 0; i < 100; i++ {
+		if res := resolveAttack(r, rules, attacker, defender); res.Hit {
+			t.Fatalf("resolveAttack hit through 40 defence = %+v", res)
+		}
+	}
+}
+
+func TestCreatureHPAcrossHits(t *testing.T) {
+	r := newRNG(1)
+	rules := CombatRules{CritMultiplier: 1}
+	attacker := &testFighter{hp: 10, stats: combatStats{hitChance: 200, damage: DiceRoll{0, 0, 3}}}
+	target := &creature{hp: 10, maxHP: 10}
+
+	for hit := 1; hit <= 4; hit++ {
+		res := resolveAttack(r, rules, attacker, target)
+		wantHP := 10 - hit*3
+		if res.HPLeft != wantHP || target.hp != wantHP {
+			t.Fatalf("hit %d left creature with %d HP; want %d", hit, target.hp, wantHP)
+		}
+
+		if res.Killed != (hit == 4) {
+			t.Fatalf("hit %d Killed = %v; want %v", hit, res.Killed, hit == 4)
+		}
+	}
+}
+
+// Fight a freshly made player with a sword against every creature many times
+// and check the weakest creatures lose most of the time
+func TestCombatBalance(t *testing.T) {
+	r := newRNG(1)
+
+	rules, err := loadCombatRules("../assets/datafiles/combat.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creatureGen, err := newCreatureGenerator("../assets/datafiles/creatures.yaml", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const fights = 200
+	for _, id := range creatureGen.keys {
+		wins := 0
+		for i := 0; i < fights; i++ {
+			if simulateFight(r, rules, creatureGen.createCreature(id)) {
+				wins++
+			}
+		}
+
+		t.Logf("Player vs %-14s won %3d%%", id, wins*100/fights)
+		if id == "rat" && wins*100/fights < 90 {
+			t.Errorf("Player only beat a rat %d out of %d times", wins, fights)
+		}
+	}
+}
+
+// Fight to the death, the player swings first, returns true if the player won
+func simulateFight(r *gameRNG, rules CombatRules, c *creature) bool {
+	m := NewMap(3, 3, 1)
+	m.tiles[1][1].makeFloor()
+	p := NewPlayer(r, m.Tile(1, 1), nil)
+
+	p.attackRoll = DiceRoll{1, 6, 0}
+	p.attackChance += 10
+
+	for round := 0; round < 1000; round++ {
+		if res := resolveAttack(r, rules, p, c); res.Killed {
+			return true
+		}
+
+		if res := resolveAttack(r, rules, c, p); res.Killed {
+			return false
+		}
+	}
+
+	return false
+}
+
+func TestCreatureKillsPlayer(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+
+	// Every attack hits for exactly 10, before the player's defence
+	g.combatRules = CombatRules{CritChance: 100, CritMultiplier: 1, DefenceAbsorb: 50}
+	rat := targetRat(g, 4, 3)
+	rat.ai = []string{"hunt"}
+	rat.hostile = true
+	rat.attack, _ = ParseDiceRoll("1d1+9")
+	rat.speed, rat.energy = speedNormal, 0
+
+	p.hp = 5
+	p.defence = 4
+
+	var died *GameEvent
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventPlayerDied {
+			died = &e
+		}
+	})
+
+	g.ProcessAction(NewWaitAction())
+
+	if p.hp != 5-(10-2) {
+		t.Errorf("defence 4 should absorb 2 of the 10 damage, player has %d HP", p.hp)
+	}
+
+	if died == nil || died.Entity() != entity(rat) || !strings.Contains(died.Text(), "rat") || p.KilledBy() != "a rat" {
+		t.Fatalf("the player died event should say the rat did it, got %v", died)
+	}
+
+	if !g.IsOver() || g.ProcessAction(NewWaitAction()).Success {
+		t.Errorf("the game should be over once the player is dead")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"roguelike/core"
 	"slices"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,12 +17,30 @@ type creature struct {
 	entityBase
 
 	currentTile *tile
-	hp          int //nolint
+	hp          int
+	maxHP       int
 	xp          int //nolint
-	depth       int //nolint
+	depth       int
+	hostile     bool
 
 	defence int      //nolint
-	attack  DiceRoll //nolint
+	attack  DiceRoll // Damage done when the creature hits
+
+	lootTable string // Loot table for items dropped when killed, none if empty
+	hooks     creatureHooks
+	statuses  statusList // Timed effects such as poison, see status.go
+
+	// Used by the turn scheduler
+	energy int
+	speed  int
+
+	// Used by the AI, see ai.go
+	ai             []string // Names of behaviours, in priority order
+	sight          int      // How far the creature can see
+	fleeHealth     int      // Percentage of max HP at which the creature will flee
+	keepDistance   int      // Distance the creature tries to keep from the player
+	lastSeenPlayer *pos     // Where the player was last seen, nil if not seen
+	fleeing        bool
 }
 
 func (c creature) String() string {
@@ -40,13 +59,45 @@ func (c creature) BlocksMove() bool {
 	return true
 }
 
+func (c creature) HP() int {
+	return c.hp
+}
+
+func (c creature) MaxHP() int {
+	return c.maxHP
+}
+
+func (c *creature) Tile() *tile {
+	return c.currentTile
+}
+
+func (c *creature) Pos() core.Pos {
+	return *c.pos
+}
+
+func (c *creature) moveToTile(t *tile) {
+	c.currentTile.creature = nil
+	t.placeCreature(c)
+}
+
+func (c *creature) activeStatuses() statusList {
+	return c.statuses
+}
+
 // ===== Creature Generator =================================================================================================
 
 type creatureGenerator struct {
+	rng          *gameRNG
 	genFunctions map[string](func() *creature)
 	keys         []string
+	rarity       map[string]rarity
+	minDepth     map[string]int // Shallowest level each creature is native to
+	maxDepth     map[string]int // Deepest level each creature is native to, zero means no limit
 }
 
+// Percentage extra HP a creature gets for each level deeper than its minDepth
+const depthScaling = 10
+
 type yamlCreature struct {
 	// Entity fields
 	Description string `yaml:"description"`
@@ -55,16 +106,38 @@ type yamlCreature struct {
 	Colour      string `yaml:"colour"`
 
 	// Creature specific fields
-	Hostile bool `yaml:"hostile"`
-	Hp      int  `yaml:"hp"`
-	Xp      int  `yaml:"xp"`
+	Hostile bool   `yaml:"hostile"`
+	Hp      int    `yaml:"hp"`
+	Xp      int    `yaml:"xp"`
+	Speed   int    `yaml:"speed"`
+	Attack  string `yaml:"attack"` // Dice notation, a plain number N is the same as 1dN
+	Defence int    `yaml:"defense"`
+
+	// Generation fields, the same as items
+	Rarity   string `yaml:"rarity"`
+	MinDepth int    `yaml:"minDepth"`
+	MaxDepth int    `yaml:"maxDepth"`
+
+	LootTable string `yaml:"lootTable"`
+
+	// Hook scripts, see hooks.go
+	OnSpawn string `yaml:"onSpawn"`
+	OnTurn  string `yaml:"onTurn"`
+	OnHit   string `yaml:"onHit"`
+	OnDeath string `yaml:"onDeath"`
+
+	// AI fields, see ai.go
+	AI           []string `yaml:"ai"`
+	Sight        int      `yaml:"sight"`
+	FleeHealth   int      `yaml:"fleeHealth"`
+	KeepDistance int      `yaml:"keepDistance"`
 }
 
 type yamlCreaturesFile struct {
 	Creatures map[string]yamlCreature `yaml:"creatures"`
 }
 
-func newCreatureGenerator(dataFile string) (*creatureGenerator, error) {
+func newCreatureGenerator(dataFile string, r *gameRNG) (*creatureGenerator, error) {
 	data, err := core.ReadFile(dataFile)
 	if err != nil {
 		return nil, err
@@ -77,27 +150,77 @@ func newCreatureGenerator(dataFile string) (*creatureGenerator, error) {
 	}
 
 	gen := creatureGenerator{
+		rng:          r,
 		genFunctions: make(map[string](func() *creature)),
 		keys:         make([]string, 0),
+		rarity:       make(map[string]rarity),
+		minDepth:     make(map[string]int),
+		maxDepth:     make(map[string]int),
 	}
 
 	for id, creat := range file.Creatures {
+		if problems := creat.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("creature '%s' %s", id, problems[0])
+		}
+
+		attack, _ := parseAttack(creat.Attack)
+		creatRarity, _ := parseRarity(creat.Rarity)
+
+		if creat.Speed <= 0 {
+			creat.Speed = speedNormal
+		}
+
+		// Creatures without any AI set in the datafile get some sensible defaults
+		if len(creat.AI) == 0 {
+			creat.AI = []string{"wander"}
+			if creat.Hostile {
+				creat.AI = []string{"hunt", "wander"}
+			}
+		}
+
+		if creat.Sight <= 0 {
+			creat.Sight = defaultSight
+		}
+
+		if creat.FleeHealth <= 0 {
+			creat.FleeHealth = defaultFleeHealth
+		}
+
+		if creat.KeepDistance <= 0 {
+			creat.KeepDistance = defaultDistance
+		}
+
 		gen.genFunctions[id] = func() *creature {
 			return &creature{
 				entityBase: entityBase{
 					id:         id,
-					instanceID: core.RandId(6),
+					instanceID: core.RandId(gen.rng.Rand, 6),
 					desc:       creat.Description,
 					name:       creat.Name,
 					graphicId:  creat.Graphic,
 					colour:     creat.Colour,
 				},
-				hp: creat.Hp,
-				xp: creat.Xp,
+				hp:           creat.Hp,
+				maxHP:        creat.Hp,
+				xp:           creat.Xp,
+				hostile:      creat.Hostile,
+				attack:       attack,
+				defence:      creat.Defence,
+				speed:        creat.Speed,
+				energy:       gen.rng.IntN(energyPerTurn),
+				ai:           creat.AI,
+				sight:        creat.Sight,
+				fleeHealth:   creat.FleeHealth,
+				keepDistance: creat.KeepDistance,
+				lootTable:    creat.LootTable,
+				hooks:        creatureHooks{creat.OnSpawn, creat.OnTurn, creat.OnHit, creat.OnDeath},
 			}
 		}
 
 		gen.keys = append(gen.keys, id)
+		gen.rarity[id] = creatRarity
+		gen.minDepth[id] = creat.MinDepth
+		gen.maxDepth[id] = creat.MaxDepth
 	}
 
 	// Sort the keys as the map iteration order above is random
@@ -105,6 +228,20 @@ func newCreatureGenerator(dataFile string) (*creatureGenerator, error) {
 	return &gen, nil
 }
 
+// Creature attacks are dice notation, older datafiles used a plain number of sides
+func parseAttack(attack string) (DiceRoll, error) {
+	if dice, ok := ParseDiceRoll(attack); ok {
+		return dice, nil
+	}
+
+	sides, err := strconv.Atoi(attack)
+	if err != nil || sides <= 0 {
+		return DiceRoll{}, fmt.Errorf("bad attack '%s', it should be dice like 1d6", attack)
+	}
+
+	return DiceRoll{1, sides, 0}, nil
+}
+
 func (gen creatureGenerator) createCreature(id string) *creature {
 	genFunc, ok := gen.genFunctions[id]
 	if !ok {
@@ -115,12 +252,58 @@ func (gen creatureGenerator) createCreature(id string) *creature {
 	return genFunc()
 }
 
-func (gen creatureGenerator) createRandomCreature() *creature {
-	if len(gen.genFunctions) == 0 {
+// Create a random creature for a level at the given depth, picked by rarity from
+// the creatures native to that depth. Creatures deeper than their minDepth get extra HP
+func (gen creatureGenerator) createRandomCreature(depth int) *creature {
+	candidates := gen.nativeTo(depth, true)
+	if len(candidates) == 0 {
+		// Nothing lives this deep, so use anything that could be found here
+		candidates = gen.nativeTo(depth, false)
+	}
+
+	if len(candidates) == 0 {
+		candidates = gen.keys
+	}
+
+	totalWeight := 0
+	for _, id := range candidates {
+		totalWeight += gen.rarity[id].weight()
+	}
+
+	if totalWeight == 0 {
 		return nil
 	}
 
-	// Get a random creature, we have to use the keys slice as the map iteration order is random
-	id := gen.keys[rng.IntN(len(gen.keys))]
-	return gen.createCreature(id)
+	pick := gen.rng.IntN(totalWeight)
+	for _, id := range candidates {
+		pick -= gen.rarity[id].weight()
+		if pick >= 0 {
+			continue
+		}
+
+		c := gen.createCreature(id)
+		c.depth = depth
+		if extra := depth - max(gen.minDepth[id], 1); extra > 0 {
+			c.maxHP += c.maxHP * extra * depthScaling / 100
+			c.hp = c.maxHP
+		}
+
+		return c
+	}
+
+	return nil
+}
+
+// Creatures whose depth range includes the given depth, optionally ignoring maxDepth
+func (gen creatureGenerator) nativeTo(depth int, useMax bool) []string {
+	ids := make([]string, 0)
+	for _, id := range gen.keys {
+		if depth < gen.minDepth[id] || (useMax && gen.maxDepth[id] > 0 && depth > gen.maxDepth[id]) {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
 }
@@ -17,6 +17,7 @@ const (
 	effectTypeAttackChance
 	effectTypeAttackDamage
 	effectTypeAttackRoll
+	effectTypeMaxHP
 )
 
 type effect struct {
@@ -25,47 +26,52 @@ type effect struct {
 	roll       *DiceRoll
 }
 
-func newEffect(effectName string, effectValue string) *effect {
-	val, _ := strconv.ParseInt(effectValue, 10, 64)
-	roll, _ := ParseDiceRoll(effectValue)
+// Effects with a numeric value, the attack effect is a dice roll
+var numericEffects = map[string]effectType{
+	"defence": effectTypeDefence,
+	"toHit":   effectTypeAttackChance,
+	"damage":  effectTypeAttackDamage,
+	"maxHP":   effectTypeMaxHP,
+}
+
+func newEffect(effectName string, effectValue string) (*effect, error) {
+	if effectName == "attack" {
+		roll, ok := ParseDiceRoll(effectValue)
+		if !ok {
+			return nil, fmt.Errorf("bad dice roll '%s'", effectValue)
+		}
 
-	switch effectName {
-	case "defence":
-		return &effect{effectTypeDefence, int(val), nil}
-	case "attack":
-		return &effect{effectTypeAttackRoll, 0, &roll}
-	case "toHit":
-		return &effect{effectTypeAttackChance, int(val), nil}
-	case "damage":
-		return &effect{effectTypeAttackDamage, int(val), nil}
+		return &effect{effectTypeAttackRoll, 0, &roll}, nil
 	}
 
-	return &effect{effectTypeNone, 0, nil}
-}
+	effectType, ok := numericEffects[effectName]
+	if !ok {
+		return nil, fmt.Errorf("unknown effect '%s'", effectName)
+	}
 
-func (e effect) apply(p *Player) {
-	switch e.effectType {
-	case effectTypeDefence:
-		p.defence += e.value
-	case effectTypeAttackChance:
-		p.attackChance += e.value
-	case effectTypeAttackDamage:
-		p.attackDamage += e.value
-	case effectTypeAttackRoll:
-		p.attackRoll = *e.roll
+	val, err := strconv.Atoi(effectValue)
+	if err != nil {
+		return nil, fmt.Errorf("effect '%s' needs a number, not '%s'", effectName, effectValue)
 	}
+
+	return &effect{effectType, val, nil}, nil
 }
 
-func (e effect) remove(p *Player) {
+// Add the effect to a set of stats, attack rolls don't add up so the better roll is kept
+func (e effect) apply(s *playerStats) {
 	switch e.effectType {
 	case effectTypeDefence:
-		p.defence -= e.value
+		s.defence += e.value
 	case effectTypeAttackChance:
-		p.attackChance -= e.value
+		s.attackChance += e.value
 	case effectTypeAttackDamage:
-		p.attackDamage -= e.value
+		s.attackDamage += e.value
 	case effectTypeAttackRoll:
-		p.attackRoll = DiceRoll{0, 0, 0}
+		if e.roll.average() > s.attackRoll.average() {
+			s.attackRoll = *e.roll
+		}
+	case effectTypeMaxHP:
+		s.maxHP += e.value
 	}
 }
 
@@ -84,6 +90,8 @@ func (e effect) description() string {
 		return fmt.Sprintf("%sdam", mod)
 	case effectTypeAttackRoll:
 		return e.roll.String()
+	case effectTypeMaxHP:
+		return fmt.Sprintf("%shp", mod)
 	}
 
 	return "Unknown"
@@ -16,6 +16,7 @@ const (
 	entityTypeCreature entityType = iota
 	entityTypeItem
 	entityTypeFurniture
+	entityTypeTrap
 )
 
 type entityBase struct {
@@ -0,0 +1,297 @@
+package engine
+
+// ============================================================================
+// Equipment slots the player has, and the stats their equipment gives them
+// Every slot holds one item, items go in any slot for their equip location,
+// e.g. rings can be worn on either hand. Two-handed weapons need the shield
+// slot to be empty. The player's stats are worked out from their base stats
+// plus everything equipped, so the order items are put on doesn't matter
+// ============================================================================
+
+import (
+	"fmt"
+	"slices"
+)
+
+type equipSlot int
+
+const (
+	slotWeapon equipSlot = iota
+	slotMissile
+	slotBody
+	slotShield
+	slotHead
+	slotFeet
+	slotHands
+	slotLeftRing
+	slotRightRing
+	slotNeck
+	equipSlotCount
+)
+
+// Used by EquipAction when the game should pick the slot
+const slotAny equipSlot = -1
+
+// Which slots items for each equip location can go in, in the order they are filled
+var equipLocationSlots = map[equipLocation][]equipSlot{
+	equipLocationWeapon:  {slotWeapon},
+	equipLocationMissile: {slotMissile},
+	equipLocationBody:    {slotBody},
+	equipLocationShield:  {slotShield},
+	equipLocationHead:    {slotHead},
+	equipLocationFeet:    {slotFeet},
+	equipLocationHands:   {slotHands},
+	equipLocationFinger:  {slotLeftRing, slotRightRing},
+	equipLocationNeck:    {slotNeck},
+}
+
+func (s equipSlot) String() string {
+	switch s {
+	case slotWeapon:
+		return "weapon"
+	case slotMissile:
+		return "missile"
+	case slotBody:
+		return "body"
+	case slotShield:
+		return "shield"
+	case slotHead:
+		return "head"
+	case slotFeet:
+		return "feet"
+	case slotHands:
+		return "hands"
+	case slotLeftRing:
+		return "left ring"
+	case slotRightRing:
+		return "right ring"
+	case slotNeck:
+		return "neck"
+	default:
+		return "none"
+	}
+}
+
+func parseEquipSlot(name string) (equipSlot, bool) {
+	for s := range equipSlotCount {
+		if s.String() == name {
+			return s, true
+		}
+	}
+
+	return slotAny, false
+}
+
+// Is an item in the slot held in the player's hands, rather than worn
+func (s equipSlot) wielded() bool {
+	return s == slotWeapon || s == slotShield || s == slotMissile
+}
+
+// Stats which the player's class, levels, perks & equipment add up to
+type playerStats struct {
+	maxHP        int
+	defence      int      // Defence protects against damage
+	attackDamage int      // Base damage added to weapon damage
+	attackChance int      // Base chance to hit
+	attackRoll   DiceRoll // Dice roll for attack damage, the best of any equipped
+}
+
+// Work out the player's stats from their base stats and everything equipped
+// Their HP is only capped to the new max, so swapping equipment can't heal them
+func (p *Player) recomputeStats() {
+	p.playerStats = p.base
+
+	// Slots are gone through in a fixed order, so the same equipment always gives the same stats
+	for _, item := range p.equipSlots {
+		if item == nil {
+			continue
+		}
+
+		for _, e := range item.effects {
+			e.apply(&p.playerStats)
+		}
+	}
+
+	p.hp = min(p.hp, p.maxHP)
+}
+
+// EquipSlotsFor lists the slots an item could be equipped in, more than one means the player can pick
+func (p *Player) EquipSlotsFor(item *Item) []equipSlot {
+	return equipLocationSlots[item.equipLocation]
+}
+
+// Pick a slot for an item, the first free one it can go in or the first if they are all full
+func (p *Player) freeSlotFor(item *Item) equipSlot {
+	slots := p.EquipSlotsFor(item)
+	if len(slots) == 0 {
+		return slotAny
+	}
+
+	for _, s := range slots {
+		if p.equipSlots[s] == nil {
+			return s
+		}
+	}
+
+	return slots[0]
+}
+
+// The two-handed weapon the player is wielding, if they are
+func (p *Player) twoHandedWeapon() *Item {
+	if w := p.equipSlots[slotWeapon]; w != nil && w.twoHanded {
+		return w
+	}
+
+	return nil
+}
+
+// Check an item can go in a slot, with a message if not
+func (p *Player) canEquip(item *Item, slot equipSlot) bool {
+	if !slices.Contains(p.EquipSlotsFor(item), slot) {
+		events.new(EventMiscMessage, item, fmt.Sprintf("The %s can't go there", item.Name()))
+		return false
+	}
+
+	if w := p.twoHandedWeapon(); slot == slotShield && w != nil {
+		events.new(EventMiscMessage, item, fmt.Sprintf("You need both hands to wield your %s", w.Name()))
+		return false
+	}
+
+	return true
+}
+
+// Is there room in the backpack for items coming off, when freed backpack slots are emptied first
+func (p *Player) roomFor(freed int, items ...*Item) bool {
+	needed := 0
+	for _, i := range items {
+		if p.stackFor(i) == nil {
+			needed++
+		}
+	}
+
+	return len(p.backpack)-freed+needed <= PLAYER_MAX_ITEMS
+}
+
+// Items which equipping an item in a slot would take off, what's in the slot and
+// the shield for a two-handed weapon
+func (p *Player) displacedBy(item *Item, slot equipSlot) []*Item {
+	displaced := []*Item{}
+	if i := p.equipSlots[slot]; i != nil {
+		displaced = append(displaced, i)
+	}
+
+	if s := p.equipSlots[slotShield]; item.twoHanded && s != nil && slot != slotShield {
+		displaced = append(displaced, s)
+	}
+
+	return displaced
+}
+
+// Put an item from the backpack in a slot, anything it displaces goes back in the backpack
+// Returns false if there isn't room in the backpack for what comes off
+func (p *Player) EquipItem(item *Item, slot equipSlot) bool {
+	if !p.backpack.Contains(item) || slot < 0 || slot >= equipSlotCount {
+		return false
+	}
+
+	// The item leaving the backpack frees up its slot
+	displaced := p.displacedBy(item, slot)
+	if !p.roomFor(1, displaced...) {
+		return false
+	}
+
+	p.backpack.Remove(item)
+	for _, i := range displaced {
+		s, _ := p.EquippedSlot(i)
+		p.equipSlots[s] = nil
+		i.equipped = false
+		p.addToBackpack(i)
+	}
+
+	p.equipSlots[slot] = item
+	item.equipped = true
+
+	p.recomputeStats()
+	return true
+}
+
+// Take the item out of a slot and put it in the backpack
+// Returns false if the slot is empty or there isn't room in the backpack
+func (p *Player) UnequipItem(slot equipSlot) bool {
+	if slot < 0 || slot >= equipSlotCount || p.equipSlots[slot] == nil {
+		return false
+	}
+
+	i := p.equipSlots[slot]
+	if p.addToBackpack(i) == nil {
+		return false
+	}
+
+	p.equipSlots[slot] = nil
+	i.equipped = false
+
+	p.recomputeStats()
+	return true
+}
+
+// Take off whatever is in a slot, with a message
+func (p *Player) unequip(slot equipSlot) bool {
+	i := p.equipSlots[slot]
+	if i == nil {
+		return false
+	}
+
+	if !p.UnequipItem(slot) {
+		events.new(EventPackFull, i, fmt.Sprintf("No room in your pack for the %s", i.Name()))
+		return false
+	}
+
+	unequippedMessage(i, slot)
+	return true
+}
+
+func unequippedMessage(item *Item, slot equipSlot) {
+	msg := fmt.Sprintf("The %s was taken off", item.Name())
+	if slot.wielded() {
+		msg = fmt.Sprintf("No longer wielding a %s", item.Name())
+	}
+
+	events.new(EventItemUnequipped, item, msg)
+}
+
+func (p *Player) IsEquipped(item *Item) bool {
+	_, ok := p.EquippedSlot(item)
+	return ok
+}
+
+// EquippedSlot is the slot an item is equipped in, false if it isn't equipped
+func (p *Player) EquippedSlot(item *Item) (equipSlot, bool) {
+	for s, i := range p.equipSlots {
+		if i == item {
+			return equipSlot(s), true
+		}
+	}
+
+	return slotAny, false
+}
+
+// EquippedIn is the item in a slot, nil if it's empty
+func (p *Player) EquippedIn(slot equipSlot) *Item {
+	if slot < 0 || slot >= equipSlotCount {
+		return nil
+	}
+
+	return p.equipSlots[slot]
+}
+
+// Everything the player has equipped, in slot order
+func (p *Player) equipped() []*Item {
+	items := make([]*Item, 0, equipSlotCount)
+	for _, i := range p.equipSlots {
+		if i != nil {
+			items = append(items, i)
+		}
+	}
+
+	return items
+}
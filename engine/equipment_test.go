@@ -0,0 +1,209 @@
+package engine
+
+// ============================================================================
+// Tests for equipment slots & the stats equipment gives
+// ============================================================================
+
+import "testing"
+
+// Put an item in the backpack and equip it, in a particular slot unless slotAny
+func equipTestItem(t *testing.T, g *Game, id string, slot equipSlot) *Item {
+	t.Helper()
+	i := g.itemGen.createItem(id)
+	g.player.backpack.Add(i)
+
+	if res := g.ProcessAction(NewEquipSlotAction(i, slot)); !res.Success {
+		t.Fatalf("equipping %s should succeed", id)
+	}
+
+	return i
+}
+
+func TestRingSlots(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+	defence, hit := p.defence, p.attackChance
+
+	protection := equipTestItem(t, g, "ring_protection", slotAny)
+	accuracy := equipTestItem(t, g, "ring_accuracy", slotAny)
+
+	if p.EquippedIn(slotLeftRing) != protection || p.EquippedIn(slotRightRing) != accuracy {
+		t.Fatalf("two rings should be worn one on each hand")
+	}
+
+	if p.defence != defence+2 || p.attackChance != hit+10 {
+		t.Errorf("both rings should add to the player's stats, got defence %d, hit %d", p.defence, p.attackChance)
+	}
+
+	// With both hands full a third ring goes where it's told
+	power := equipTestItem(t, g, "ring_power", slotRightRing)
+	if p.EquippedIn(slotRightRing) != power || p.IsEquipped(accuracy) || !p.backpack.Contains(accuracy) {
+		t.Errorf("the ring on the right hand should be swapped for the new one")
+	}
+
+	if p.attackChance != hit || p.defence != defence+2 {
+		t.Errorf("the ring taken off shouldn't add to the stats any more, got defence %d, hit %d", p.defence, p.attackChance)
+	}
+
+	if res := g.ProcessAction(NewEquipSlotAction(accuracy, slotNeck)); res.Success {
+		t.Errorf("a ring can't be worn around the neck")
+	}
+}
+
+func TestTwoHandedWeapon(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+
+	shield := p.EquippedIn(slotShield)
+	if shield == nil {
+		t.Fatalf("warriors should start with a shield")
+	}
+
+	axe := equipTestItem(t, g, "axe", slotAny)
+	if p.EquippedIn(slotWeapon) != axe || p.IsEquipped(shield) {
+		t.Errorf("wielding a two-handed axe should take off the shield")
+	}
+
+	if res := g.ProcessAction(NewEquipAction(shield)); res.Success || p.IsEquipped(shield) {
+		t.Errorf("a shield can't be used with a two-handed weapon")
+	}
+
+	g.ProcessAction(NewEquipAction(axe))
+	if res := g.ProcessAction(NewEquipAction(shield)); !res.Success || !p.IsEquipped(shield) {
+		t.Errorf("the shield can go back on once the axe is put away")
+	}
+}
+
+func TestStatsIndependentOfOrder(t *testing.T) {
+	equipAll := func(ids ...string) playerStats {
+		g := NewGame("../assets/datafiles", 4, "rogue")
+		for _, id := range ids {
+			i := g.itemGen.createItem(id)
+			g.player.backpack.Add(i)
+			g.ProcessAction(NewEquipAction(i))
+		}
+
+		return g.player.playerStats
+	}
+
+	one := equipAll("ring_protection", "chainmail", "ring_power", "sword")
+	two := equipAll("sword", "ring_power", "chainmail", "ring_protection")
+	if one != two {
+		t.Errorf("the same equipment should give the same stats whatever order it's put on\n%+v\n%+v", one, two)
+	}
+
+	// Taking off one item with an attack roll leaves the roll from another
+	g := testGame(t)
+	p := g.player
+	ring := g.itemGen.createItem("ring_power")
+	small, _ := newEffect("attack", "1d3")
+	ring.effects = append(ring.effects, *small)
+	p.backpack.Add(ring)
+	g.ProcessAction(NewEquipAction(ring))
+
+	if p.attackRoll.String() != "d6" {
+		t.Errorf("the sword's roll is better so should be used, got %s", p.attackRoll)
+	}
+
+	g.ProcessAction(NewEquipAction(p.EquippedIn(slotWeapon)))
+	if p.attackRoll.String() != "d3" {
+		t.Errorf("with the sword put away the ring's roll should be used, got %s", p.attackRoll)
+	}
+}
+
+func TestEquipmentSaveAndReplay(t *testing.T) {
+	g := testGame(t)
+	g.recording = nil
+	ring := equipTestItem(t, g, "ring_accuracy", slotRightRing)
+	g.gainExp(g.ExpForNextLevel())
+
+	saved, err := g.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGame("../assets/datafiles", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lp := loaded.player
+	if r := lp.EquippedIn(slotRightRing); r == nil || r.instanceID != ring.instanceID {
+		t.Errorf("the ring should still be on the right hand")
+	}
+
+	if lp.playerStats != g.player.playerStats || lp.base != g.player.base || lp.hp != g.player.hp {
+		t.Errorf("stats should be the same once loaded\n%+v\n%+v", lp.playerStats, g.player.playerStats)
+	}
+
+	a, err := g.actionFromStep(g.recording[0])
+	if equip, ok := a.(*EquipAction); err != nil || !ok || equip.slot != slotRightRing {
+		t.Errorf("equip step should play back into the same slot, got %+v %v", g.recording[0], err)
+	}
+}
+
+func TestEquipWithFullPack(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+
+	full := false
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventPackFull {
+			full = true
+		}
+	})
+
+	axe := g.itemGen.createItem("axe")
+	p.backpack.Add(axe)
+	for len(p.backpack) < PLAYER_MAX_ITEMS {
+		p.backpack.Add(g.itemGen.createItem("sword"))
+	}
+
+	// The axe frees up its own slot, but the sword and shield need two
+	sword, shield := p.EquippedIn(slotWeapon), p.EquippedIn(slotShield)
+	if res := g.ProcessAction(NewEquipAction(axe)); res.Success || !full || p.EquippedIn(slotWeapon) != sword || p.EquippedIn(slotShield) != shield {
+		t.Errorf("the axe can't be wielded when there's no room for both the sword and shield")
+	}
+
+	full = false
+	if res := g.ProcessAction(NewEquipAction(shield)); res.Success || !full || !p.IsEquipped(shield) || len(p.backpack) != PLAYER_MAX_ITEMS {
+		t.Errorf("the shield can't be taken off with a full pack")
+	}
+
+	// Swapping one item for another always has room
+	if res := g.ProcessAction(NewEquipAction(p.backpack.AllItems()[PLAYER_MAX_ITEMS-1])); !res.Success || !p.backpack.Contains(sword) {
+		t.Errorf("a sword should be swapped for another with a full pack")
+	}
+}
+
+// Max HP from equipment mustn't heal, or taking it off and on again would heal the player for free
+func TestMaxHPEquipmentDoesNotHeal(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+	ring := g.itemGen.createItem("ring_power")
+	tough, _ := newEffect("maxHP", "10")
+	ring.effects = append(ring.effects, *tough)
+	p.backpack.Add(ring)
+
+	maxHP := p.maxHP
+	p.hp = 20
+	for i := 0; i < 5; i++ {
+		g.ProcessAction(NewEquipAction(ring))
+		g.ProcessAction(NewEquipAction(ring))
+	}
+
+	if p.hp != 20 {
+		t.Errorf("swapping a max HP ring on and off should leave HP at 20, got %d", p.hp)
+	}
+
+	g.ProcessAction(NewEquipAction(ring))
+	if p.maxHP != maxHP+10 || p.hp != 20 {
+		t.Errorf("the ring should add 10 max HP without healing, got %d/%d", p.hp, p.maxHP)
+	}
+
+	p.hp = p.maxHP
+	g.ProcessAction(NewEquipAction(ring))
+	if p.hp != maxHP {
+		t.Errorf("taking the ring off should cap HP to %d, got %d", maxHP, p.hp)
+	}
+}
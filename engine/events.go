@@ -19,12 +19,39 @@ const (
 	EventItemDropped    = "item_dropped"
 	EventItemEquipped   = "item_equipped"
 	EventItemUnequipped = "item_unequipped"
-
-	EventCreatureKilled = "creature_killed"
-	EventCombatMissed   = "combat_missed"
-	EventCombatHit      = "combat_hit"
-
-	EventPackFull = "player_pack_full"
+	EventItemIdentified = "item_identified"
+
+	EventCreatureKilled  = "creature_killed"
+	EventCreatureFlee    = "creature_flee"
+	EventCreatureWounded = "creature_wounded"
+	EventCombatMissed    = "combat_missed"
+	EventCombatHit       = "combat_hit"
+	EventMissileFired    = "missile_fired"
+	EventPlayerHit       = "player_hit"
+	EventPlayerDied      = "player_died"
+	EventPlayerNearDeath = "player_near_death"
+	EventLevelUp         = "level_up"
+	EventPerkChosen      = "perk_chosen"
+
+	EventPackFull    = "player_pack_full"
+	EventEncumbrance = "player_encumbrance"
+
+	EventLevelChanged = "level_changed"
+
+	EventFurnitureOpened = "furniture_opened"
+	EventFurnitureClosed = "furniture_closed"
+	EventFurnitureUsed   = "furniture_used"
+	EventFurnitureBashed = "furniture_bashed"
+	EventFurnitureBroken = "furniture_broken"
+
+	EventTrapTriggered = "trap_triggered"
+	EventTrapFound     = "trap_found"
+
+	EventStatusAdded   = "status_added"
+	EventStatusExpired = "status_expired"
+
+	EventSpellCast    = "spell_cast"
+	EventSpellLearned = "spell_learned"
 )
 
 type GameEvent struct {
@@ -0,0 +1,153 @@
+package engine
+
+// ============================================================================
+// Field of view using symmetric recursive shadowcasting
+// Symmetric means if A can see B then B can see A, which keeps things fair
+// between the player and creatures. Works from any observer position
+// Based on https://www.albertford.com/shadowcasting/
+// ============================================================================
+
+import (
+	"roguelike/core"
+)
+
+// A slope is a fraction, kept as integers so the maths is exact
+type slope struct {
+	num int
+	den int
+}
+
+// A row of tiles in a quadrant, between two slopes
+type fovRow struct {
+	depth int
+	start slope
+	end   slope
+}
+
+// A quadrant transforms (depth, col) row coordinates to map positions
+type fovQuadrant struct {
+	cardinal core.Direction
+	origin   core.Pos
+}
+
+func (q fovQuadrant) transform(depth, col int) core.Pos {
+	switch q.cardinal {
+	case core.DirNorth:
+		return core.Pos{X: q.origin.X + col, Y: q.origin.Y - depth}
+	case core.DirSouth:
+		return core.Pos{X: q.origin.X + col, Y: q.origin.Y + depth}
+	case core.DirEast:
+		return core.Pos{X: q.origin.X + depth, Y: q.origin.Y + col}
+	default:
+		return core.Pos{X: q.origin.X - depth, Y: q.origin.Y + col}
+	}
+}
+
+// Columns of the row that fall between the start & end slopes
+func (r fovRow) cols() (int, int) {
+	minCol := floorDiv(2*r.depth*r.start.num+r.start.den, 2*r.start.den)
+	maxCol := -floorDiv(-(2*r.depth*r.end.num - r.end.den), 2*r.end.den)
+
+	return minCol, maxCol
+}
+
+func (r fovRow) next() fovRow {
+	return fovRow{r.depth + 1, r.start, r.end}
+}
+
+// Floor tiles are only visible if they are in the row's sector, this is what makes it symmetric
+func (r fovRow) isSymmetric(col int) bool {
+	return col*r.start.den >= r.depth*r.start.num && col*r.end.den <= r.depth*r.end.num
+}
+
+// Slope of the left edge of a tile
+func tileSlope(depth, col int) slope {
+	return slope{2*col - 1, 2 * depth}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+
+	return q
+}
+
+// Calculate what can be seen from a position, calling visit for every visible position
+// The view is a circle with the given radius, tiles that block LOS are visible but hide what's behind
+func (m *GameMap) computeFOV(origin core.Pos, radius int, visit func(p core.Pos)) {
+	if !origin.InBounds(m.Width, m.Height) {
+		return
+	}
+
+	visit(origin)
+
+	inRadius := func(p core.Pos) bool {
+		dx, dy := p.X-origin.X, p.Y-origin.Y
+		return dx*dx+dy*dy <= radius*radius+radius
+	}
+
+	// Off the edge of the map is treated as solid
+	blocks := func(p core.Pos) bool {
+		t := m.TileAt(p)
+		return t == nil || t.BlocksLOS()
+	}
+
+	for _, dir := range core.Directions {
+		q := fovQuadrant{dir, origin}
+
+		var scan func(row fovRow)
+		scan = func(row fovRow) {
+			if row.depth > radius {
+				return
+			}
+
+			minCol, maxCol := row.cols()
+			prevWall, prevFloor := false, false
+
+			for col := minCol; col <= maxCol; col++ {
+				p := q.transform(row.depth, col)
+				isWall := blocks(p)
+
+				if (isWall || row.isSymmetric(col)) && inRadius(p) && p.InBounds(m.Width, m.Height) {
+					visit(p)
+				}
+
+				if prevWall && !isWall {
+					row.start = tileSlope(row.depth, col)
+				}
+
+				if prevFloor && isWall {
+					nextRow := row.next()
+					nextRow.end = tileSlope(row.depth, col)
+					scan(nextRow)
+				}
+
+				prevWall, prevFloor = isWall, !isWall
+			}
+
+			if prevFloor {
+				scan(row.next())
+			}
+		}
+
+		scan(fovRow{1, slope{-1, 1}, slope{1, 1}})
+	}
+}
+
+// Check if one position can see another within a radius, as FOV is symmetric it works both ways
+func (m *GameMap) canSee(from, to core.Pos, radius int) bool {
+	if from.Distance(to) > float64(radius)+1 {
+		return false
+	}
+
+	seen := false
+	m.computeFOV(from, radius, func(p core.Pos) {
+		if p == to {
+			seen = true
+		}
+	})
+
+	return seen
+}
@@ -0,0 +1,74 @@
+package engine
+
+// ============================================================================
+// Tests for field of view
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+func TestFOVCircular(t *testing.T) {
+	m := openMap(21, 21)
+	origin := core.Pos{X: 10, Y: 10}
+
+	visible := make(map[core.Pos]bool)
+	m.computeFOV(origin, 6, func(p core.Pos) {
+		visible[p] = true
+	})
+
+	tests := []struct {
+		pos  core.Pos
+		seen bool
+	}{
+		{origin, true},
+		{core.Pos{X: 16, Y: 10}, true},
+		{core.Pos{X: 10, Y: 4}, true},
+		{core.Pos{X: 14, Y: 14}, true},
+		{core.Pos{X: 16, Y: 16}, false},
+		{core.Pos{X: 17, Y: 10}, false},
+	}
+
+	for _, test := range tests {
+		if visible[test.pos] != test.seen {
+			t.Errorf("FOV from %v visible %v = %v; want %v", origin, test.pos, visible[test.pos], test.seen)
+		}
+	}
+}
+
+func TestFOVWalls(t *testing.T) {
+	m := openMap(11, 11)
+	m.tiles[5][3].makeWall()
+
+	// Wall is visible, but the tile directly behind it isn't
+	if !m.canSee(core.Pos{X: 5, Y: 5}, core.Pos{X: 5, Y: 3}, 6) {
+		t.Errorf("FOV should see the wall itself")
+	}
+
+	if m.canSee(core.Pos{X: 5, Y: 5}, core.Pos{X: 5, Y: 2}, 6) {
+		t.Errorf("FOV should not see through a wall")
+	}
+}
+
+func TestFOVSymmetric(t *testing.T) {
+	r := newRNG(3)
+	m := NewMap(40, 40, 1)
+	newCaGenerator(m, r).generate()
+
+	floors := make([]core.Pos, 0)
+	m.enumerateFunc(func(tile *tile, x, y int) {
+		if !tile.BlocksLOS() {
+			floors = append(floors, tile.pos)
+		}
+	})
+
+	for i := 0; i < 2000; i++ {
+		a := floors[r.IntN(len(floors))]
+		b := floors[r.IntN(len(floors))]
+
+		if m.canSee(a, b, 8) != m.canSee(b, a, 8) {
+			t.Fatalf("FOV is not symmetric between %v and %v", a, b)
+		}
+	}
+}
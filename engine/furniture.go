@@ -1,12 +1,35 @@
-// nolint
 package engine
 
 // ============================================================================
-// Furniture entities are things like doors, barrels, tables etc
+// Furniture entities are things like doors, barrels, chests etc
+// Furniture sits on a tile, each piece has its own blocking & LOS state
+// which can change, e.g. when a door is opened
 // ============================================================================
 
+import (
+	"fmt"
+	"roguelike/core"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
 type furniture struct {
 	entityBase
+
+	openable    bool   // Has an open state, e.g. doors & chests
+	open        bool   // Currently open
+	openGraphic string // Graphic used when open
+	openMove    bool   // Blocks movement when open
+	openLOS     bool   // Blocks line of sight when open
+
+	hp        int    // Damage it can take before it's broken, zero means it can't be broken
+	loot      int    // Max number of random items inside, given when opened or broken
+	lootTable string // Loot table the items inside are picked from
+	heal      int    // HP given to the player the first time it's used
+	used      bool
+
+	onInteract string // Hook script run when the player does anything with it, see hooks.go
 }
 
 func (f furniture) Type() entityType {
@@ -14,13 +37,344 @@ func (f furniture) Type() entityType {
 }
 
 func (f furniture) BlocksLOS() bool {
-	return true
+	if f.open {
+		return f.openLOS
+	}
+
+	return f.blocksLOS
 }
 
 func (f furniture) BlocksMove() bool {
-	return true
+	if f.open {
+		return f.openMove
+	}
+
+	return f.blocksMove
+}
+
+func (f furniture) Appearance() Appearance {
+	a := f.entityBase.Appearance()
+	if f.open && f.openGraphic != "" {
+		a.Graphic = f.openGraphic
+	}
+
+	return a
 }
 
 func (f furniture) String() string {
 	return "furn_" + f.id + "_" + f.instanceID
 }
+
+// Doors are furniture that can be walked through once opened
+func (f furniture) isDoor() bool {
+	return f.openable && !f.openMove
+}
+
+// ===== Furniture Generator ==================================================
+
+// The furnitureGenerator is a kind of factory for creating furniture
+type furnitureGenerator struct {
+	rng          *gameRNG
+	genFunctions map[string](func() *furniture)
+	keys         []string
+	doors        []string       // Furniture placed in doorways
+	place        map[string]int // Max number of each other type placed on a level
+}
+
+type yamlFurnitureOpen struct {
+	Graphic    string `yaml:"graphic"`
+	BlocksMove bool   `yaml:"blocksMove"`
+	BlocksLOS  bool   `yaml:"blocksLOS"`
+}
+
+type yamlFurniture struct {
+	Description string             `yaml:"description"`
+	Name        string             `yaml:"name"`
+	Graphic     string             `yaml:"graphic"`
+	Colour      string             `yaml:"colour"`
+	BlocksMove  bool               `yaml:"blocksMove"`
+	BlocksLOS   bool               `yaml:"blocksLOS"`
+	Open        *yamlFurnitureOpen `yaml:"open"`
+	HP          int                `yaml:"hp"`
+	Loot        int                `yaml:"loot"`
+	LootTable   string             `yaml:"lootTable"`
+	Heal        int                `yaml:"heal"`
+	Place       int                `yaml:"place"`
+	OnInteract  string             `yaml:"onInteract"`
+}
+
+type yamlFurnitureFile struct {
+	Furniture map[string]yamlFurniture `yaml:"furniture"`
+}
+
+func newFurnitureGenerator(dataFile string, r *gameRNG) (*furnitureGenerator, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlFurnitureFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := furnitureGenerator{
+		rng:          r,
+		genFunctions: make(map[string](func() *furniture)),
+		keys:         make([]string, 0),
+		place:        make(map[string]int),
+	}
+
+	for id, entry := range file.Furniture {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("furniture '%s' %s", id, problems[0])
+		}
+
+		if entry.LootTable == "" {
+			entry.LootTable = "chest"
+		}
+
+		gen.genFunctions[id] = func() *furniture {
+			f := &furniture{
+				entityBase: entityBase{
+					id:         id,
+					instanceID: core.RandId(gen.rng.Rand, 6),
+					desc:       entry.Description,
+					name:       entry.Name,
+					graphicId:  entry.Graphic,
+					colour:     entry.Colour,
+					blocksMove: entry.BlocksMove,
+					blocksLOS:  entry.BlocksLOS,
+				},
+				hp:         entry.HP,
+				loot:       entry.Loot,
+				lootTable:  entry.LootTable,
+				heal:       entry.Heal,
+				onInteract: entry.OnInteract,
+			}
+
+			if entry.Open != nil {
+				f.openable = true
+				f.openGraphic = entry.Open.Graphic
+				f.openMove = entry.Open.BlocksMove
+				f.openLOS = entry.Open.BlocksLOS
+			}
+
+			return f
+		}
+
+		gen.keys = append(gen.keys, id)
+
+		if entry.Open != nil && !entry.Open.BlocksMove {
+			gen.doors = append(gen.doors, id)
+		} else if entry.Place > 0 {
+			gen.place[id] = entry.Place
+		}
+	}
+
+	// Sort the keys as the map iteration order above is random
+	slices.Sort(gen.keys)
+	slices.Sort(gen.doors)
+	return &gen, nil
+}
+
+func (gen furnitureGenerator) createFurniture(id string) *furniture {
+	genFunc, ok := gen.genFunctions[id]
+	if !ok {
+		return nil
+	}
+
+	return genFunc()
+}
+
+// ===== Placement ============================================================
+
+// Percentage chance each doorway gets a door
+const doorChance = 50
+
+// Put furniture on a newly generated level
+func (gen furnitureGenerator) placeFurniture(m *GameMap) {
+	// Doors go in doorways, only dungeons have proper doorways
+	if m.generationMethod == "bsp" && len(gen.doors) > 0 {
+		m.enumerateFunc(func(t *tile, x, y int) {
+			if m.isDoorway(t) && gen.rng.Chance(doorChance) {
+				t.placeFurniture(gen.createFurniture(gen.rng.pick(gen.doors...)))
+			}
+		})
+	}
+
+	for _, id := range gen.keys {
+		for i := gen.rng.IntN(gen.place[id] + 1); i > 0; i-- {
+			if t := m.openFloorTile(gen.rng); t != nil {
+				t.placeFurniture(gen.createFurniture(id))
+			}
+		}
+	}
+}
+
+// A doorway is a floor tile squeezed between two walls, leading into a room
+func (m *GameMap) isDoorway(t *tile) bool {
+	if t.tileType != tileTypeFloor || t.furniture != nil {
+		return false
+	}
+
+	isWall := func(p pos) bool {
+		n := m.TileAt(p)
+		return n == nil || n.tileType == tileTypeWall
+	}
+
+	// Rooms are open areas, a tile next to the doorway with lots of floor around it
+	isRoom := func(p pos) bool {
+		floors := 0
+		for _, n := range p.NeighboursAll() {
+			if !isWall(n) {
+				floors++
+			}
+		}
+
+		return floors >= 5
+	}
+
+	n, s := t.Add(core.DirNorth.Pos()), t.Add(core.DirSouth.Pos())
+	e, w := t.Add(core.DirEast.Pos()), t.Add(core.DirWest.Pos())
+
+	// Don't put doors right next to each other
+	for _, p := range []pos{n, s, e, w} {
+		if nt := m.TileAt(p); nt != nil && nt.furniture != nil {
+			return false
+		}
+	}
+
+	if isWall(n) && isWall(s) && !isWall(e) && !isWall(w) {
+		return isRoom(e) != isRoom(w)
+	}
+
+	if isWall(e) && isWall(w) && !isWall(n) && !isWall(s) {
+		return isRoom(n) != isRoom(s)
+	}
+
+	return false
+}
+
+// Find a floor tile surrounded by open space, furniture put here can never block a path
+func (m *GameMap) openFloorTile(r *gameRNG) *tile {
+	for tries := 0; tries < 50; tries++ {
+		t := m.randomFloorTile(r, true)
+		if t == nil || t.furniture != nil || t.creature != nil {
+			continue
+		}
+
+		open := true
+		for _, p := range t.NeighboursAll() {
+			if n := m.TileAt(p); n == nil || n.BlocksMove() || n.tileType != tileTypeFloor {
+				open = false
+				break
+			}
+		}
+
+		if open {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// ===== Interaction ==========================================================
+
+// Open a piece of furniture, returns false if it can't be opened
+func (f *furniture) openUp(g *Game, opener actor) bool {
+	if !f.openable || f.open {
+		return false
+	}
+
+	f.open = true
+	if opener == actor(g.player) {
+		events.new(EventFurnitureOpened, f, fmt.Sprintf("You open the %s", f.name))
+		f.giveLoot(g, g.player.currentTile)
+	} else if g.gameMap.TileAt(*f.pos).inFOV {
+		events.new(EventFurnitureOpened, f, fmt.Sprintf("The %s opens the %s", opener.Name(), f.name))
+	}
+
+	return true
+}
+
+// Close a piece of furniture, it can't be closed with something in the way
+func (f *furniture) shut(g *Game) bool {
+	t := g.gameMap.TileAt(*f.pos)
+	if !f.open || t.creature != nil || !t.items.IsEmpty() {
+		return false
+	}
+
+	f.open = false
+	events.new(EventFurnitureClosed, f, fmt.Sprintf("You close the %s", f.name))
+
+	return true
+}
+
+// Use a piece of furniture, what happens depends on what it is
+func (f *furniture) interact(g *Game) bool {
+	switch {
+	case f.isDoor() && f.open:
+		return f.shut(g)
+	case f.openable && !f.open:
+		return f.openUp(g, g.player)
+	case f.heal > 0 && !f.used:
+		f.used = true
+		g.player.hp = core.MinInt(g.player.hp+f.heal, g.player.maxHP)
+		events.new(EventFurnitureUsed, f, fmt.Sprintf("You touch the %s and feel restored", f.name))
+		return true
+	}
+
+	events.new(EventFurnitureUsed, f, fmt.Sprintf("Nothing happens with the %s", f.name))
+	return false
+}
+
+// Hit a piece of furniture, when it's broken it's removed and drops any loot
+func (f *furniture) bash(g *Game) bool {
+	if f.hp <= 0 {
+		events.new(EventFurnitureBashed, f, fmt.Sprintf("You can't break the %s", f.name))
+		return false
+	}
+
+	stats := g.player.combatStats()
+	f.hp -= max(stats.damage.Roll(g.rng)+stats.damageBonus, 1)
+
+	if f.hp > 0 {
+		events.new(EventFurnitureBashed, f, fmt.Sprintf("You bash the %s", f.name))
+		return true
+	}
+
+	t := g.gameMap.TileAt(*f.pos)
+	t.furniture = nil
+	events.new(EventFurnitureBroken, f, fmt.Sprintf("You smash the %s to pieces", f.name))
+
+	if !f.open {
+		f.giveLoot(g, t)
+	}
+
+	g.updateFOV()
+	return true
+}
+
+// Drop any loot inside onto a tile, only happens once
+func (f *furniture) giveLoot(g *Game, t *tile) {
+	if f.loot <= 0 || f.used {
+		return
+	}
+
+	f.used = true
+	count := g.rng.IntN(f.loot + 1)
+	if count == 0 {
+		events.new(EventFurnitureUsed, f, fmt.Sprintf("The %s is empty", f.name))
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		item := g.itemGen.createRandomItem(f.lootTable, g.gameMap.depth)
+		if t.addItem(item) {
+			events.new(EventFurnitureUsed, item, fmt.Sprintf("You find a %s", item.Name()))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+// ============================================================================
+// Tests for furniture, doors, bashing and placement
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"roguelike/core/pathfind"
+	"testing"
+)
+
+func TestDoorOpenClose(t *testing.T) {
+	g := testGame(t)
+	door := g.furnitureGen.createFurniture("door")
+	doorTile := g.gameMap.Tile(4, 3)
+	doorTile.placeFurniture(door)
+
+	if !doorTile.BlocksMove() || !doorTile.BlocksLOS() {
+		t.Fatalf("closed door should block movement and LOS")
+	}
+
+	if g.gameMap.canSee(core.Pos{X: 4, Y: 4}, core.Pos{X: 4, Y: 2}, 6) {
+		t.Errorf("should not see through a closed door")
+	}
+
+	// Walking into the door opens it, without moving
+	g.ProcessAction(NewMoveAction(core.DirNorth))
+	if !door.open || g.player.Pos() != (core.Pos{X: 4, Y: 4}) {
+		t.Fatalf("walking into a door should open it, open = %v, player at %v", door.open, g.player.Pos())
+	}
+
+	if doorTile.BlocksMove() || doorTile.BlocksLOS() || doorTile.Appearance().Graphic != "door_open" {
+		t.Errorf("open door should not block movement or LOS")
+	}
+
+	if res := g.ProcessAction(NewCloseAction(core.DirNorth)); !res.Success || door.open {
+		t.Errorf("closing the door failed")
+	}
+}
+
+func TestBashBarrel(t *testing.T) {
+	g := testGame(t)
+	barrel := g.furnitureGen.createFurniture("barrel")
+	barrelTile := g.gameMap.Tile(5, 4)
+	barrelTile.placeFurniture(barrel)
+
+	for i := 0; i < 100 && barrelTile.furniture != nil; i++ {
+		g.ProcessAction(NewBashAction(core.DirEast))
+	}
+
+	if barrelTile.furniture != nil || barrelTile.BlocksMove() {
+		t.Fatalf("barrel should have been smashed")
+	}
+
+	statue := g.furnitureGen.createFurniture("statue")
+	g.gameMap.Tile(3, 4).placeFurniture(statue)
+	if res := g.ProcessAction(NewBashAction(core.DirWest)); res.Success {
+		t.Errorf("statues can't be broken")
+	}
+}
+
+// Furniture must never cut off part of a level
+func TestFurnitureKeepsLevelsConnected(t *testing.T) {
+	g := NewGame("../assets/datafiles", 3, "")
+	doors := 0
+
+	for depth := 1; depth <= 6; depth++ {
+		m := generateLevel(g, depth)
+		start := m.TileAt(m.upStairs)
+
+		// Treat doors as open, everything else that blocks is in the way
+		grid := pathfind.NewGrid(m.size, func(p core.Pos) bool {
+			t := m.TileAt(p)
+			if f := t.Furniture(); f != nil && f.isDoor() {
+				doors++
+				return false
+			}
+
+			return t.BlocksMove() && t.creature == nil
+		})
+
+		dm := grid.DijkstraMap(start.pos)
+		if dm.Cost(m.downStairs) == pathfind.Unreachable {
+			t.Errorf("level %d has no route between the stairs", depth)
+		}
+	}
+
+	if doors == 0 {
+		t.Errorf("no doors were placed on any level")
+	}
+}
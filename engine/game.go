@@ -1,7 +1,6 @@
 package engine
 
 import (
-	"encoding/json"
 	"fmt"
 	"roguelike/core"
 )
@@ -13,19 +12,43 @@ import (
 
 type Game struct {
 	player  *Player
-	gameMap *GameMap
+	gameMap *GameMap   // The current level the player is on
+	levels  []*GameMap // All levels visited so far, indexed by depth-1
 
 	// Entity generators
-	itemGen     *itemGenerator
-	creatureGen *creatureGenerator
+	itemGen      *itemGenerator
+	creatureGen  *creatureGenerator
+	furnitureGen *furnitureGenerator
+	trapGen      *trapGenerator
+
+	// Game clock, advanced by the turn scheduler
+	ticks int
+	seed  uint64
+	rng   *gameRNG // Every random draw in the game comes from here, so a seed replays exactly
+
+	// Every player action so far, for replays
+	recording []ReplayStep
+
+	combatRules CombatRules
+	progression *progression
+	classes     *classList
+	levelHooks  *levelHooks
+	spells      map[string]*Spell
+	scripts     scriptCache // Scripts from the datafiles, compiled when first run
+	scriptDepth int         // How many scripts are running, see runScript
+
+	// An error the game can't carry on after, e.g. a script that had to be stopped
+	failed error
+
+	// True while a player action is carried out, anything it sets off is down
+	// to the player, e.g. creatures killed by a keg they bashed exploding
+	playerActing bool
 }
 
 // Create a new game instance, it all starts here
-func NewGame(dataFileDir string, seed uint64, viewDist int, listeners ...EventListener) *Game {
-	// Seed the shared global RNG
-	seedRNG(seed)
-
-	g := &Game{}
+// The class is the ID of one of the classes from LoadClasses, empty for the default class
+func NewGame(dataFileDir string, seed uint64, class string, listeners ...EventListener) *Game {
+	g := &Game{seed: seed, rng: newRNG(seed)}
 
 	// Reset the global event manager
 	events = eventManager{}
@@ -33,19 +56,48 @@ func NewGame(dataFileDir string, seed uint64, viewDist int, listeners ...EventLi
 	// Register event listeners
 	events.addEventListeners(listeners...)
 
-	generateMap(g, dataFileDir)
+	var err error
+	g.combatRules, err = loadCombatRules(dataFileDir + "/combat.yaml")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := loadGenerators(g, dataFileDir); err != nil {
+		panic(err)
+	}
+
+	g.gameMap = generateLevel(g, 1)
+	g.levels = []*GameMap{g.gameMap}
+
+	playerClass, err := g.classes.get(class)
+	if err != nil {
+		panic(err)
+	}
+
+	// The player knows what their starting kit is
+	kit := make([]*Item, 0, len(playerClass.items))
+	for _, id := range playerClass.items {
+		i := g.itemGen.createItem(id)
+		if i != nil && !i.IsIdentified() {
+			g.itemGen.ident.known[id] = true
+		}
+
+		kit = append(kit, i)
+	}
 
-	g.player = NewPlayer(g.gameMap.randomFloorTile(true))
-	g.player.fovDistance = viewDist
+	g.player = NewPlayer(g.rng, g.gameMap.TileAt(g.gameMap.upStairs), playerClass, kit...)
 
 	levelText := fmt.Sprintf("You are on level %d of %s", g.Map().Depth(), g.Map().Description())
-	events.new(EventMiscMessage, nil, "Welcome adventurer "+g.Player().Name())
+	events.new(EventMiscMessage, nil, fmt.Sprintf("Welcome %s the %s", g.Player().Name(), playerClass.name))
 	events.new(EventMiscMessage, nil, levelText)
 
 	// DEBUG
 	// g.player.backpack.Add(g.itemGen.createItem("meat"))
 
 	g.updateFOV()
+	g.spawnLevelCreatures(g.gameMap)
+	g.runLevelHook(true)
+
 	return g
 }
 
@@ -57,51 +109,115 @@ func (g *Game) Player() *Player {
 	return g.player
 }
 
+// Move the player to another level, creating it if it's not been visited before
+// Levels left behind are kept as they are, so they can be returned to
+// Returns false if the level's onEnter hook stopped the player arriving
+func (g *Game) changeLevel(depth int) bool {
+	if depth < 1 {
+		return false
+	}
+
+	leaving, leavingTile := g.gameMap, g.player.currentTile
+	firstVisit := len(g.levels) < depth
+
+	// Clear the FOV on the level being left, so it's not shown as in view when we return
+	g.gameMap.clearFOV()
+
+	goingDown := depth > g.gameMap.depth
+	for len(g.levels) < depth {
+		g.levels = append(g.levels, generateLevel(g, len(g.levels)+1))
+	}
+
+	g.gameMap = g.levels[depth-1]
+
+	// Arrive on the stairs at the other end
+	arrival := g.gameMap.TileAt(g.gameMap.downStairs)
+	verb := "climb up"
+	if goingDown {
+		arrival = g.gameMap.TileAt(g.gameMap.upStairs)
+		verb = "descend"
+	}
+
+	g.player.moveToTile(g.gameMap.freeTileNear(g.rng, arrival))
+	g.updateFOV()
+
+	if firstVisit {
+		g.spawnLevelCreatures(g.gameMap)
+	}
+
+	if g.runLevelHook(firstVisit) {
+		g.gameMap.clearFOV()
+		g.gameMap = leaving
+		g.player.moveToTile(leavingTile)
+		g.updateFOV()
+		return false
+	}
+
+	levelText := fmt.Sprintf("You %s to level %d of %s", verb, depth, g.gameMap.Description())
+	events.new(EventLevelChanged, nil, levelText)
+	return true
+}
+
+// Move the player somewhere random on the level, they stay put if nowhere is free
+func (g *Game) teleportPlayer() {
+	if t := g.gameMap.randomFloorTile(g.rng, false); t != nil {
+		g.player.moveToTile(t)
+	}
+}
+
+// Whether something is in the way on a tile, the player isn't kept in tile.creature so is checked too
+func (g *Game) occupied(t *tile) bool {
+	return t.BlocksMove() || t == g.player.currentTile
+}
+
+// Seed the game was created with
+func (g *Game) Seed() uint64 {
+	return g.seed
+}
+
+// Depth of the current level
+func (g *Game) Depth() int {
+	return g.gameMap.depth
+}
+
+// The player has died, the game is over
+func (g *Game) killPlayer(killer entity, cause string) {
+	if g.player.dead {
+		return
+	}
+
+	g.player.dead = true
+	g.player.killedBy = cause
+	events.new(EventPlayerDied, killer, fmt.Sprintf("You were killed by %s!", cause))
+}
+
+// The game is over when the player has died, or something went badly wrong
+func (g *Game) IsOver() bool {
+	return g.player.dead || g.failed != nil
+}
+
+// Err is the error that stopped the game, nil unless it couldn't carry on
+func (g *Game) Err() error {
+	return g.failed
+}
+
 // Update what the player can see, called after every action
 func (g *Game) updateFOV() {
 	p := g.player
-	fovRange := p.fovDistance
-
-	// Remove all previous FOV
-	for _, t := range g.gameMap.fovList {
-		t.inFOV = false
-	}
-	g.gameMap.fovList = nil
-
-	// TODO: Implement a better FOV algorithm!
-
-	// Cast rays from center of player to edges of a square centred on the player
-	// - if the ray hits a wall stop and otherwise the tile is inFov and seen
-	for x := p.X - fovRange; x < p.X+fovRange+1; x++ {
-		for y := p.Y - fovRange; y < p.Y+fovRange+1; y++ {
-			if x < 0 || x >= g.gameMap.Width || y < 0 || y >= g.gameMap.Height {
-				continue
-			}
-
-			// Step along the ray from the player to the edge of the square, stopping if we hit a wall
-			rayCoords := p.RayCastTo(pos{X: x, Y: y}, float64(fovRange)) // nolint
-			for _, checkCoord := range rayCoords {
-				tile := &g.gameMap.tiles[checkCoord.X][checkCoord.Y]
-
-				// Check if title contains any entities that block LOS
-				for _, entity := range tile.items {
-					if entity.BlocksLOS() {
-						tile.blocksLOS = true
-						break
-					}
-				}
-
-				tile.inFOV = true
-				tile.seen = true
-				g.gameMap.fovList = append(g.gameMap.fovList, tile)
-
-				// When tile blocks LOS, stop but *after* marking it as seen
-				if tile.blocksLOS {
-					break
-				}
-			}
-		}
+
+	// Blind players can only see the tile they are standing on
+	distance := p.fovDistance
+	if p.statuses.has(statusBlindness) {
+		distance = 0
 	}
+
+	g.gameMap.clearFOV()
+	g.gameMap.computeFOV(p.pos, distance, func(pos core.Pos) {
+		tile := g.gameMap.TileAt(pos)
+		tile.inFOV = true
+		tile.seen = true
+		g.gameMap.fovList = append(g.gameMap.fovList, tile)
+	})
 }
 
 // ViewPort is the area of the map that is centered on the player
@@ -124,15 +240,3 @@ func (g Game) GetViewPort(vpWidth, vpHeight int) core.Rect {
 
 	return vp
 }
-
-// MarshalJSON is a custom JSON marshaller for the Game struct
-func (g *Game) MarshalJSON() ([]byte, error) {
-	// TODO: This is not implemented yet, we need custom marshalling
-	return json.Marshal(struct {
-		Player  *Player  `json:"player"`
-		GameMap *GameMap `json:"gameMap"`
-	}{
-		Player:  g.player,
-		GameMap: g.gameMap,
-	})
-}
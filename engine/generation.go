@@ -4,73 +4,185 @@ package engine
 // Generation used to create the game world using several techniques
 // ============================================================================
 
+import (
+	"fmt"
+	"roguelike/core"
+	"roguelike/core/pathfind"
+)
+
+const (
+	maxLevelAttempts = 20  // Levels generated before giving up on a depth
+	maxStairsTries   = 100 // Random tiles tried for the down stairs when the entrance is cut off
+	floorTileTries   = 4   // Random tiles tried per tile on the map, when looking for a floor tile
+)
+
 type Generator interface {
 	generate()
 }
 
-func generateMap(g *Game, dataFileDir string) {
+// Load the datafiles used to create items, creatures, furniture & traps, the level scripts and spells
+func loadGenerators(g *Game, dataFileDir string) error {
+	g.scripts = make(scriptCache)
+
 	var err error
-	g.itemGen, err = newItemGenerator(dataFileDir + "/items.yaml")
-	if err != nil {
-		panic(err)
+	if g.itemGen, err = newItemGenerator(dataFileDir+"/items.yaml", g.rng); err != nil {
+		return err
+	}
+
+	g.itemGen.shuffleAppearances(g.seed)
+
+	if g.creatureGen, err = newCreatureGenerator(dataFileDir+"/creatures.yaml", g.rng); err != nil {
+		return err
+	}
+
+	if g.furnitureGen, err = newFurnitureGenerator(dataFileDir+"/furniture.yaml", g.rng); err != nil {
+		return err
+	}
+
+	if g.trapGen, err = newTrapGenerator(dataFileDir+"/traps.yaml", g.rng); err != nil {
+		return err
+	}
+
+	if g.levelHooks, err = loadLevelHooks(dataFileDir + "/levels.yaml"); err != nil {
+		return err
+	}
+
+	if g.progression, err = loadProgression(dataFileDir + "/progression.yaml"); err != nil {
+		return err
 	}
 
-	g.creatureGen, err = newCreatureGenerator(dataFileDir + "/creatures.yaml")
-	if err != nil {
-		panic(err)
+	if g.classes, err = loadClasses(dataFileDir + "/classes.yaml"); err != nil {
+		return err
+	}
+
+	g.spells, err = loadSpells(dataFileDir + "/spells.yaml")
+	return err
+}
+
+// Create a new level at the given depth, deeper levels are bigger and more dangerous
+// Levels the stairs can't be placed on are thrown away and generated again
+func generateLevel(g *Game, depth int) *GameMap {
+	for range maxLevelAttempts {
+		if m := tryGenerateLevel(g, depth); m != nil {
+			return m
+		}
 	}
 
-	size := rng.IntN(4)
-	depth := 1
+	panic(fmt.Sprintf("couldn't generate a level at depth %d after %d attempts", depth, maxLevelAttempts))
+}
+
+// Make one attempt at generating a level, nil if it turned out unusable
+func tryGenerateLevel(g *Game, depth int) *GameMap {
+	var m *GameMap
+
+	// Size of the map is random, but deeper levels tend to be bigger
+	size := core.MinInt(g.rng.IntN(2)+(depth-1)/2, 3)
 	genDepth := 1
 	switch size {
 	case 0:
 		// Tiny
-		g.gameMap = NewMap(32, 32, depth)
-		genDepth = rng.IntN(3) + 3 // 3,4,5
-		g.gameMap.description = "a tiny"
+		m = NewMap(32, 32, depth)
+		genDepth = g.rng.IntN(3) + 3 // 3,4,5
+		m.description = "a tiny"
 
 	case 1:
 		// Small
-		g.gameMap = NewMap(40, 40, depth)
-		genDepth = rng.IntN(3) + 3 // 3,4,5
-		g.gameMap.description = "a small"
+		m = NewMap(40, 40, depth)
+		genDepth = g.rng.IntN(3) + 3 // 3,4,5
+		m.description = "a small"
 
 	case 2:
 		// Medium
-		g.gameMap = NewMap(48, 48, depth)
-		genDepth = rng.IntN(3) + 4 // 4,5,6
-		g.gameMap.description = "a fair sized"
+		m = NewMap(48, 48, depth)
+		genDepth = g.rng.IntN(3) + 4 // 4,5,6
+		m.description = "a fair sized"
 
 	case 3:
 		// Large
-		g.gameMap = NewMap(64, 64, depth)
-		genDepth = rng.IntN(3) + 5 // 5,6,7
-		g.gameMap.description = "a large"
+		m = NewMap(64, 64, depth)
+		genDepth = g.rng.IntN(3) + 5 // 5,6,7
+		m.description = "a large"
 	}
 
 	var gen Generator
-	gen = newBSPGenerator(genDepth, g.gameMap)
+	gen = newBSPGenerator(genDepth, m, g.rng)
 
 	// 25% chance of a cave map
-	if rng.IntN(4) == 0 {
-		gen = newCaGenerator(g.gameMap)
+	if g.rng.IntN(4) == 0 {
+		gen = newCaGenerator(m, g.rng)
 	}
 
 	gen.generate()
+	if !placeStairs(g.rng, m) {
+		return nil
+	}
+
+	g.furnitureGen.placeFurniture(m)
+	g.trapGen.placeTraps(m)
 
 	// Place items
 	den := (size + 1) * 3
-	numItems := rng.IntN(den) + den
+	numItems := g.rng.IntN(den) + den
 	for i := 0; i < numItems; i++ {
-		item := g.itemGen.createRandomItem(rarityCommon)
-		g.gameMap.randomFloorTile(false).addItem(item)
+		item := g.itemGen.createRandomItem("level", depth)
+		if t := m.randomFloorTile(g.rng, false); t != nil {
+			t.addItem(item)
+		}
 	}
 
-	// Place creatures
-	numCreatures := rng.IntN(den) + den
+	// Place creatures, there are more of them the deeper you go
+	// The entrance is kept clear, as that's where the player starts
+	numCreatures := g.rng.IntN(den) + den + depth - 1
 	for i := 0; i < numCreatures; i++ {
-		creature := g.creatureGen.createRandomCreature()
-		g.gameMap.randomFloorTile(false).placeCreature(creature)
+		creature := g.creatureGen.createRandomCreature(depth)
+		if t := m.randomFloorTile(g.rng, false); t != nil && t.pos != m.upStairs {
+			m.placeCreature(creature, t)
+		}
+	}
+
+	return m
+}
+
+// Place the stairs, as far apart as possible so the whole level must be crossed
+// Levels below the first get stairs up, the first level's entrance is just a floor tile
+// Returns false if the level doesn't have room for both
+func placeStairs(r *gameRNG, m *GameMap) bool {
+	entrance := m.randomFloorTile(r, true)
+	if entrance == nil {
+		return false
 	}
+
+	if m.depth > 1 {
+		entrance.makeStairs(tileTypeStairsUp)
+	}
+
+	m.upStairs = entrance.pos
+
+	// Use a Dijkstra map to find the furthest reachable tile, caves can have disconnected areas
+	dm := m.pathGrid(nil).DijkstraMap(entrance.pos)
+	furthest := entrance
+	furthestCost := 0
+
+	m.enumerateFunc(func(t *tile, x, y int) {
+		cost := dm.Cost(t.pos)
+		if t.tileType == tileTypeFloor && cost != pathfind.Unreachable && cost > furthestCost {
+			furthest = t
+			furthestCost = cost
+		}
+	})
+
+	// Should never happen, but if the entrance is cut off put the stairs anywhere
+	for tries := 0; furthest == entrance; tries++ {
+		if tries >= maxStairsTries {
+			return false
+		}
+
+		if furthest = m.randomFloorTile(r, true); furthest == nil {
+			return false
+		}
+	}
+
+	furthest.makeStairs(tileTypeStairsDown)
+	m.downStairs = furthest.pos
+	return true
 }
@@ -25,10 +25,12 @@ type bspGenerator struct {
 
 	// Holds the game map to generate into
 	gameMap *GameMap
+
+	rng *gameRNG
 }
 
-func newBSPGenerator(maxDepth int, gameMap *GameMap) *bspGenerator {
-	return &bspGenerator{maxDepth, gameMap}
+func newBSPGenerator(maxDepth int, gameMap *GameMap, r *gameRNG) *bspGenerator {
+	return &bspGenerator{maxDepth, gameMap, r}
 }
 
 func (b bspNode) isLeaf() bool {
@@ -37,10 +39,10 @@ func (b bspNode) isLeaf() bool {
 
 func (gen *bspGenerator) generate() {
 	// Create a BSP tree
-	root := gen.buildBSP(rect{pos{0, 0}, gen.gameMap.Size()}, 0) //nolint
+	root := gen.buildBSP(rect{Pos: pos{X: 0, Y: 0}, Size: gen.gameMap.Size()}, 0)
 
 	// Traverse the tree which creates rooms at leaf nodes
-	root.traverseBSP(gen.gameMap)
+	root.traverseBSP(gen.gameMap, gen.rng)
 
 	// Separate pass to create corridors
 	root.createCorridors(gen.gameMap)
@@ -62,7 +64,7 @@ func (gen bspGenerator) buildBSP(r core.Rect, depth int) *bspNode {
 	} else if aspect <= 0.75 {
 		horiz = true
 	} else {
-		horiz = rng.IntN(100) < 50
+		horiz = gen.rng.IntN(100) < 50
 	}
 
 	if horiz {
@@ -75,7 +77,7 @@ func (gen bspGenerator) buildBSP(r core.Rect, depth int) *bspNode {
 		if splitRand <= 0 {
 			splitRand = 1
 		}
-		split += rng.IntN(splitRand) - int(float64(split)*factor)
+		split += gen.rng.IntN(splitRand) - int(float64(split)*factor)
 
 		left := core.NewRect(r.X, r.Y, r.Width, split)
 		right := core.NewRect(r.X, r.Y+split, r.Width, r.Height-split)
@@ -90,7 +92,7 @@ func (gen bspGenerator) buildBSP(r core.Rect, depth int) *bspNode {
 		if splitRand <= 0 {
 			splitRand = 1
 		}
-		split += rng.IntN(splitRand) - int(float64(split)*factor)
+		split += gen.rng.IntN(splitRand) - int(float64(split)*factor)
 
 		left := core.NewRect(r.X, r.Y, split, r.Height)
 		right := core.NewRect(r.X+split, r.Y, r.Width-split, r.Height)
@@ -98,21 +100,21 @@ func (gen bspGenerator) buildBSP(r core.Rect, depth int) *bspNode {
 	}
 }
 
-func (node *bspNode) traverseBSP(gm *GameMap) {
+func (node *bspNode) traverseBSP(gm *GameMap, r *gameRNG) {
 	if node.Left != nil {
-		node.Left.traverseBSP(gm)
+		node.Left.traverseBSP(gm, r)
 	}
 	if node.Right != nil {
-		node.Right.traverseBSP(gm)
+		node.Right.traverseBSP(gm, r)
 	}
 
 	// Percentage chance to create a room
-	if rng.IntN(100) < 70 {
+	if r.IntN(100) < 70 {
 		// Create a room at the center of leaf nodes
 		if node.Left == nil && node.Right == nil {
 			// Room size is randomly % of the node size
-			width := node.Width * (40 + rng.IntN(50)) / 100
-			height := node.Height * (40 + rng.IntN(50)) / 100
+			width := node.Width * (40 + r.IntN(50)) / 100
+			height := node.Height * (40 + r.IntN(50)) / 100
 			room := core.NewRect(node.center.X-width/2, node.center.Y-height/2, width, height)
 
 			// Carve the room area
@@ -17,9 +17,10 @@ type caGenerator struct {
 	cells   [][]bool
 	width   int
 	height  int
+	rng     *gameRNG
 }
 
-func newCaGenerator(gameMap *GameMap) *caGenerator {
+func newCaGenerator(gameMap *GameMap, r *gameRNG) *caGenerator {
 	cells := make([][]bool, gameMap.Width)
 	for i := 0; i < gameMap.Width; i++ {
 		cells[i] = make([]bool, gameMap.Height)
@@ -30,6 +31,7 @@ func newCaGenerator(gameMap *GameMap) *caGenerator {
 		cells:   cells,
 		width:   gameMap.Width,
 		height:  gameMap.Height,
+		rng:     r,
 	}
 }
 
@@ -37,7 +39,7 @@ func (gen *caGenerator) generate() {
 	// Start with a random map
 	for x := 0; x < gen.width; x++ {
 		for y := 0; y < gen.height; y++ {
-			if rng.Float64() < startChance {
+			if gen.rng.Float64() < startChance {
 				gen.cells[x][y] = true
 			} else {
 				gen.cells[x][y] = false
@@ -0,0 +1,122 @@
+package engine
+
+// ============================================================================
+// Tests for level generation and moving between levels
+// ============================================================================
+
+import (
+	"testing"
+)
+
+func TestLevelStairs(t *testing.T) {
+	g := NewGame("../assets/datafiles", 5, "")
+
+	for depth := 1; depth <= 4; depth++ {
+		m := generateLevel(g, depth)
+
+		if m.TileAt(m.downStairs).tileType != tileTypeStairsDown {
+			t.Errorf("level %d has no stairs down at %v", depth, m.downStairs)
+		}
+
+		upType := m.TileAt(m.upStairs).tileType
+		if depth == 1 && upType != tileTypeFloor {
+			t.Errorf("level 1 should not have stairs up")
+		}
+
+		if depth > 1 && upType != tileTypeStairsUp {
+			t.Errorf("level %d has no stairs up at %v", depth, m.upStairs)
+		}
+	}
+}
+
+func TestStairsNeedRoom(t *testing.T) {
+	// A map that's all wall has nowhere for anything to go
+	m := NewMap(8, 8, 2)
+	if m.randomFloorTile(newRNG(1), false) != nil || placeStairs(newRNG(1), m) {
+		t.Errorf("a map with no floor shouldn't have a floor tile or stairs")
+	}
+
+	// With a single floor tile both stairs can't be placed
+	m.Tile(3, 3).makeFloor()
+	if placeStairs(newRNG(1), m) {
+		t.Errorf("stairs up and down can't share a single floor tile")
+	}
+
+	m = openMap(8, 8)
+	if !placeStairs(newRNG(1), m) || m.upStairs == m.downStairs {
+		t.Errorf("an open map should have room for both stairs")
+	}
+}
+
+// Seed 16 used to start the player on top of a goblin
+func TestStartTileFree(t *testing.T) {
+	for seed := uint64(1); seed <= 50; seed++ {
+		g := NewGame("../assets/datafiles", seed, "")
+		if c := g.player.currentTile.creature; c != nil {
+			t.Errorf("seed %d starts the player on the same tile as a %s", seed, c.id)
+		}
+	}
+}
+
+func TestLevelsPersist(t *testing.T) {
+	g := NewGame("../assets/datafiles", 5, "")
+	first := g.gameMap
+	creatures := len(first.creatures)
+
+	g.player.moveToTile(first.TileAt(first.downStairs))
+	if res := g.ProcessAction(NewDescendAction()); !res.Success {
+		t.Fatalf("descending the stairs failed")
+	}
+
+	if g.Depth() != 2 || g.player.Pos() != g.gameMap.upStairs {
+		t.Fatalf("after descending depth = %d, player at %v; want 2, %v", g.Depth(), g.player.Pos(), g.gameMap.upStairs)
+	}
+
+	if res := g.ProcessAction(NewDescendAction()); res.Success {
+		t.Errorf("descending without being on stairs down should fail")
+	}
+
+	if res := g.ProcessAction(NewAscendAction()); !res.Success {
+		t.Fatalf("climbing the stairs failed")
+	}
+
+	if g.gameMap != first || g.Depth() != 1 {
+		t.Fatalf("climbing the stairs did not return to the first level")
+	}
+
+	if len(first.creatures) != creatures || !g.player.currentTile.seen {
+		t.Errorf("first level was not kept as it was left")
+	}
+}
+
+func TestCreaturesFitDepth(t *testing.T) {
+	gen, err := newCreatureGenerator("../assets/datafiles/creatures.yaml", newRNG(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, depth := range []int{1, 5, 10, 30} {
+		for i := 0; i < 200; i++ {
+			c := gen.createRandomCreature(depth)
+			if c.depth != depth {
+				t.Fatalf("creature depth not set, got %d want %d", c.depth, depth)
+			}
+
+			if depth < gen.minDepth[c.id] || (gen.maxDepth[c.id] > 0 && depth > gen.maxDepth[c.id]) {
+				t.Errorf("%s found at depth %d, outside its range %d-%d", c.id, depth, gen.minDepth[c.id], gen.maxDepth[c.id])
+			}
+
+			if base := gen.createCreature(c.id); depth > gen.minDepth[c.id] && c.maxHP <= base.maxHP {
+				t.Errorf("%s at depth %d should be tougher than at its native depth", c.id, depth)
+			}
+		}
+	}
+
+	if _, err := parseAttack("2d4+1"); err != nil {
+		t.Errorf("dice attack should parse: %v", err)
+	}
+
+	if dice, err := parseAttack("6"); err != nil || dice != (DiceRoll{1, 6, 0}) {
+		t.Errorf("plain number attack should be 1d6, got %v %v", dice, err)
+	}
+}
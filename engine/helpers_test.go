@@ -0,0 +1,40 @@
+package engine
+
+// ============================================================================
+// Helpers shared by the tests, for setting up small games & maps
+// ============================================================================
+
+import "testing"
+
+// A map with no walls at all
+func openMap(width, height int) *GameMap {
+	m := NewMap(width, height, 1)
+	m.setArea(false, 0, 0, width, height)
+
+	return m
+}
+
+// Small open game with nothing in it, the player is in the middle
+func testGame(t *testing.T) *Game {
+	t.Helper()
+	g := NewGame("../assets/datafiles", 1, "")
+
+	m := openMap(9, 9)
+	g.gameMap = m
+	g.levels = []*GameMap{m}
+	g.player.moveToTile(m.Tile(4, 4))
+	g.updateFOV()
+
+	return g
+}
+
+// A rat which won't move or die, placed where the player can see it
+func targetRat(g *Game, x, y int) *creature {
+	rat := g.creatureGen.createCreature("rat")
+	rat.ai = nil
+	rat.hp, rat.maxHP = 1000, 1000
+	g.spawnCreature(rat, g.gameMap.Tile(x, y))
+	g.updateFOV()
+
+	return rat
+}
@@ -0,0 +1,251 @@
+package engine
+
+// ============================================================================
+// Hooks are scripts in the datafiles run when things happen in the game
+//
+//	creatures  onSpawn, onTurn, onHit, onDeath
+//	furniture  onInteract
+//	levels     onEnter (levels.yaml)
+//
+// Hooks run like any other script (see scripts.go) with some extra globals,
+// and calling cancel() stops the default behaviour, e.g. a creature's onDeath
+// hook can cancel its death, and a furniture onInteract hook can stop a door opening
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scripts run by creatures
+type creatureHooks struct {
+	onSpawn string // When the creature first appears
+	onTurn  string // Before the creature's AI takes a turn
+	onHit   string // When the player hits the creature, the damage has already been done
+	onDeath string // When the player kills the creature
+}
+
+// Level scripts loaded from levels.yaml, keyed by depth
+type levelHooks struct {
+	onEnter      map[int]string
+	defaultEnter string // Used for levels not in the file
+}
+
+type yamlLevel struct {
+	OnEnter string `yaml:"onEnter"`
+}
+
+type yamlLevelsFile struct {
+	Levels map[string]yamlLevel `yaml:"levels"`
+}
+
+func (e yamlLevel) check() []fieldProblem {
+	return checkScript("onEnter", e.OnEnter)
+}
+
+func loadLevelHooks(dataFile string) (*levelHooks, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlLevelsFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := levelHooks{onEnter: make(map[int]string)}
+	for key, entry := range file.Levels {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("level '%s' %s", key, problems[0])
+		}
+
+		if key == "default" {
+			hooks.defaultEnter = entry.OnEnter
+			continue
+		}
+
+		depth, err := parseLevelKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		hooks.onEnter[depth] = entry.OnEnter
+	}
+
+	return &hooks, nil
+}
+
+// Levels are keyed by depth, apart from the default
+func parseLevelKey(key string) (int, error) {
+	depth, err := strconv.Atoi(key)
+	if err != nil || depth < 1 {
+		return 0, fmt.Errorf("level '%s' should be a depth or 'default'", key)
+	}
+
+	return depth, nil
+}
+
+func (h levelHooks) enterScript(depth int) string {
+	if script, ok := h.onEnter[depth]; ok {
+		return script
+	}
+
+	return h.defaultEnter
+}
+
+// ===== Running Hooks ========================================================
+
+// Run a hook script, returns true if the script called cancel()
+// A string result is shown as a message, the same as item scripts
+func (g *Game) runHook(source string, owner entity, script string, globals map[string]any) bool {
+	if script == "" {
+		return false
+	}
+
+	cancelled := false
+	if globals == nil {
+		globals = make(map[string]any)
+	}
+
+	globals["cancel"] = func() {
+		cancelled = true
+	}
+
+	result, err := runScript(g, source, script, globals)
+	if err != nil {
+		events.new(EventSystemMsg, owner, err.Error())
+		return false
+	}
+
+	if msgText, ok := result.Export().(string); ok {
+		events.new(EventMiscMessage, owner, msgText)
+	}
+
+	if g.player.hp <= 0 {
+		cause := "dark magic"
+		if named, ok := owner.(interface{ Name() string }); ok {
+			cause = "a " + named.Name()
+		}
+
+		g.killPlayer(owner, cause)
+	}
+
+	return cancelled
+}
+
+// Run one of a creature's hooks, creatures brought to zero HP by a script die
+// apart from in onHit & onDeath, where the attack decides what happens
+func (g *Game) runCreatureHook(c *creature, hook string, script string, globals map[string]any) bool {
+	if script == "" {
+		return false
+	}
+
+	if globals == nil {
+		globals = make(map[string]any)
+	}
+
+	globals["creature"] = scriptCreatureAPI(g, c)
+	cancelled := g.runHook(fmt.Sprintf("creature '%s' %s", c.id, hook), c, script, globals)
+
+	if c.hp <= 0 && c.currentTile != nil && hook != "onHit" && hook != "onDeath" {
+		g.killCreature(c, g.playerActing, fmt.Sprintf("The %s dies", c.Name()))
+	}
+
+	return cancelled
+}
+
+// A creature has been brought to zero HP, it's removed and drops any loot
+// The player gains its experience if they killed it, however it died
+// Returns false if its onDeath hook cancelled the death, it's left with at least 1 HP
+func (g *Game) killCreature(c *creature, byPlayer bool, message string) bool {
+	if g.runCreatureHook(c, "onDeath", c.hooks.onDeath, nil) {
+		c.hp = max(c.hp, 1)
+		return false
+	}
+
+	deathTile := c.currentTile
+	if deathTile == nil {
+		return true
+	}
+
+	g.gameMap.removeCreature(c)
+	events.new(EventCreatureKilled, c, message)
+
+	if item := g.itemGen.dropLoot(c.lootTable, g.gameMap.depth); deathTile.addItem(item) {
+		events.new(EventItemDropped, item, fmt.Sprintf("The %s drops a %s", c.Name(), item.Name()))
+	}
+
+	if byPlayer {
+		g.gainExp(c.xp)
+	}
+
+	return true
+}
+
+// An explosion, damaging the player and any creatures in range
+func (g *Game) blast(centre pos, radius int, damage DiceRoll) {
+	if g.player.pos.Distance(centre) <= float64(radius) {
+		dmg := max(damage.Roll(g.rng), 0)
+		g.player.applyDamage(dmg)
+		events.new(EventPlayerHit, nil, fmt.Sprintf("The blast hits you for %d damage", dmg))
+	}
+
+	for _, c := range append([]*creature(nil), g.gameMap.creatures...) {
+		if c.currentTile == nil || c.pos.Distance(centre) > float64(radius) {
+			continue
+		}
+
+		c.applyDamage(max(damage.Roll(g.rng), 0))
+		if c.hp <= 0 {
+			g.killCreature(c, g.playerActing, fmt.Sprintf("The %s is killed by the blast", c.Name()))
+		}
+	}
+}
+
+// Put a creature on the map and run its onSpawn hook, which can stop it appearing
+func (g *Game) spawnCreature(c *creature, t *tile) bool {
+	if c == nil || !g.gameMap.placeCreature(c, t) {
+		return false
+	}
+
+	if g.runCreatureHook(c, "onSpawn", c.hooks.onSpawn, nil) && c.currentTile != nil {
+		g.gameMap.removeCreature(c)
+		return false
+	}
+
+	return c.currentTile != nil
+}
+
+// Run the onSpawn hooks for everything on a newly generated level
+func (g *Game) spawnLevelCreatures(m *GameMap) {
+	for _, c := range append([]*creature(nil), m.creatures...) {
+		if g.runCreatureHook(c, "onSpawn", c.hooks.onSpawn, nil) && c.currentTile != nil {
+			m.removeCreature(c)
+		}
+	}
+}
+
+// Run a furniture's onInteract hook, action is what the player is doing, e.g. open or bash
+func (g *Game) runFurnitureHook(f *furniture, action string) bool {
+	if f.onInteract == "" {
+		return false
+	}
+
+	return g.runHook(fmt.Sprintf("furniture '%s' onInteract", f.id), f, f.onInteract, map[string]any{
+		"furniture": scriptFurnitureAPI(g, f),
+		"action":    action,
+	})
+}
+
+// Run the onEnter hook for the current level
+func (g *Game) runLevelHook(firstVisit bool) bool {
+	depth := g.gameMap.depth
+	return g.runHook(fmt.Sprintf("level %d onEnter", depth), nil, g.levelHooks.enterScript(depth), map[string]any{
+		"firstVisit": firstVisit,
+	})
+}
@@ -0,0 +1,143 @@
+package engine
+
+// ============================================================================
+// Tests for script hooks on creatures, furniture and levels
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+// Put a rat with some hooks next to the player, east of them
+func hookedRat(t *testing.T, g *Game, hooks creatureHooks) *creature {
+	t.Helper()
+	rat := g.creatureGen.createCreature("rat")
+	rat.hooks = hooks
+	rat.speed = 0
+
+	if !g.spawnCreature(rat, g.gameMap.Tile(5, 4)) {
+		t.Fatalf("rat should spawn")
+	}
+
+	return rat
+}
+
+// Attack until something hits, fumbles mean the first attack can miss
+func attackUntilHit(g *Game, c *creature, check func() bool) {
+	for i := 0; i < 50 && c.currentTile != nil && !check(); i++ {
+		g.ProcessAction(NewAttackAction(c))
+	}
+}
+
+func TestCreatureHooks(t *testing.T) {
+	g := testGame(t)
+
+	immune := hookedRat(t, g, creatureHooks{onHit: "cancel()"})
+	for i := 0; i < 20; i++ {
+		g.ProcessAction(NewAttackAction(immune))
+	}
+
+	if immune.hp != immune.maxHP {
+		t.Errorf("cancelled onHit should undo the damage, hp %d/%d", immune.hp, immune.maxHP)
+	}
+
+	g.gameMap.removeCreature(immune)
+
+	undying := hookedRat(t, g, creatureHooks{onDeath: "creature.setHP(3); cancel()"})
+	undying.hp = 1
+	attackUntilHit(g, undying, func() bool { return undying.hp == 3 })
+
+	if undying.currentTile == nil || undying.hp != 3 {
+		t.Errorf("cancelled onDeath should keep the creature alive, hp %d", undying.hp)
+	}
+
+	g.gameMap.removeCreature(undying)
+
+	ticks := hookedRat(t, g, creatureHooks{onTurn: "creature.setHP(2)"})
+	ticks.speed = speedNormal
+	g.ProcessAction(NewWaitAction())
+	if ticks.hp != 2 {
+		t.Errorf("onTurn hook should have run, hp %d", ticks.hp)
+	}
+
+	g.gameMap.removeCreature(ticks)
+
+	shy := g.creatureGen.createCreature("rat")
+	shy.hooks.onSpawn = "cancel()"
+	if g.spawnCreature(shy, g.gameMap.Tile(5, 4)) || len(g.gameMap.creatures) != 0 {
+		t.Errorf("cancelled onSpawn should stop the creature appearing")
+	}
+}
+
+// A slime walled in with the player and one free tile next to it must split onto the free tile
+func TestSlimeSplitNotOnPlayer(t *testing.T) {
+	g := testGame(t)
+	g.gameMap.setArea(true, 4, 3, 3, 3)
+	g.gameMap.Tile(4, 4).makeFloor()
+	g.gameMap.Tile(5, 4).makeFloor()
+	g.gameMap.Tile(6, 5).makeFloor()
+
+	slime := g.creatureGen.createCreature("slime")
+	slime.ai = nil
+	if !g.spawnCreature(slime, g.gameMap.Tile(5, 4)) {
+		t.Fatalf("slime should spawn")
+	}
+
+	// Splitting is down to chance, and once the free tile is taken there's nowhere left
+	for i := 0; i < 20; i++ {
+		slime.hp = slime.maxHP
+		g.runCreatureHook(slime, "onHit", slime.hooks.onHit, map[string]any{"damage": 1})
+	}
+
+	if g.player.currentTile.creature != nil {
+		t.Errorf("slime split onto the player")
+	}
+
+	if len(g.gameMap.creatures) != 2 || g.gameMap.Tile(6, 5).creature == nil {
+		t.Errorf("slime should split once onto the free tile, %d creatures on the map", len(g.gameMap.creatures))
+	}
+}
+
+func TestFurnitureHooks(t *testing.T) {
+	g := testGame(t)
+
+	door := g.furnitureGen.createFurniture("door")
+	door.onInteract = `if (action == "open") { cancel(); "It's stuck" }`
+	g.gameMap.Tile(4, 3).placeFurniture(door)
+
+	g.ProcessAction(NewOpenAction(core.DirNorth))
+	g.ProcessAction(NewMoveAction(core.DirNorth))
+	if door.open {
+		t.Errorf("cancelled onInteract should keep the door shut")
+	}
+
+	keg := g.furnitureGen.createFurniture("keg")
+	kegTile := g.gameMap.Tile(5, 4)
+	kegTile.placeFurniture(keg)
+	g.player.hp = 100
+	g.player.maxHP = 100
+
+	g.ProcessAction(NewBashAction(core.DirEast))
+	if kegTile.furniture != nil || g.player.hp >= 100 {
+		t.Errorf("bashing the keg should blow it up and hurt the player, hp %d", g.player.hp)
+	}
+}
+
+func TestLevelEnterHook(t *testing.T) {
+	g := NewGame("../assets/datafiles", 5, "")
+	g.levelHooks.onEnter[2] = `if (firstVisit) { cancel(); "The way is barred" }`
+
+	start := g.player.currentTile
+	g.player.moveToTile(g.gameMap.TileAt(g.gameMap.downStairs))
+	g.ProcessAction(NewDescendAction())
+
+	if g.Depth() != 1 || g.player.currentTile == start {
+		t.Fatalf("cancelled onEnter should leave the player on the stairs of level 1, depth %d", g.Depth())
+	}
+
+	g.ProcessAction(NewDescendAction())
+	if g.Depth() != 2 {
+		t.Errorf("second visit should be allowed, depth %d", g.Depth())
+	}
+}
@@ -0,0 +1,100 @@
+package engine
+
+// ============================================================================
+// Unidentified items, potions, scrolls & rings look like one of the
+// appearances for their kind until the player uses them or identifies them
+// some other way. Which appearance goes with which item is shuffled for every
+// game, and the items the player knows are saved with the game
+// ============================================================================
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+)
+
+// Mixed with the game seed for the appearance shuffle, so it doesn't follow the same sequence as the game RNG
+const appearanceStream = 0xa99ea7
+
+// What an unidentified item looks like
+type appearance struct {
+	Name   string `yaml:"name"`
+	Colour string `yaml:"colour"`
+}
+
+// Which items the player knows and what the rest look like, shared by every item in a game
+type identities struct {
+	looks map[string]appearance // Item id to what it looks like until identified
+	known map[string]bool       // Item ids the player has identified
+}
+
+// Give each unidentified item one of the appearances for its kind, shuffled using the game seed
+// This uses its own RNG rather than the game's, so loading a game gets the same shuffle
+// without it being saved, and the game RNG isn't moved on
+func (gen *itemGenerator) shuffleAppearances(seed uint64) {
+	r := rand.New(rand.NewPCG(seed, appearanceStream))
+	gen.ident.looks = make(map[string]appearance)
+
+	for _, kind := range slices.Sorted(maps.Keys(gen.appearances)) {
+		looks := slices.Clone(gen.appearances[kind])
+		r.Shuffle(len(looks), func(i, j int) {
+			looks[i], looks[j] = looks[j], looks[i]
+		})
+
+		// Keys are sorted, so the same seed always gives the same appearances
+		next := 0
+		for _, id := range gen.keys {
+			if gen.appearanceKind[id] == kind {
+				gen.ident.looks[id] = looks[next]
+				next++
+			}
+		}
+	}
+}
+
+// IsIdentified is true if the player knows what the item is
+func (i Item) IsIdentified() bool {
+	if i.ident == nil {
+		return true
+	}
+
+	_, hasLook := i.ident.looks[i.id]
+	return !hasLook || i.ident.known[i.id]
+}
+
+// Identify a kind of item for the rest of the game, returns false if it was already known
+func (g *Game) identify(i *Item) bool {
+	if i.IsIdentified() {
+		return false
+	}
+
+	look := i.Name()
+	i.ident.known[i.id] = true
+	events.new(EventItemIdentified, i, fmt.Sprintf("The %s is a %s", look, i.name))
+
+	return true
+}
+
+// Identify a random unknown item the player is carrying, returns nil if everything is known
+func (g *Game) identifyCarried() *Item {
+	unknown := make([]*Item, 0)
+	for _, i := range g.player.Inventory() {
+		if !i.IsIdentified() && !slices.ContainsFunc(unknown, func(u *Item) bool { return u.id == i.id }) {
+			unknown = append(unknown, i)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	i := unknown[g.rng.IntN(len(unknown))]
+	g.identify(i)
+	return i
+}
+
+// Item ids the player has identified, in name order for saving
+func (ids *identities) knownIDs() []string {
+	return slices.Sorted(maps.Keys(ids.known))
+}
@@ -0,0 +1,112 @@
+package engine
+
+// ============================================================================
+// Tests for unidentified items and their shuffled appearances
+// ============================================================================
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAppearancesShuffledPerSeed(t *testing.T) {
+	looks := func(seed uint64) map[string]appearance {
+		return NewGame("../assets/datafiles", seed, "").itemGen.ident.looks
+	}
+
+	first := looks(1)
+	if !maps.Equal(first, looks(1)) {
+		t.Errorf("the same seed should give the same appearances")
+	}
+
+	if maps.Equal(first, looks(2)) && maps.Equal(first, looks(3)) {
+		t.Errorf("different seeds should shuffle the appearances")
+	}
+
+	seen := make(map[string]string)
+	for id, look := range first {
+		if other, ok := seen[look.Name]; ok {
+			t.Errorf("'%s' and '%s' both look like a %s", id, other, look.Name)
+		}
+
+		seen[look.Name] = id
+	}
+
+	if _, ok := first["potion_healing"]; !ok {
+		t.Errorf("potions should have an appearance")
+	}
+}
+
+func TestIdentifyOnUse(t *testing.T) {
+	g := testGame(t)
+	potion := g.itemGen.createItem("potion_haste")
+	other := g.itemGen.createItem("potion_haste")
+	g.player.backpack.Add(potion)
+
+	look := g.itemGen.ident.looks["potion_haste"]
+	if potion.IsIdentified() || potion.Name() != look.Name || potion.Appearance().Colour != look.Colour {
+		t.Fatalf("an unknown potion should look like a %s, got %s", look.Name, potion.Name())
+	}
+
+	if res := g.ProcessAction(NewUseAction(potion)); !res.Success {
+		t.Fatalf("drinking the potion should succeed")
+	}
+
+	if !other.IsIdentified() || other.Name() != "potion of haste" || other.NameTitle() != "Potion of haste" {
+		t.Errorf("every potion of haste should be known once one is drunk, got %s", other.Name())
+	}
+
+	ring := g.itemGen.createItem("ring_accuracy")
+	g.player.backpack.Add(ring)
+	if ring.DescribeEffects() != "unknown" {
+		t.Errorf("an unknown ring shouldn't give away its effects, got %s", ring.DescribeEffects())
+	}
+
+	g.ProcessAction(NewEquipAction(ring))
+	if !ring.IsIdentified() {
+		t.Errorf("putting on a ring should identify it")
+	}
+}
+
+func TestIdentifyScroll(t *testing.T) {
+	g := testGame(t)
+	ring := g.itemGen.createItem("ring_power")
+	scroll := g.itemGen.createItem("scroll_of_identify")
+	g.player.backpack.Add(ring)
+	g.player.backpack.Add(scroll)
+
+	if res := g.ProcessAction(NewUseAction(scroll)); !res.Success {
+		t.Fatalf("reading the scroll should succeed")
+	}
+
+	if !ring.IsIdentified() {
+		t.Errorf("the scroll should identify the ring, not itself")
+	}
+
+	if g.identifyCarried() != nil {
+		t.Errorf("nothing should be left to identify")
+	}
+}
+
+func TestIdentifiedItemsSaved(t *testing.T) {
+	g := NewGame("../assets/datafiles", 9, "warrior")
+	if !g.itemGen.createItem("potion_healing").IsIdentified() {
+		t.Errorf("items in the starting kit should be known")
+	}
+
+	g.identify(g.itemGen.createItem("scroll_of_cthon"))
+
+	saved, err := g.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGame("../assets/datafiles", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !maps.Equal(loaded.itemGen.ident.known, g.itemGen.ident.known) || !maps.Equal(loaded.itemGen.ident.looks, g.itemGen.ident.looks) {
+		t.Errorf("a loaded game should know the same items, and they should look the same")
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+// ============================================================================
+// Stacks of items & how much the player is carrying
+// Stackable items of the same kind share a single backpack slot, they are
+// merged when picked up and split when some are dropped, thrown or used.
+// Carrying more than the player's capacity slows them down
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+)
+
+type encumbrance int
+
+const (
+	encumbranceNone       encumbrance = iota
+	encumbranceBurdened               // Carrying more than the player's capacity
+	encumbranceOverloaded             // Carrying more than half as much again
+)
+
+// Energy a move costs at each level of encumbrance
+var encumbranceMoveEnergy = map[encumbrance]int{
+	encumbranceNone:       energyPerTurn,
+	encumbranceBurdened:   energyPerTurn * 3 / 2,
+	encumbranceOverloaded: energyPerTurn * 2,
+}
+
+// Take some items off a stack as a new stack, giving the whole stack if count covers it
+func (i *Item) split(r *gameRNG, count int) *Item {
+	if count <= 0 || count >= i.quantity {
+		return i
+	}
+
+	part := *i
+	part.instanceID = core.RandId(r.Rand, 6)
+	part.pos = nil
+	part.quantity = count
+	i.quantity -= count
+
+	return &part
+}
+
+// The stack in the backpack an item would be added to, if there is one
+func (p *Player) stackFor(item *Item) *Item {
+	if !item.stackable {
+		return nil
+	}
+
+	for _, i := range p.backpack.AllItems() {
+		if i.id == item.id && i != item {
+			return i
+		}
+	}
+
+	return nil
+}
+
+// Put an item in the backpack, adding it to a stack of the same kind if there is one
+// Returns the item or stack it ended up in, nil if it needs a slot and the backpack is full
+func (p *Player) addToBackpack(item *Item) *Item {
+	if stack := p.stackFor(item); stack != nil {
+		stack.quantity += item.quantity
+		return stack
+	}
+
+	if len(p.backpack) >= PLAYER_MAX_ITEMS {
+		return nil
+	}
+
+	p.backpack.Add(item)
+	return item
+}
+
+// Take some of an item out of the backpack, splitting its stack if there are more
+// Returns what was taken, nil if the item isn't in the backpack
+func (p *Player) takeFromBackpack(r *gameRNG, item *Item, count int) *Item {
+	// The item might be a copy, so find the one actually carried
+	for _, carried := range p.backpack.AllItems() {
+		if carried.instanceID != item.instanceID {
+			continue
+		}
+
+		taken := carried.split(r, count)
+		if taken == carried {
+			p.backpack.Remove(carried)
+		}
+
+		return taken
+	}
+
+	return nil
+}
+
+// CarriedWeight is the weight of everything the player has, equipped or not
+func (p *Player) CarriedWeight() int {
+	total := 0
+	for _, i := range p.Inventory() {
+		total += i.StackWeight()
+	}
+
+	return total
+}
+
+// CarryCapacity is how much the player can carry before being slowed down
+func (p *Player) CarryCapacity() int {
+	return PLAYER_MAX_WEIGHT
+}
+
+func (p *Player) encumbrance() encumbrance {
+	switch weight := p.CarriedWeight(); {
+	case weight > p.CarryCapacity()*3/2:
+		return encumbranceOverloaded
+	case weight > p.CarryCapacity():
+		return encumbranceBurdened
+	}
+
+	return encumbranceNone
+}
+
+// Encumbrance describes how weighed down the player is, blank if they aren't
+func (p *Player) Encumbrance() string {
+	switch p.encumbrance() {
+	case encumbranceBurdened:
+		return "burdened"
+	case encumbranceOverloaded:
+		return "overloaded"
+	}
+
+	return ""
+}
+
+// Energy the player uses moving a single tile, more when carrying too much
+func (p *Player) moveEnergy() int {
+	return encumbranceMoveEnergy[p.encumbrance()]
+}
+
+// Let the player know when what they carry starts or stops slowing them down
+func (g *Game) checkEncumbrance(before encumbrance) {
+	after := g.player.encumbrance()
+	if after == before {
+		return
+	}
+
+	msg := "You can move freely again"
+	switch after {
+	case encumbranceBurdened:
+		msg = "You are burdened by everything you carry"
+	case encumbranceOverloaded:
+		msg = "You can barely move under the weight you carry"
+	}
+
+	events.new(EventEncumbrance, nil, fmt.Sprintf("%s (%d/%d)", msg, g.player.CarriedWeight(), g.player.CarryCapacity()))
+}
@@ -0,0 +1,146 @@
+package engine
+
+// ============================================================================
+// Tests for stacks of items & encumbrance
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+// Put some items on the ground next to the player and walk onto them
+func walkOntoItems(t *testing.T, g *Game, items ...*Item) {
+	t.Helper()
+	for _, i := range items {
+		g.gameMap.Tile(4, 3).addItem(i)
+	}
+
+	if res := g.ProcessAction(NewMoveAction(core.DirNorth)); !res.Success {
+		t.Fatalf("player should be able to move")
+	}
+}
+
+func TestItemStacking(t *testing.T) {
+	g := testGame(t)
+	slots := len(g.player.backpack)
+
+	arrows := g.itemGen.createItem("arrow")
+	arrows.quantity = 3
+	g.player.backpack.Add(arrows)
+
+	more := g.itemGen.createItem("arrow")
+	more.quantity = 2
+	walkOntoItems(t, g, more)
+
+	if len(g.player.backpack) != slots+1 || arrows.Quantity() != 5 || arrows.NameQuantity() != "arrow (x5)" {
+		t.Errorf("arrows picked up should join the stack, got %d in %d slots", arrows.Quantity(), len(g.player.backpack))
+	}
+
+	if g.player.addToBackpack(g.itemGen.createItem("sword")) == nil || len(g.player.backpack) != slots+2 {
+		t.Errorf("items which don't stack should take their own slot")
+	}
+
+	// Dropping some of a stack splits it, leaving the rest behind
+	if res := g.ProcessAction(NewDropCountAction(arrows, 2)); !res.Success {
+		t.Fatalf("dropping some arrows should succeed")
+	}
+
+	dropped := g.player.currentTile.items.AllItems()
+	if arrows.quantity != 3 || len(dropped) != 1 || dropped[0].quantity != 2 || dropped[0] == arrows {
+		t.Errorf("2 arrows should be dropped as a new stack, 3 left carried")
+	}
+
+	g.ProcessAction(NewDropAction(arrows))
+	if g.player.backpack.Contains(arrows) || g.player.currentTile.items.Count() != 2 {
+		t.Errorf("dropping without a count should drop the whole stack")
+	}
+}
+
+func TestUseAndThrowFromStack(t *testing.T) {
+	g := testGame(t)
+
+	potions := g.itemGen.createItem("potion_healing")
+	potions.quantity = 2
+	g.player.backpack.Add(potions)
+
+	if res := g.ProcessAction(NewUseAction(potions)); !res.Success || potions.quantity != 1 || !g.player.backpack.Contains(potions) {
+		t.Errorf("drinking a potion should only use one from the stack")
+	}
+
+	g.ProcessAction(NewUseAction(potions))
+	if g.player.backpack.Contains(potions) {
+		t.Errorf("the last potion in a stack should be used up")
+	}
+
+	knives := g.itemGen.createItem("throwing_knife")
+	knives.quantity = 3
+	g.player.backpack.Add(knives)
+	rat := targetRat(g, 4, 2)
+
+	if res := g.ProcessAction(NewThrowAction(knives, rat)); !res.Success || knives.quantity != 2 {
+		t.Fatalf("throwing a knife should take one from the stack")
+	}
+
+	thrown := g.gameMap.Tile(4, 2).items.AllItems()
+	if len(thrown) != 1 || thrown[0].quantity != 1 || thrown[0].instanceID == knives.instanceID {
+		t.Errorf("a single knife should land by the rat")
+	}
+}
+
+func TestEncumbrance(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+
+	if p.Encumbrance() != "" || p.moveEnergy() != energyPerTurn {
+		t.Fatalf("starting kit shouldn't slow the player, carrying %d", p.CarriedWeight())
+	}
+
+	warned := ""
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventEncumbrance {
+			warned = e.Text()
+		}
+	})
+
+	// Warriors carry 35 in their kit, another suit of chainmail takes them over
+	carried := p.CarriedWeight()
+	walkOntoItems(t, g, g.itemGen.createItem("chainmail"))
+
+	if p.CarriedWeight() != carried+30 || p.Encumbrance() != "burdened" {
+		t.Errorf("picking up chainmail should burden the player, carrying %d", p.CarriedWeight())
+	}
+
+	if warned == "" {
+		t.Errorf("the player should be told they are burdened")
+	}
+
+	if res := g.ProcessAction(NewMoveAction(core.DirSouth)); res.EnergySpent != energyPerTurn*3/2 {
+		t.Errorf("burdened moves should cost more energy, got %d", res.EnergySpent)
+	}
+
+	p.addToBackpack(g.itemGen.createItem("axe"))
+	p.addToBackpack(g.itemGen.createItem("axe"))
+	if p.Encumbrance() != "overloaded" || p.moveEnergy() != energyPerTurn*2 {
+		t.Errorf("carrying half as much again over capacity should overload the player, carrying %d", p.CarriedWeight())
+	}
+}
+
+func TestStackSaved(t *testing.T) {
+	g := NewGame("../assets/datafiles", 3, "rogue")
+	arrows := g.player.ammoFor(g.player.launcher())
+
+	saved, err := g.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGame("../assets/datafiles", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i := loaded.findItem(arrows.instanceID); i == nil || i.quantity != 4 {
+		t.Errorf("stack quantities should be saved")
+	}
+}
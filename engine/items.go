@@ -3,12 +3,11 @@ package engine
 
 import (
 	"fmt"
-	"math/rand/v2"
+	"maps"
 	"roguelike/core"
 	"slices"
 	"strings"
 
-	"github.com/dop251/goja"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,13 +19,24 @@ type Item struct {
 	entityBase
 	usable        bool          // Can be used by the player
 	equipLocation equipLocation // Where the item can be equipped
+	twoHanded     bool          // Weapons which need both hands, so no shield can be used
 	dropped       bool          // Previously dropped on the ground
-	weight        int           // Weight of the item
+	weight        int           // Weight of a single item, see StackWeight for a whole stack
+	stackable     bool          // Items of the same kind share a backpack slot, e.g. arrows
+	quantity      int           // How many items are in the stack, always 1 for items which don't stack
 	onUseScript   string        // Script to run when the item is used
 	rarity        rarity        // Rarity of the item
 	consumable    bool          // Consumable items are removed from the player's inventory when used
 	equipped      bool          // Is the item currently equipped
 	effects       []effect      // Effects of the item
+	missileRange  int           // How far the item can be fired or thrown
+	fires         string        // Kind of ammunition a launcher fires, e.g. arrow
+	ammo          string        // Kind of ammunition this item is
+	rangedDamage  DiceRoll      // Damage done by a launcher's shots, or by the item when thrown
+	spell         string        // Spell a wand casts when zapped
+	charges       int           // Zaps left in a wand
+	learnSpell    string        // Spell the player learns when using the item, e.g. a scroll
+	ident         *identities   // What the player knows about items, see identify.go
 }
 
 func (i Item) Type() entityType {
@@ -37,6 +47,37 @@ func (i Item) String() string {
 	return fmt.Sprintf("item_%s_%s at %s", i.name, i.id, i.pos)
 }
 
+// Name is what the player knows the item as, unidentified items go by their appearance
+func (i Item) Name() string {
+	if !i.IsIdentified() {
+		return i.ident.looks[i.id].Name
+	}
+
+	return i.name
+}
+
+// NameQuantity is the item's name, with how many there are for a stack of more than one
+func (i Item) NameQuantity() string {
+	if i.quantity > 1 {
+		return fmt.Sprintf("%s (x%d)", i.Name(), i.quantity)
+	}
+
+	return i.Name()
+}
+
+func (i Item) NameTitle() string {
+	name := i.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func (i Item) Description() string {
+	if !i.IsIdentified() {
+		return "You won't know what this is until you try it"
+	}
+
+	return i.desc
+}
+
 func (i Item) BlocksLOS() bool {
 	return false
 }
@@ -49,6 +90,11 @@ func (i Item) EquipLocation() equipLocation {
 	return i.equipLocation
 }
 
+// Two-handed weapons can't be wielded with a shield
+func (i Item) TwoHanded() bool {
+	return i.twoHanded
+}
+
 func (i Item) IsEquipment() bool {
 	return i.equipLocation != EquipLocationNone
 }
@@ -61,6 +107,19 @@ func (i Item) Weight() int {
 	return i.weight
 }
 
+// StackWeight is the weight of every item in the stack
+func (i Item) StackWeight() int {
+	return i.weight * i.quantity
+}
+
+func (i Item) Stackable() bool {
+	return i.stackable
+}
+
+func (i Item) Quantity() int {
+	return i.quantity
+}
+
 func (i Item) Rarity() rarity {
 	return i.rarity
 }
@@ -69,7 +128,39 @@ func (i Item) IsEquipped() bool {
 	return i.equipped
 }
 
+// A launcher fires ammunition from the missile slot, e.g. a bow
+func (i Item) IsLauncher() bool {
+	return i.fires != ""
+}
+
+// Throwable items do damage when thrown at a creature
+func (i Item) Throwable() bool {
+	return !i.IsLauncher() && i.rangedDamage != (DiceRoll{})
+}
+
+// How far the item can be fired or thrown, in tiles
+func (i Item) Range() int {
+	return i.missileRange
+}
+
+// Wands cast a spell when zapped, until their charges run out
+func (i Item) IsWand() bool {
+	return i.spell != ""
+}
+
+func (i Item) Charges() int {
+	return i.charges
+}
+
 func (i Item) ItemType() string {
+	if i.IsWand() {
+		return "Wand"
+	}
+
+	if i.ammo != "" {
+		return "Ammunition"
+	}
+
 	if i.usable {
 		return "Usable / Consumable"
 	}
@@ -82,6 +173,10 @@ func (i Item) ItemType() string {
 }
 
 func (i Item) DescribeEffects() string {
+	if !i.IsIdentified() && len(i.effects) > 0 {
+		return "unknown"
+	}
+
 	descs := make([]string, 0)
 	for _, e := range i.effects {
 		descs = append(descs, e.description())
@@ -90,38 +185,50 @@ func (i Item) DescribeEffects() string {
 	return strings.Join(descs, ", ")
 }
 
-func (i Item) use(g Game) bool {
-	if i.onUseScript == "" || !i.usable {
-		return false
+// Describe how the item is used at range, blank if it isn't a missile weapon
+func (i Item) DescribeRanged() string {
+	switch {
+	case i.IsLauncher():
+		return fmt.Sprintf("fires %ss for %s, range %d", i.fires, i.rangedDamage.String(), i.missileRange)
+	case i.Throwable():
+		return fmt.Sprintf("thrown for %s, range %d", i.rangedDamage.String(), i.missileRange)
 	}
 
-	vm := goja.New()
+	return ""
+}
 
-	// Push some globals into the vm for use in the scriptlets
-	vm.Set("player", g.player)
-	vm.Set("chance", func(chance int) bool {
-		return rand.IntN(100) < chance
-	})
-	vm.Set("rand", func(min, max int) int {
-		return rand.IntN(max-min) + min
-	})
+func (i Item) use(g *Game) bool {
+	if (i.onUseScript == "" && i.learnSpell == "") || !i.usable {
+		return false
+	}
 
-	result, err := vm.RunString(i.onUseScript)
-	if err != nil {
-		events.new(EventSystemMsg, &i, err.Error())
+	// Scrolls of learning teach a spell, they can't be read again for one already known
+	if i.learnSpell != "" && !g.learnSpell(i.learnSpell) {
+		events.new(EventMiscMessage, &i, fmt.Sprintf("You already know everything the %s can teach you", i.Name()))
 		return false
 	}
 
-	if result != nil {
-		if msgText, ok := result.Export().(string); ok {
-			events.new(EventItemUsed, &i, msgText)
-		} else {
-			events.new(EventItemUsed, &i, "The item's effect is unknown")
+	// Using an item shows what it is, this is done first so a scroll of identify doesn't pick itself
+	g.identify(&i)
+
+	if i.onUseScript != "" {
+		result, err := runScript(g, fmt.Sprintf("item '%s'", i.id), i.onUseScript, nil)
+		if err != nil {
+			events.new(EventSystemMsg, &i, err.Error())
+			return false
+		}
+
+		if result != nil {
+			if msgText, ok := result.Export().(string); ok {
+				events.new(EventItemUsed, &i, msgText)
+			} else {
+				events.new(EventItemUsed, &i, "The item's effect is unknown")
+			}
 		}
 	}
 
-	// Items have single use
-	g.player.backpack.Remove(&i)
+	// Items have single use, only one is taken from a stack
+	g.player.takeFromBackpack(g.rng, &i, 1)
 
 	return true
 }
@@ -130,8 +237,24 @@ func (i Item) use(g Game) bool {
 
 // The itemGenerator is a kind of factory for creating items
 type itemGenerator struct {
+	rng          *gameRNG
 	genFunctions map[string](func() *Item)
 	keys         []string
+	rarity       map[string]rarity
+	minDepth     map[string]int      // Shallowest level each item is found on
+	maxDepth     map[string]int      // Deepest level each item is found on, zero means no limit
+	quantity     map[string]DiceRoll // How many of a stackable item are found together
+	lootTables   map[string]lootTable
+
+	appearances    map[string][]appearance // What unidentified items can look like, by kind
+	appearanceKind map[string]string       // Item id to its kind of appearance, for items which start unidentified
+	ident          *identities
+}
+
+// A loot table is a named set of items to pick from, e.g. for levels, chests or creatures
+type lootTable struct {
+	items  []string // Item ids in the table, every item if empty
+	chance int      // Percentage chance anything is dropped at all
 }
 
 type yamlItem struct {
@@ -141,17 +264,38 @@ type yamlItem struct {
 	Colour        string            `yaml:"colour"`
 	Usable        bool              `yaml:"usable"`
 	EquipLocation string            `yaml:"equipLocation"`
+	TwoHanded     bool              `yaml:"twoHanded"`
 	Weight        int               `yaml:"weight"`
+	Stackable     bool              `yaml:"stackable"`
+	Quantity      string            `yaml:"quantity"`
 	OnUseScript   string            `yaml:"onUseScript"`
 	Consumable    bool              `yaml:"consumable"`
 	Effects       map[string]string `yaml:"effects"`
+	Rarity        string            `yaml:"rarity"`
+	MinDepth      int               `yaml:"minDepth"`
+	MaxDepth      int               `yaml:"maxDepth"`
+	Range         int               `yaml:"range"`
+	Fires         string            `yaml:"fires"`
+	Ammo          string            `yaml:"ammo"`
+	RangedDamage  string            `yaml:"rangedDamage"`
+	Appearance    string            `yaml:"appearance"`
+	Spell         string            `yaml:"spell"`
+	Charges       int               `yaml:"charges"`
+	LearnSpell    string            `yaml:"learnSpell"`
+}
+
+type yamlLootTable struct {
+	Items  []string `yaml:"items"`
+	Chance *int     `yaml:"chance"` // Defaults to 100 when not given
 }
 
 type yamlItemsFile struct {
-	Items map[string]yamlItem `yaml:"items"`
+	Items       map[string]yamlItem      `yaml:"items"`
+	LootTables  map[string]yamlLootTable `yaml:"lootTables"`
+	Appearances map[string][]appearance  `yaml:"appearances"`
 }
 
-func newItemGenerator(dataFile string) (*itemGenerator, error) {
+func newItemGenerator(dataFile string, r *gameRNG) (*itemGenerator, error) {
 	data, err := core.ReadFile(dataFile)
 	if err != nil {
 		return nil, err
@@ -164,68 +308,134 @@ func newItemGenerator(dataFile string) (*itemGenerator, error) {
 	}
 
 	gen := itemGenerator{
+		rng:          r,
 		genFunctions: make(map[string](func() *Item)),
 		keys:         make([]string, 0),
+		rarity:       make(map[string]rarity),
+		minDepth:     make(map[string]int),
+		maxDepth:     make(map[string]int),
+		quantity:     make(map[string]DiceRoll),
+		lootTables:   make(map[string]lootTable),
+
+		appearances:    itemsFile.Appearances,
+		appearanceKind: make(map[string]string),
+		ident:          &identities{looks: make(map[string]appearance), known: make(map[string]bool)},
 	}
 
 	for id, entry := range itemsFile.Items {
-		// TODO: Needs to be rarity aware
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("item '%s' %s", id, problems[0])
+		}
+
+		if entry.Appearance != "" {
+			if _, ok := itemsFile.Appearances[entry.Appearance]; !ok {
+				return nil, fmt.Errorf("item '%s' has unknown appearance '%s'", id, entry.Appearance)
+			}
+
+			gen.appearanceKind[id] = entry.Appearance
+		}
+
+		itemRarity, _ := parseRarity(entry.Rarity)
+		equip := parseEquipLocation(entry.EquipLocation)
+		rangedDamage, _ := ParseDiceRoll(entry.RangedDamage)
+
+		// Thrown items go a short way unless they say otherwise
+		missileRange := entry.Range
+		if missileRange == 0 && entry.Fires == "" && entry.RangedDamage != "" {
+			missileRange = throwRange
+		}
+
+		// Parse the effects in name order, so every item has them in the same order
+		effects := make([]effect, 0, len(entry.Effects))
+		for _, effectName := range slices.Sorted(maps.Keys(entry.Effects)) {
+			e, _ := newEffect(effectName, entry.Effects[effectName])
+			effects = append(effects, *e)
+		}
+
 		gen.genFunctions[id] = func() *Item {
 			i := &Item{
 				entityBase: entityBase{
 					id:         id,
-					instanceID: core.RandId(6),
+					instanceID: core.RandId(gen.rng.Rand, 6),
 					desc:       entry.Description,
 					name:       entry.Name,
 					graphicId:  entry.Graphic,
 					colour:     entry.Colour,
 				},
-				usable:      entry.Usable,
-				weight:      entry.Weight,
-				onUseScript: entry.OnUseScript,
-				equipLocation: func() equipLocation {
-					switch entry.EquipLocation {
-					case "weapon":
-						return equipLocationWeapon
-					case "missile":
-						return equipLocationMissile
-					case "body":
-						return equipLocationBody
-					case "shield":
-						return equipLocationShield
-					case "head":
-						return equipLocationHead
-					case "feet":
-						return equipLocationFeet
-					case "hands":
-						return equipLocationHands
-					case "ring":
-						return equipLocationFinger
-					case "neck":
-						return equipLocationNeck
-					default:
-						return EquipLocationNone
-					}
-				}(),
+				usable:        entry.Usable,
+				weight:        entry.Weight,
+				stackable:     entry.Stackable,
+				quantity:      1,
+				onUseScript:   entry.OnUseScript,
+				equipLocation: equip,
+				twoHanded:     entry.TwoHanded,
+				rarity:        itemRarity,
+				effects:       slices.Clone(effects),
+				missileRange:  missileRange,
+				fires:         entry.Fires,
+				ammo:          entry.Ammo,
+				rangedDamage:  rangedDamage,
+				spell:         entry.Spell,
+				charges:       entry.Charges,
+				learnSpell:    entry.LearnSpell,
+				ident:         gen.ident,
 			}
 
-			// Loop over the effects, parse them and add them to the item
-			for effectName, effectValue := range entry.Effects {
-				effect := newEffect(effectName, effectValue)
-				if effect != nil {
-					i.effects = append(i.effects, *effect)
-				}
+			// Unidentified items are the colour of their appearance, even once they're known
+			if look, ok := gen.ident.looks[id]; ok {
+				i.colour = look.Colour
 			}
 
 			return i
 		}
 
 		gen.keys = append(gen.keys, id)
+		gen.rarity[id] = itemRarity
+		gen.minDepth[id] = entry.MinDepth
+		gen.maxDepth[id] = entry.MaxDepth
+		if quantity, ok := ParseDiceRoll(entry.Quantity); ok {
+			gen.quantity[id] = quantity
+		}
 	}
 
 	// Sort the keys as the map iteration order above is random
 	slices.Sort(gen.keys)
 
+	// Every unidentified item needs a different appearance
+	used := make(map[string]int)
+	for _, kind := range gen.appearanceKind {
+		used[kind]++
+	}
+
+	for kind, looks := range itemsFile.Appearances {
+		if used[kind] > len(looks) {
+			return nil, fmt.Errorf("appearance '%s' has %d looks for %d items", kind, len(looks), used[kind])
+		}
+	}
+
+	for name, entry := range itemsFile.LootTables {
+		for _, id := range entry.Items {
+			if _, ok := gen.genFunctions[id]; !ok {
+				return nil, fmt.Errorf("loot table '%s' has unknown item '%s'", name, id)
+			}
+		}
+
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("loot table '%s' %s", name, problems[0])
+		}
+
+		table := lootTable{items: entry.Items, chance: 100}
+		if len(table.items) == 0 {
+			table.items = gen.keys
+		}
+
+		if entry.Chance != nil {
+			table.chance = *entry.Chance
+		}
+
+		gen.lootTables[name] = table
+	}
+
 	return &gen, nil
 }
 
@@ -240,23 +450,62 @@ func (gen itemGenerator) createItem(id string) *Item {
 	return itemFunc()
 }
 
-func (gen itemGenerator) createRandomItem(rarity rarity) *Item {
-	if len(gen.genFunctions) == 0 {
+// Create a random item from a loot table for a level at the given depth
+// Items outside their depth range are skipped, and rarer items are picked less often
+// Items with a quantity roll, e.g. arrows, are found as a stack
+func (gen itemGenerator) createRandomItem(table string, depth int) *Item {
+	loot, ok := gen.lootTables[table]
+	if !ok {
+		return nil
+	}
+
+	// Tables list items in a fixed order, so the same seed always picks the same items
+	candidates := make([]string, 0, len(loot.items))
+	totalWeight := 0
+	for _, id := range loot.items {
+		if depth < gen.minDepth[id] || (gen.maxDepth[id] > 0 && depth > gen.maxDepth[id]) {
+			continue
+		}
+
+		candidates = append(candidates, id)
+		totalWeight += gen.rarity[id].weight()
+	}
+
+	if totalWeight == 0 {
 		return nil
 	}
 
-	//	generatorAtRarity := make([]func() *Item, 0)
+	pick := gen.rng.IntN(totalWeight)
+	for _, id := range candidates {
+		pick -= gen.rarity[id].weight()
+		if pick < 0 {
+			i := gen.createItem(id)
+			if quantity, ok := gen.quantity[id]; ok {
+				i.quantity = max(quantity.Roll(gen.rng), 1)
+			}
+
+			return i
+		}
+	}
+
+	return nil
+}
 
-	// Get a random item, we have to use the keys slice as the map iteration order is random
-	id := gen.keys[rng.IntN(len(gen.keys))]
-	return gen.createItem(id)
+// Maybe create an item from a loot table, depending on the table's drop chance
+func (gen itemGenerator) dropLoot(table string, depth int) *Item {
+	loot, ok := gen.lootTables[table]
+	if !ok || !gen.rng.Chance(loot.chance) {
+		return nil
+	}
+
+	return gen.createRandomItem(table, depth)
 }
 
 // ====== Item Equip Location =================================================================================================
 
 type equipLocation int
 
-// TODO: Player can equip more than one ring!
+// Where an item is equipped, the player's slots for each are in equipment.go
 const (
 	EquipLocationNone equipLocation = iota
 	equipLocationWeapon
@@ -270,6 +519,31 @@ const (
 	equipLocationNeck
 )
 
+func parseEquipLocation(name string) equipLocation {
+	switch name {
+	case "weapon":
+		return equipLocationWeapon
+	case "missile":
+		return equipLocationMissile
+	case "body":
+		return equipLocationBody
+	case "shield":
+		return equipLocationShield
+	case "head":
+		return equipLocationHead
+	case "feet":
+		return equipLocationFeet
+	case "hands":
+		return equipLocationHands
+	case "ring":
+		return equipLocationFinger
+	case "neck":
+		return equipLocationNeck
+	default:
+		return EquipLocationNone
+	}
+}
+
 func (el equipLocation) String() string {
 	switch el {
 	case EquipLocationNone:
@@ -311,6 +585,36 @@ const (
 	rarityLegendary
 )
 
+// Relative chance of an item of each rarity being picked
+var rarityWeights = map[rarity]int{
+	rarityVeryCommon: 100,
+	rarityCommon:     60,
+	rarityUncommon:   30,
+	rarityRare:       12,
+	rarityVeryRare:   5,
+	rarityEpic:       2,
+	rarityLegendary:  1,
+}
+
+func (r rarity) weight() int {
+	return rarityWeights[r]
+}
+
+// Items without a rarity in the datafile are very common
+func parseRarity(name string) (rarity, bool) {
+	if name == "" {
+		return rarityVeryCommon, true
+	}
+
+	for r := rarityVeryCommon; r <= rarityLegendary; r++ {
+		if r.String() == name {
+			return r, true
+		}
+	}
+
+	return rarityVeryCommon, false
+}
+
 func (r rarity) String() string {
 	switch r {
 	case rarityVeryCommon:
@@ -0,0 +1,133 @@
+package engine
+
+// ============================================================================
+// Tests for item generation and loot tables
+// ============================================================================
+
+import (
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Pick lots of items from a loot table and count how many of each we get
+func countLoot(t *testing.T, gen *itemGenerator, table string, depth, picks int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+
+	for i := 0; i < picks; i++ {
+		item := gen.createRandomItem(table, depth)
+		if item == nil {
+			t.Fatalf("loot table '%s' gave no item at depth %d", table, depth)
+		}
+
+		counts[item.id]++
+	}
+
+	return counts
+}
+
+func TestItemDistribution(t *testing.T) {
+	gen, err := newItemGenerator("../assets/datafiles/items.yaml", newRNG(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const picks = 50000
+	const depth = 1
+
+	counts := countLoot(t, gen, "level", depth, picks)
+
+	gen.rng = newRNG(42)
+	if again := countLoot(t, gen, "level", depth, picks); !maps.Equal(counts, again) {
+		t.Errorf("same seed gave a different distribution\n%v\n%v", counts, again)
+	}
+
+	totalWeight := 0
+	for _, id := range gen.keys {
+		if depth >= gen.minDepth[id] {
+			totalWeight += gen.rarity[id].weight()
+		}
+	}
+
+	for _, id := range gen.keys {
+		if depth < gen.minDepth[id] {
+			if counts[id] > 0 {
+				t.Errorf("item '%s' has minDepth %d but was found %d times at depth %d", id, gen.minDepth[id], counts[id], depth)
+			}
+
+			continue
+		}
+
+		// Each item should turn up in proportion to its rarity weight, give or take 10%
+		expected := float64(picks*gen.rarity[id].weight()) / float64(totalWeight)
+		if math.Abs(float64(counts[id])-expected) > expected*0.1 {
+			t.Errorf("item '%s' (%s) found %d times, expected about %.0f", id, gen.rarity[id], counts[id], expected)
+		}
+	}
+}
+
+func TestItemDepthAndTables(t *testing.T) {
+	gen, err := newItemGenerator("../assets/datafiles/items.yaml", newRNG(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deep := countLoot(t, gen, "level", 20, 5000)
+	for id := range deep {
+		if gen.maxDepth[id] > 0 && gen.maxDepth[id] < 20 {
+			t.Errorf("item '%s' has maxDepth %d but was found at depth 20", id, gen.maxDepth[id])
+		}
+	}
+
+	if deep["scroll_of_cthon"] == 0 {
+		t.Errorf("very rare scroll should turn up at depth 20")
+	}
+
+	for id := range countLoot(t, gen, "supplies", 1, 500) {
+		if id != "potion_healing" && id != "potion_sickness" && id != "meat" {
+			t.Errorf("item '%s' is not in the supplies loot table", id)
+		}
+	}
+
+	if gen.createRandomItem("no_such_table", 1) != nil {
+		t.Errorf("unknown loot table should not give an item")
+	}
+
+	if r := gen.createItem("scroll_of_cthon").Rarity(); r != rarityVeryRare {
+		t.Errorf("scroll rarity should be very rare, got %s", r)
+	}
+}
+
+func TestLootTableChance(t *testing.T) {
+	data, err := os.ReadFile("../assets/datafiles/items.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A chance of zero has to be kept, only a missing chance means always
+	tables := strings.Replace(string(data), "lootTables:\n", "lootTables:\n  never:\n    chance: 0\n\n", 1)
+	file := filepath.Join(t.TempDir(), "items.yaml")
+	if err := os.WriteFile(file, []byte(tables), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := newItemGenerator(file, newRNG(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gen.lootTables["never"].chance != 0 || gen.lootTables["supplies"].chance != 100 {
+		t.Errorf("chance should be 0 when set to 0 and 100 when not given, got %d and %d",
+			gen.lootTables["never"].chance, gen.lootTables["supplies"].chance)
+	}
+
+	for range 100 {
+		if gen.dropLoot("never", 1) != nil {
+			t.Fatalf("a loot table with no chance shouldn't drop anything")
+		}
+	}
+}
@@ -2,6 +2,8 @@ package engine
 
 import (
 	"roguelike/core"
+	"roguelike/core/pathfind"
+	"slices"
 )
 
 // ============================================================================
@@ -16,10 +18,15 @@ type tileType int
 const (
 	tileTypeFloor tileType = iota
 	tileTypeWall
+	tileTypeStairsUp
+	tileTypeStairsDown
 )
 
 const maxTileItems = 10
 
+// Palette colour used to draw stairs
+const stairsColour = "13"
+
 // Tile is a single square on the map which can contain stuff
 type tile struct {
 	pos
@@ -30,8 +37,9 @@ type tile struct {
 	blocksLOS  bool
 	items      entityList
 	creature   *creature
-	furniture  *furniture //nolint
-	IsWallHint bool       // Used really only for debugging/dumping the map
+	furniture  *furniture
+	trap       *trap
+	IsWallHint bool // Used really only for debugging/dumping the map
 }
 
 // Appearance is a struct that holds the appearance of a tile
@@ -75,6 +83,16 @@ func (t *tile) makeWall() {
 	t.IsWallHint = true
 }
 
+// Turn a floor tile into stairs, going up or down
+func (t *tile) makeStairs(stairsType tileType) {
+	if t == nil {
+		return
+	}
+
+	t.makeFloor()
+	t.tileType = stairsType
+}
+
 // Add an item to the tile's item stack, up to the max allowed
 func (t *tile) addItem(i *Item) bool {
 	if t == nil {
@@ -114,6 +132,40 @@ func (t *tile) placeCreature(c *creature) bool {
 	return true
 }
 
+// Place a piece of furniture on this tile
+func (t *tile) placeFurniture(f *furniture) bool {
+	if t == nil || f == nil || t.furniture != nil {
+		return false
+	}
+
+	t.furniture = f
+	f.setPos(&t.pos)
+
+	return true
+}
+
+// Hide a trap on this tile
+func (t *tile) placeTrap(tr *trap) bool {
+	if t == nil || tr == nil || t.trap != nil {
+		return false
+	}
+
+	t.trap = tr
+	tr.setPos(&t.pos)
+
+	return true
+}
+
+// Remove a creature from this tile
+func (t *tile) removeCreature() {
+	if t == nil || t.creature == nil {
+		return
+	}
+
+	t.creature.currentTile = nil
+	t.creature = nil
+}
+
 // Appearance returns the appearance of the tile as a string
 // Used by the renderer and UI to display this tile
 func (t *tile) Appearance() *Appearance {
@@ -142,6 +194,28 @@ func (t *tile) Appearance() *Appearance {
 		return &appear
 	}
 
+	if t.furniture != nil {
+		appear := t.furniture.Appearance()
+		appear.InFOV = t.inFOV
+
+		return &appear
+	}
+
+	// Traps can only be seen once they've been found
+	if t.trap != nil && !t.trap.hidden {
+		appear := t.trap.Appearance()
+		appear.InFOV = t.inFOV
+
+		return &appear
+	}
+
+	switch t.tileType {
+	case tileTypeStairsUp:
+		return &Appearance{Graphic: "stairs_up", Colour: stairsColour, InFOV: t.inFOV}
+	case tileTypeStairsDown:
+		return &Appearance{Graphic: "stairs_down", Colour: stairsColour, InFOV: t.inFOV}
+	}
+
 	return &Appearance{Graphic: "floor", InFOV: t.inFOV}
 }
 
@@ -155,6 +229,10 @@ func (t *tile) BlocksMove() bool {
 		return t.creature.BlocksMove()
 	}
 
+	if t.furniture != nil && t.furniture.BlocksMove() {
+		return true
+	}
+
 	// Items don't block move, but lets check them anyway
 	for _, i := range t.items {
 		if i.BlocksMove() {
@@ -175,6 +253,10 @@ func (t *tile) BlocksLOS() bool {
 		return t.creature.BlocksLOS()
 	}
 
+	if t.furniture != nil && t.furniture.BlocksLOS() {
+		return true
+	}
+
 	for _, e := range t.items {
 		if e.BlocksLOS() {
 			return true
@@ -194,6 +276,15 @@ func (t *tile) ListItems() []Item {
 	return itemsOut
 }
 
+// Returns the furniture on this tile, if any
+func (t *tile) Furniture() *furniture {
+	if t == nil {
+		return nil
+	}
+
+	return t.furniture
+}
+
 // Returns the creature on this tile, if any
 func (t *tile) Creature() *creature {
 	if t == nil {
@@ -210,10 +301,13 @@ type GameMap struct {
 	size
 	tiles [][]tile // 2D array of tiles, this holds the world
 
-	fovList          []*tile // List of all tiles in the FOV
-	depth            int     // Depth of the map
-	description      string  // Some human-readable description of the map
+	fovList          []*tile     // List of all tiles in the FOV
+	creatures        []*creature // All creatures on the map, in the order they take turns
+	depth            int         // Depth of the map
+	description      string      // Some human-readable description of the map
 	generationMethod string
+	upStairs         pos // Where the player arrives from above, or starts on the first level
+	downStairs       pos // Where the player arrives from below
 }
 
 // NewMap creates a new map with the given width and height
@@ -252,6 +346,15 @@ func (m *GameMap) TileAt(pos core.Pos) *tile {
 	return &m.tiles[pos.X][pos.Y]
 }
 
+// Nothing on the map is in view any more
+func (m *GameMap) clearFOV() {
+	for _, t := range m.fovList {
+		t.inFOV = false
+	}
+
+	m.fovList = nil
+}
+
 // The size (width and height) of the map
 func (m *GameMap) Size() core.Size {
 	return m.size
@@ -307,11 +410,66 @@ func (m *GameMap) revealMap() {
 	}
 }
 
-// Find a random floor tile on the map
-func (m *GameMap) randomFloorTile(noItems bool) *tile {
-	for {
-		x := rng.IntN(m.Width)
-		y := rng.IntN(m.Height)
+// Place a creature on the map at the given tile, and add it to the list of creatures
+func (m *GameMap) placeCreature(c *creature, t *tile) bool {
+	if t == nil || !t.placeCreature(c) {
+		return false
+	}
+
+	m.creatures = append(m.creatures, c)
+	return true
+}
+
+// Remove a creature from the map completely, e.g. when it is killed
+func (m *GameMap) removeCreature(c *creature) {
+	c.currentTile.removeCreature()
+	m.creatures = slices.DeleteFunc(m.creatures, func(other *creature) bool {
+		return other == c
+	})
+}
+
+// Grid for pathfinding over the map, tiles that block movement can't be entered
+// The creature doing the moving (if any) doesn't block itself
+func (m *GameMap) pathGrid(mover *creature) *pathfind.Grid {
+	return pathfind.NewGrid(m.size, func(p core.Pos) bool {
+		t := m.TileAt(p)
+		if t != nil && mover != nil && t.creature == mover {
+			return false
+		}
+
+		// Creatures can open doors, so closed doors don't get in their way
+		if f := t.Furniture(); mover != nil && f != nil && f.isDoor() && t.creature == nil {
+			return false
+		}
+
+		return t.BlocksMove()
+	})
+}
+
+// Find the nearest tile that nothing is standing on, starting with the given tile
+func (m *GameMap) freeTileNear(r *gameRNG, t *tile) *tile {
+	if t.creature == nil {
+		return t
+	}
+
+	for _, p := range t.NeighboursAll() {
+		if n := m.TileAt(p); n != nil && !n.BlocksMove() {
+			return n
+		}
+	}
+
+	if f := m.randomFloorTile(r, false); f != nil {
+		return f
+	}
+
+	return t
+}
+
+// Find a random floor tile on the map, nil if none turns up after plenty of tries
+func (m *GameMap) randomFloorTile(r *gameRNG, noItems bool) *tile {
+	for tries := 0; tries < m.Width*m.Height*floorTileTries; tries++ {
+		x := r.IntN(m.Width)
+		y := r.IntN(m.Height)
 		t := m.Tile(x, y)
 
 		if t.tileType == tileTypeFloor {
@@ -326,9 +484,10 @@ func (m *GameMap) randomFloorTile(noItems bool) *tile {
 			return t
 		}
 	}
+
+	return nil
 }
 
-//nolint:unused
 func (m *GameMap) enumerateFunc(f func(tile *tile, x, y int)) {
 	for x := 0; x < m.Width; x++ {
 		for y := 0; y < m.Height; y++ {
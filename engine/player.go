@@ -12,37 +12,57 @@ import (
 	fn "github.com/s0rg/fantasyname"
 )
 
-const PLAYER_MAX_ITEMS = 10
+const (
+	PLAYER_MAX_ITEMS  = 10 // Backpack slots, a stack of items only takes up one
+	PLAYER_MAX_WEIGHT = 60 // Weight the player can carry before being slowed down, see inventory.go
+)
 
 type Player struct {
 	pos
 	currentTile *tile
 	name        string
+	class       *Class
+
+	hp int
 
-	// Health
-	hp    int
-	maxHP int
+	// Stats with everything equipped, worked out from the base stats, see equipment.go
+	playerStats
+	base playerStats // From the player's class, levels & perks
 
-	// Base attributes, can be modified by equipment
-	defence      int      //nolint Defence protects against damage
-	attackDamage int      //nolint Base damage added to weapon damage
-	attackChance int      //nolint Base chance to hit
-	attackRoll   DiceRoll //nolint Dice roll for attack damage
+	exp          int
+	level        int
+	pendingPerks int      // Perks earned by levelling up but not picked yet
+	perks        []string // Perks picked so far, see progression.go
 
-	exp   int
-	level int
+	// Magic, see spells.go
+	mana    int
+	maxMana int
+	spells  []string // Ids of the spells learned, in the order they were learned
+
+	// Set when the player dies, along with what killed them
+	dead     bool
+	killedBy string
 
 	backpack entityList
 
 	// Inspired by Angband https://angband.readthedocs.io/en/latest/command.html#inventory-commands
-	equipSlots map[equipLocation]*Item
+	equipSlots [equipSlotCount]*Item
 
 	fovDistance int
+
+	// Timed effects such as poison, see status.go
+	statuses statusList
+
+	// Used by the turn scheduler
+	energy int
+	speed  int
 }
 
-func NewPlayer(tile *tile, items ...Item) *Player {
+// Create a player of a class, with their starting kit. Equipment is put on
+// if nothing is in that slot already, everything else goes in the backpack
+func NewPlayer(r *gameRNG, tile *tile, class *Class, items ...*Item) *Player {
 	name := "Jimmy No Name"
-	gen, err := fn.Compile("sd", fn.Collapse(true), fn.RandFn(rng.IntN))
+	gen, err := fn.Compile("sd", fn.Collapse(true), fn.RandFn(r.IntN))
 	if err == nil {
 		name = gen.String()
 		// Capitalize the first letter
@@ -50,22 +70,37 @@ func NewPlayer(tile *tile, items ...Item) *Player {
 	}
 
 	p := &Player{
-		pos:          tile.pos,
-		currentTile:  tile,
-		name:         name,
-		hp:           10,
-		maxHP:        50,
-		exp:          0,
-		level:        1,
-		backpack:     NewEntityList(),
-		equipSlots:   make(map[equipLocation]*Item),
-		fovDistance:  6,
-		defence:      0,
-		attackDamage: 1,
-		attackChance: 75,
-		attackRoll:   DiceRoll{0, 0, 0},
+		pos:         tile.pos,
+		currentTile: tile,
+		name:        name,
+		hp:          50,
+		base:        playerStats{maxHP: 50, attackDamage: 1, attackChance: 75},
+		exp:         0,
+		level:       1,
+		backpack:    NewEntityList(),
+		fovDistance: 6,
+		energy:      energyPerTurn,
+		speed:       speedNormal,
+	}
+
+	if class != nil {
+		class.applyStats(p)
 	}
 
+	p.playerStats = p.base
+
+	for _, item := range items {
+		if item == nil || p.addToBackpack(item) != item {
+			continue
+		}
+
+		if slot := p.freeSlotFor(item); item.IsEquipment() && p.equipSlots[slot] == nil && p.canEquip(item, slot) {
+			p.EquipItem(item, slot)
+		}
+	}
+
+	// Starting kit can add max HP, the player still starts out unhurt
+	p.hp = p.maxHP
 	return p
 }
 
@@ -81,6 +116,23 @@ func (p *Player) MaxHP() int {
 	return p.maxHP
 }
 
+func (p *Player) Mana() int {
+	return p.mana
+}
+
+func (p *Player) MaxMana() int {
+	return p.maxMana
+}
+
+func (p *Player) IsDead() bool {
+	return p.dead
+}
+
+// What killed the player, blank if they are still alive
+func (p *Player) KilledBy() string {
+	return p.killedBy
+}
+
 func (p *Player) Exp() int {
 	return p.exp
 }
@@ -89,6 +141,33 @@ func (p *Player) Level() int {
 	return p.level
 }
 
+// ClassName is the name of the class the player picked, e.g. Warrior
+func (p *Player) ClassName() string {
+	if p.class == nil {
+		return ""
+	}
+
+	return p.class.name
+}
+
+// PendingPerks is how many perks the player has earned and not picked yet
+func (p *Player) PendingPerks() int {
+	return p.pendingPerks
+}
+
+func (p *Player) Speed() int {
+	return p.speed
+}
+
+// Statuses the player has, with the turns left, e.g. poisoned(5)
+func (p *Player) Statuses() []string {
+	return p.statuses.descriptions()
+}
+
+func (p *Player) activeStatuses() statusList {
+	return p.statuses
+}
+
 func (p *Player) Pos() core.Pos {
 	return p.pos
 }
@@ -101,9 +180,7 @@ func (p *Player) Inventory() []*Item {
 	}
 
 	// add all equipped items
-	for _, i := range p.equipSlots {
-		items = append(items, i)
-	}
+	items = append(items, p.equipped()...)
 
 	// sort the items by id
 	sort.Slice(items, func(i, j int) bool {
@@ -117,18 +194,17 @@ func (p *Player) BackpackSize() int {
 	return PLAYER_MAX_ITEMS
 }
 
-func (p *Player) DropItem(item *Item) bool {
-	if !p.backpack.Contains(item) {
-		return false
+// Drop some of an item, or all of it if count is zero, returning what was dropped
+func (p *Player) DropItem(r *gameRNG, item *Item, count int) *Item {
+	if !p.backpack.Contains(item) || p.currentTile.items.Count() >= maxTileItems {
+		return nil
 	}
 
-	if placedOK := p.currentTile.addItem(item); placedOK {
-		p.backpack.Remove(item)
-		item.dropped = true
-		return true
-	}
+	dropped := p.takeFromBackpack(r, item, count)
+	p.currentTile.addItem(dropped)
+	dropped.dropped = true
 
-	return false
+	return dropped
 }
 
 func (p *Player) Tile() *tile {
@@ -140,68 +216,28 @@ func (p *Player) moveToTile(t *tile) {
 	p.currentTile = t
 }
 
-// Pickup an item from the ground, returning true if the item was picked up
-func (p *Player) PickupItem(item *Item) bool {
-	if len(p.backpack) >= PLAYER_MAX_ITEMS {
-		return false
+// Pickup an item from the ground, it's added to a stack of the same kind if there is one
+// Returns the item or stack in the backpack, nil if it couldn't be picked up
+func (p *Player) PickupItem(item *Item) *Item {
+	carried := p.addToBackpack(item)
+	if carried == nil {
+		return nil
 	}
 
-	p.backpack.Add(item)
 	p.currentTile.items.Remove(item)
 	item.pos = nil
 
-	return true
+	return carried
 }
 
 func (p *Player) SetHP(hp int) {
 	p.hp = hp
 }
 
+// Set the player's max HP before any equipment is added
 func (p *Player) SetMaxHP(hp int) {
-	p.maxHP = hp
-}
-
-func (p *Player) EquipItem(item *Item, slot equipLocation) {
-	if !p.backpack.Contains(item) {
-		return
-	}
-
-	// Unequip any item in the slot
-	if i, ok := p.equipSlots[slot]; ok {
-		p.backpack.Add(i)
-	}
-
-	p.backpack.Remove(item)
-	p.equipSlots[slot] = item
-	item.equipped = true
-
-	// Apply any effects of the item
-	for _, e := range item.effects {
-		e.apply(p)
-	}
-}
-
-func (p *Player) UnequipItem(slot equipLocation) {
-	if i, ok := p.equipSlots[slot]; ok {
-		p.backpack.Add(i)
-		delete(p.equipSlots, slot)
-		i.equipped = false
-
-		// Remove any effects of the item
-		for _, e := range i.effects {
-			e.remove(p)
-		}
-	}
-}
-
-func (p *Player) IsEquipped(item *Item) bool {
-	for _, i := range p.equipSlots {
-		if i == item {
-			return true
-		}
-	}
-
-	return false
+	p.base.maxHP = hp
+	p.recomputeStats()
 }
 
 func (p *Player) StatDefence() int {
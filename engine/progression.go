@@ -0,0 +1,199 @@
+package engine
+
+// ============================================================================
+// Experience levels, the player levels up as they gain experience from kills
+// The XP curve, stats gained and perks are all loaded from progression.yaml
+// Each level up also lets the player pick a perk, which they can do any time
+// ============================================================================
+
+import (
+	"fmt"
+	"maps"
+	"roguelike/core"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+type progression struct {
+	XPCurve       []int `yaml:"xpCurve"`       // Total experience needed for each level, from level 2
+	HPPerLevel    int   `yaml:"hpPerLevel"`    // Max HP gained every level
+	ToHitPerLevel int   `yaml:"toHitPerLevel"` // Chance to hit gained every level
+	ManaPerLevel  int   `yaml:"manaPerLevel"`  // Max mana gained every level, only by classes which have some
+
+	perks    map[string]*Perk
+	perkKeys []string
+}
+
+// Perk is a bonus the player can pick when they level up
+type Perk struct {
+	id          string
+	name        string
+	description string
+	effects     []effect
+}
+
+type yamlPerk struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Effects     map[string]string `yaml:"effects"`
+}
+
+type yamlProgressionFile struct {
+	Progression progression         `yaml:"progression"`
+	Perks       map[string]yamlPerk `yaml:"perks"`
+}
+
+func loadProgression(dataFile string) (*progression, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlProgressionFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := file.Progression
+	if problems := prog.check(); len(problems) > 0 {
+		return nil, fmt.Errorf("progression %s", problems[0])
+	}
+
+	prog.perks = make(map[string]*Perk)
+	for id, entry := range file.Perks {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("perk '%s' %s", id, problems[0])
+		}
+
+		perk := &Perk{id: id, name: entry.Name, description: entry.Description}
+		for _, effectName := range slices.Sorted(maps.Keys(entry.Effects)) {
+			e, _ := newEffect(effectName, entry.Effects[effectName])
+			perk.effects = append(perk.effects, *e)
+		}
+
+		prog.perks[id] = perk
+	}
+
+	prog.perkKeys = slices.Sorted(maps.Keys(prog.perks))
+	return &prog, nil
+}
+
+// Total experience needed to reach a level
+func (prog *progression) expForLevel(level int) int {
+	curve := prog.XPCurve
+	if level <= 1 {
+		return 0
+	}
+
+	if level-2 < len(curve) {
+		return curve[level-2]
+	}
+
+	// Past the end of the curve, keep adding the last step
+	last := curve[len(curve)-1]
+	step := last
+	if len(curve) > 1 {
+		step = last - curve[len(curve)-2]
+	}
+
+	return last + step*(level-1-len(curve))
+}
+
+// Perks the player hasn't picked yet, in name order
+func (prog *progression) availablePerks(p *Player) []*Perk {
+	perks := make([]*Perk, 0, len(prog.perkKeys))
+	for _, id := range prog.perkKeys {
+		if !slices.Contains(p.perks, id) {
+			perks = append(perks, prog.perks[id])
+		}
+	}
+
+	return perks
+}
+
+func (pk Perk) ID() string {
+	return pk.id
+}
+
+func (pk Perk) Name() string {
+	return pk.name
+}
+
+func (pk Perk) Description() string {
+	return pk.description
+}
+
+func (pk Perk) DescribeEffects() string {
+	desc := ""
+	for _, e := range pk.effects {
+		desc += e.description() + " "
+	}
+
+	return desc
+}
+
+// ===== Levelling Up =========================================================
+
+// Give the player experience, levelling them up as many times as it takes
+func (g *Game) gainExp(amount int) {
+	p := g.player
+	prog := g.progression
+	p.exp += amount
+
+	for p.exp >= prog.expForLevel(p.level+1) {
+		p.level++
+		p.improveBase(func(s *playerStats) {
+			s.maxHP += prog.HPPerLevel
+			s.attackChance += prog.ToHitPerLevel
+		})
+
+		if p.maxMana > 0 {
+			p.maxMana += prog.ManaPerLevel
+			p.mana += prog.ManaPerLevel
+		}
+
+		msg := fmt.Sprintf("You have reached level %d!", p.level)
+		if len(prog.availablePerks(p)) > p.pendingPerks {
+			p.pendingPerks++
+			msg += " You can choose a new perk"
+		}
+
+		events.new(EventLevelUp, nil, msg)
+	}
+}
+
+// Change the player's base stats for a level up or perk
+// Gaining max HP this way heals the player by the same amount
+func (p *Player) improveBase(change func(s *playerStats)) {
+	before := p.maxHP
+	change(&p.base)
+	p.recomputeStats()
+
+	if p.maxHP > before {
+		p.hp += p.maxHP - before
+	}
+}
+
+// ExpForNextLevel is the total experience the player needs to reach the next level
+func (g *Game) ExpForNextLevel() int {
+	return g.progression.expForLevel(g.player.level + 1)
+}
+
+// AvailablePerks lists the perks the player could pick, whether or not they have one to pick
+func (g *Game) AvailablePerks() []*Perk {
+	return g.progression.availablePerks(g.player)
+}
+
+// ChosenPerks lists the perks the player has picked, in the order they were picked
+func (g *Game) ChosenPerks() []*Perk {
+	perks := make([]*Perk, 0, len(g.player.perks))
+	for _, id := range g.player.perks {
+		if perk, ok := g.progression.perks[id]; ok {
+			perks = append(perks, perk)
+		}
+	}
+
+	return perks
+}
@@ -0,0 +1,105 @@
+package engine
+
+// ============================================================================
+// Tests for experience levels & perks
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+func TestExpCurve(t *testing.T) {
+	prog := &progression{XPCurve: []int{10, 30, 60}}
+
+	tests := []struct{ level, want int }{
+		{1, 0}, {2, 10}, {4, 60}, {5, 90}, {7, 150},
+	}
+
+	for _, test := range tests {
+		if got := prog.expForLevel(test.level); got != test.want {
+			t.Errorf("expForLevel(%d) = %d; want %d", test.level, got, test.want)
+		}
+	}
+}
+
+func TestLevelUp(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+	prog := g.progression
+
+	levelUps := 0
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventLevelUp {
+			levelUps++
+		}
+	})
+
+	p.hp = 10
+	hp, hit := p.maxHP, p.attackChance
+	g.gainExp(prog.expForLevel(3))
+
+	if p.level != 3 || levelUps != 2 || p.pendingPerks != 2 {
+		t.Fatalf("player should be level 3 with 2 perks to pick, level %d, events %d, perks %d", p.level, levelUps, p.pendingPerks)
+	}
+
+	if p.maxHP != hp+2*prog.HPPerLevel || p.attackChance != hit+2*prog.ToHitPerLevel {
+		t.Errorf("level ups should add to max HP & hit chance, got %d HP %d%%", p.maxHP, p.attackChance)
+	}
+
+	if p.hp != 10+2*prog.HPPerLevel {
+		t.Errorf("level ups should heal by the max HP gained, got %d HP", p.hp)
+	}
+
+	tough := prog.perks["tough"]
+	hp = p.maxHP
+	if !g.ProcessAction(NewPerkAction(tough)).Success || p.maxHP != hp+15 || p.pendingPerks != 1 {
+		t.Errorf("picking tough should add 15 max HP, got %d", p.maxHP)
+	}
+
+	if g.ProcessAction(NewPerkAction(tough)).Success {
+		t.Errorf("a perk can't be picked twice")
+	}
+
+	if len(g.AvailablePerks()) != len(prog.perks)-1 || g.ChosenPerks()[0] != tough {
+		t.Errorf("tough should have moved from the available to the chosen perks")
+	}
+}
+
+func TestExpForAnyKill(t *testing.T) {
+	g := testGame(t)
+	p := g.player
+	p.hp, p.maxHP = 100, 100
+
+	// A rat by a keg the player bashes is killed by the blast
+	rat := targetRat(g, 6, 4)
+	rat.hp = 1
+	g.gameMap.Tile(5, 4).placeFurniture(g.furnitureGen.createFurniture("keg"))
+	g.ProcessAction(NewBashAction(core.DirEast))
+
+	if rat.currentTile != nil || p.exp != rat.xp {
+		t.Fatalf("the player should get the experience for a rat killed by a keg they blew up, got %d", p.exp)
+	}
+
+	// Poison the player gave kills a rat later on, poison from elsewhere doesn't count
+	poisoned := targetRat(g, 4, 2)
+	poisoned.hp = 1
+	g.playerActing = true
+	_ = g.addCreatureStatus(poisoned, "poison", 5, 1)
+	g.playerActing = false
+
+	other := targetRat(g, 2, 2)
+	other.hp = 1
+	_ = g.addCreatureStatus(other, "poison", 5, 1)
+
+	g.ProcessAction(NewWaitAction())
+	g.ProcessAction(NewWaitAction())
+
+	if poisoned.currentTile != nil || other.currentTile != nil {
+		t.Fatalf("both rats should have died from poison")
+	}
+
+	if p.exp != rat.xp+poisoned.xp {
+		t.Errorf("only the rat the player poisoned should give experience, got %d", p.exp)
+	}
+}
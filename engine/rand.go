@@ -11,34 +11,35 @@ import (
 // ============================================================================
 // Random number generator
 // This is a seedable RNG for random but repeatable generation of levels etc
+// Every game has its own, and everything random in the engine must use it,
+// never math/rand directly, otherwise the same seed will not replay the same game
 // ============================================================================
 
 type gameRNG struct {
 	*rand.Rand
+	src *rand.PCG // Kept so the state can be saved and restored
 }
 
-// Global singleton RNG for the game, for reasons of determinism
-var rng gameRNG
-
-func init() {
-	s := rand.NewPCG(0, 0)
-	rng = gameRNG{rand.New(s)}
+func newRNG(seed uint64) *gameRNG {
+	s := rand.NewPCG(seed, seed)
+	return &gameRNG{rand.New(s), s}
 }
 
-func seedRNG(seed uint64) {
-	s := rand.NewPCG(seed, seed)
-	rng = gameRNG{rand.New(s)}
+// Current state of the RNG, restoring it later continues the same sequence
+func (r *gameRNG) state() ([]byte, error) {
+	return r.src.MarshalBinary()
 }
 
-func randString(strings ...string) string {
-	return strings[rng.IntN(len(strings))]
+func (r *gameRNG) restore(state []byte) error {
+	return r.src.UnmarshalBinary(state)
 }
 
-func GetRNG() gameRNG {
-	return rng
+// Pick one of the strings at random
+func (r *gameRNG) pick(strings ...string) string {
+	return strings[r.IntN(len(strings))]
 }
 
-func (r gameRNG) Chance(percentage int) bool {
+func (r *gameRNG) Chance(percentage int) bool {
 	return r.IntN(100) < percentage
 }
 
@@ -83,15 +84,21 @@ func ParseDiceRoll(dice string) (DiceRoll, bool) {
 	return DiceRoll{num, sides, modifier}, true
 }
 
-func (d DiceRoll) Roll() int {
+// Roll the dice using a game's RNG
+func (d DiceRoll) Roll(r *gameRNG) int {
 	total := 0
 	for i := 0; i < d.num; i++ {
-		total += rand.IntN(d.sides) + 1
+		total += r.IntN(d.sides) + 1
 	}
 
 	return total + d.modifier
 }
 
+// Average of the roll, for comparing one roll against another
+func (d DiceRoll) average() float64 {
+	return float64(d.num*(d.sides+1))/2 + float64(d.modifier)
+}
+
 func (d DiceRoll) String() string {
 	if d.modifier == 0 && d.num == 0 && d.sides == 0 {
 		return "0"
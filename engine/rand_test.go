@@ -5,6 +5,7 @@ package engine
 // ============================================================================
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -46,12 +47,52 @@ func TestDiceRoll_Roll(t *testing.T) {
 		{DiceRoll{0, 0, 0}, 0, 0},
 	}
 
+	r := newRNG(1)
 	for _, test := range tests {
 		for i := 0; i < 100; i++ {
-			result := test.dice.Roll()
+			result := test.dice.Roll(r)
 			if result < test.min || result > test.max {
 				t.Errorf("DiceRoll(%v).Roll() = %d; want between %d and %d", test.dice, result, test.min, test.max)
 			}
 		}
 	}
 }
+
+// Play a game recording every event, including using an item with a script
+func recordGame(seed uint64) []string {
+	stream := make([]string, 0)
+	g := NewGame("../assets/datafiles", seed, "", func(e GameEvent) {
+		id := ""
+		if e.Entity() != nil {
+			id = e.Entity().InstanceID()
+		}
+
+		stream = append(stream, string(e.Type())+"|"+id+"|"+e.Text())
+	})
+
+	meat := g.itemGen.createItem("meat")
+	g.player.backpack.Add(meat)
+	g.ProcessAction(NewUseAction(meat))
+
+	playActions(g, 300)
+	return stream
+}
+
+func TestSameSeedSameGame(t *testing.T) {
+	first := recordGame(1234)
+	second := recordGame(1234)
+
+	if len(first) != len(second) {
+		t.Fatalf("same seed gave %d events then %d events", len(first), len(second))
+	}
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("same seed gave different event %d: %q != %q", i, first[i], second[i])
+		}
+	}
+
+	if other := recordGame(4321); slices.Equal(first, other) {
+		t.Errorf("different seeds gave identical games")
+	}
+}
@@ -0,0 +1,160 @@
+package engine
+
+// ============================================================================
+// Ranged combat, firing ammunition from a launcher or throwing items
+// Missiles fly in a straight line and stop at the first wall or creature
+// ============================================================================
+
+import (
+	"cmp"
+	"fmt"
+	"roguelike/core"
+	"slices"
+)
+
+const (
+	throwRange       = 5  // How far items can be thrown, unless they have their own range
+	rangedHitPenalty = 3  // Chance to hit lost for every tile a missile travels
+	ammoBreakChance  = 50 // Chance ammunition breaks when it hits something
+)
+
+// A missile in flight, it uses the player's aim but does its own damage
+type missile struct {
+	item  *Item
+	stats combatStats
+}
+
+func (m missile) Name() string {
+	return m.item.Name()
+}
+
+func (m missile) HP() int {
+	return 0
+}
+
+func (m missile) combatStats() combatStats {
+	return m.stats
+}
+
+func (m missile) applyDamage(amount int) {}
+
+// ProjectilePath is the positions a missile fired by the player at a position passes through
+// It stops before any wall or closed door, or on the first creature, the last position is where it lands
+func (g *Game) ProjectilePath(to core.Pos, maxRange int) []core.Pos {
+	line := g.player.pos.RayCastTo(to, float64(maxRange))
+	path := make([]core.Pos, 0, len(line))
+
+	for _, p := range line[1:] {
+		t := g.gameMap.TileAt(p)
+		if t == nil {
+			break
+		}
+
+		if t.creature != nil {
+			path = append(path, p)
+			break
+		}
+
+		if t.BlocksMove() {
+			break
+		}
+
+		path = append(path, p)
+	}
+
+	return path
+}
+
+// VisibleHostiles lists the hostile creatures the player can see, nearest first
+func (g *Game) VisibleHostiles() []*creature {
+	hostiles := make([]*creature, 0)
+	for _, c := range g.gameMap.creatures {
+		if c.hostile && c.inPlayerFOV() {
+			hostiles = append(hostiles, c)
+		}
+	}
+
+	slices.SortStableFunc(hostiles, func(a, b *creature) int {
+		return cmp.Compare(a.pos.Distance(g.player.pos), b.pos.Distance(g.player.pos))
+	})
+
+	return hostiles
+}
+
+// Check the player can see a creature and it's in range, with a message if not
+func (g *Game) canTarget(target *creature, maxRange int) bool {
+	if target == nil || !target.inPlayerFOV() {
+		events.new(EventMiscMessage, nil, "You can't see anything to aim at")
+		return false
+	}
+
+	if target.pos.Distance(g.player.pos) > float64(maxRange) {
+		events.new(EventMiscMessage, target, fmt.Sprintf("The %s is out of range", target.Name()))
+		return false
+	}
+
+	return true
+}
+
+// Send a missile at a creature, whatever is first in its path gets hit
+// The item lands where the missile stops, unless it breaks
+func (g *Game) launchMissile(item *Item, damage DiceRoll, target *creature, maxRange int, breakChance int) {
+	landing := g.missileLanding(target, maxRange)
+	if c := landing.creature; c != nil {
+		m := missile{item, combatStats{
+			hitChance: g.player.attackChance - rangedHitPenalty*int(c.pos.Distance(g.player.pos)),
+			damage:    damage,
+		}}
+
+		res := resolveAttack(g.rng, g.combatRules, m, c)
+		g.playerAttacked(c, res)
+
+		if res.Hit && g.rng.Chance(breakChance) {
+			return
+		}
+	} else {
+		events.new(EventMiscMessage, item, fmt.Sprintf("The %s falls short", item.Name()))
+	}
+
+	// Anything landing on a full tile is lost
+	item.dropped = false
+	landing.addItem(item)
+}
+
+// Where something fired at a creature stops, the player's own tile if it can't get anywhere
+func (g *Game) missileLanding(target *creature, maxRange int) *tile {
+	if path := g.ProjectilePath(*target.pos, maxRange); len(path) > 0 {
+		return g.gameMap.TileAt(path[len(path)-1])
+	}
+
+	return g.player.currentTile
+}
+
+// The launcher the player has equipped, if any
+func (p *Player) launcher() *Item {
+	if i := p.equipSlots[slotMissile]; i != nil && i.IsLauncher() {
+		return i
+	}
+
+	return nil
+}
+
+// FireRange is how far the player's launcher can shoot, zero if they don't have one
+func (p *Player) FireRange() int {
+	if l := p.launcher(); l != nil {
+		return l.missileRange
+	}
+
+	return 0
+}
+
+// The first ammunition in the backpack a launcher can fire
+func (p *Player) ammoFor(launcher *Item) *Item {
+	for _, e := range p.backpack {
+		if i := e.(*Item); i.ammo == launcher.fires {
+			return i
+		}
+	}
+
+	return nil
+}
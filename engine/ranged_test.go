@@ -0,0 +1,134 @@
+package engine
+
+// ============================================================================
+// Tests for firing & throwing missiles
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+// Give the player a bow and some arrows, with a perfect aim
+func archerTestGame(t *testing.T, arrows int) *Game {
+	g := testGame(t)
+	p := g.player
+	p.attackChance = 1000
+
+	bow := g.itemGen.createItem("bow")
+	p.backpack.Add(bow)
+	p.EquipItem(bow, slotMissile)
+
+	for i := 0; i < arrows; i++ {
+		p.backpack.Add(g.itemGen.createItem("arrow"))
+	}
+
+	return g
+}
+
+func TestProjectilePath(t *testing.T) {
+	g := testGame(t)
+	g.gameMap.Tile(4, 1).makeWall()
+
+	path := g.ProjectilePath(core.Pos{X: 4, Y: 0}, 8)
+	if len(path) != 2 || path[1] != (core.Pos{X: 4, Y: 2}) {
+		t.Errorf("missile should stop before the wall, got path %v", path)
+	}
+
+	targetRat(g, 6, 4)
+	path = g.ProjectilePath(core.Pos{X: 8, Y: 4}, 8)
+	if len(path) != 2 || path[1] != (core.Pos{X: 6, Y: 4}) {
+		t.Errorf("missile should stop at the first creature, got path %v", path)
+	}
+
+	path = g.ProjectilePath(core.Pos{X: 4, Y: 8}, 2)
+	if len(path) != 2 {
+		t.Errorf("missile should stop at its range, got path %v", path)
+	}
+}
+
+func TestFireAction(t *testing.T) {
+	g := testGame(t)
+	rat := targetRat(g, 4, 1)
+
+	if res := g.ProcessAction(NewFireAction(rat)); res.Success {
+		t.Errorf("firing without a launcher should fail")
+	}
+
+	g = archerTestGame(t, 3)
+	rat = targetRat(g, 4, 1)
+
+	for i := 0; i < 3; i++ {
+		if res := g.ProcessAction(NewFireAction(rat)); !res.Success {
+			t.Fatalf("shot %d should have been fired", i+1)
+		}
+	}
+
+	if res := g.ProcessAction(NewFireAction(rat)); res.Success {
+		t.Errorf("firing with no arrows left should fail")
+	}
+
+	if g.player.ammoFor(g.player.launcher()) != nil || rat.hp == rat.maxHP {
+		t.Errorf("every arrow should have been fired and hurt the rat, rat hp %d", rat.hp)
+	}
+
+	// Arrows which don't break land under the rat
+	for _, i := range rat.currentTile.items.AllItems() {
+		if i.id != "arrow" || i.dropped {
+			t.Errorf("only arrows should land by the rat, and be picked up again on walking over, got %s", i.id)
+		}
+	}
+
+	g = archerTestGame(t, 1)
+	rat = targetRat(g, 4, 0)
+	g.player.equipSlots[slotMissile].missileRange = 3
+	if res := g.ProcessAction(NewFireAction(rat)); res.Success || g.player.ammoFor(g.player.launcher()) == nil {
+		t.Errorf("firing at a creature out of range should fail")
+	}
+}
+
+func TestThrowAction(t *testing.T) {
+	g := testGame(t)
+	g.player.attackChance = 1000
+	near := targetRat(g, 4, 2)
+	far := targetRat(g, 4, 0)
+
+	knife := g.itemGen.createItem("throwing_knife")
+	g.player.backpack.Add(knife)
+
+	// The near rat is in the way of the far one
+	if res := g.ProcessAction(NewThrowAction(knife, far)); !res.Success {
+		t.Fatalf("throwing the knife should succeed")
+	}
+
+	if g.player.backpack.Contains(knife) || !near.currentTile.items.Contains(knife) || far.hp != far.maxHP {
+		t.Errorf("the knife should hit the rat in the way and land under it")
+	}
+
+	potion := g.itemGen.createItem("potion_healing")
+	g.player.backpack.Add(potion)
+	if res := g.ProcessAction(NewThrowAction(potion, near)); res.Success {
+		t.Errorf("items without ranged damage can't be thrown")
+	}
+}
+
+func TestRangedReplaySteps(t *testing.T) {
+	g := archerTestGame(t, 1)
+	rat := targetRat(g, 4, 1)
+	g.recording = nil
+
+	g.ProcessAction(NewFireAction(rat))
+	step := g.recording[0]
+	if step.Action != "fire" || step.Target != rat.instanceID {
+		t.Fatalf("fire should be recorded with its target, got %+v", step)
+	}
+
+	a, err := g.actionFromStep(step)
+	if fire, ok := a.(*FireAction); err != nil || !ok || fire.target != rat {
+		t.Errorf("fire step should play back against the same rat, got %v %v", a, err)
+	}
+
+	if hostiles := g.VisibleHostiles(); len(hostiles) != 1 || hostiles[0] != rat {
+		t.Errorf("the rat should be the only visible hostile, got %v", hostiles)
+	}
+}
@@ -0,0 +1,356 @@
+package engine
+
+// ============================================================================
+// Replays are a seed plus every action the player took, in order
+// As the game is deterministic, playing the actions back against a new game
+// with the same seed recreates the game exactly. Used for bug reports and
+// to check in CI that saved replays still end in the same state
+// ============================================================================
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"roguelike/core"
+)
+
+// Bump this when the replay format changes, or when a change to the rules or
+// generation means the same actions no longer play out the same way
+// Older replays will refuse to load, rather than failing part way through
+const replayVersion = 5
+
+// Replay is a recorded game, it can be saved as JSON
+type Replay struct {
+	Version int          `json:"version"`
+	Seed    uint64       `json:"seed"`
+	Class   string       `json:"class"`
+	Steps   []ReplayStep `json:"steps"`
+	Hash    string       `json:"hash,omitempty"` // State hash at the end of the replay
+	Note    string       `json:"note,omitempty"` // Why the replay was last recorded
+}
+
+// ReplayStep is a single recorded player action, items and creatures are
+// referred to by their instance ID
+type ReplayStep struct {
+	Action string `json:"action"`
+	Dir    string `json:"dir,omitempty"`
+	Item   string `json:"item,omitempty"`
+	Target string `json:"target,omitempty"` // Creature instance ID, or the perk picked
+	Spell  string `json:"spell,omitempty"`
+	Count  int    `json:"count,omitempty"` // How many of a stack were dropped, zero for all of it
+	Slot   string `json:"slot,omitempty"`  // Equipment slot picked, blank if the game picked it
+}
+
+var directionNames = map[core.Direction]string{
+	core.DirNorth: "north",
+	core.DirSouth: "south",
+	core.DirEast:  "east",
+	core.DirWest:  "west",
+}
+
+var furnitureActionNames = map[furnitureActionKind]string{
+	furnitureInteract: "interact",
+	furnitureOpen:     "open",
+	furnitureClose:    "close",
+	furnitureBash:     "bash",
+}
+
+// LoadReplay parses a replay saved as JSON
+func LoadReplay(dataFileDir string, data []byte) (*Replay, error) {
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	if r.Version != replayVersion {
+		return nil, fmt.Errorf("replay is version %d, only version %d is supported", r.Version, replayVersion)
+	}
+
+	// The game is started with the replay's class, so it has to exist
+	cl, err := loadClasses(dataFileDir + "/classes.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := cl.get(r.Class); err != nil {
+		return nil, fmt.Errorf("replay has %s", err)
+	}
+
+	return &r, nil
+}
+
+// Replay returns everything the player has done so far, ready to be saved
+func (g *Game) Replay() *Replay {
+	return &Replay{
+		Version: replayVersion,
+		Seed:    g.seed,
+		Class:   g.player.class.id,
+		Steps:   append([]ReplayStep(nil), g.recording...),
+		Hash:    g.StateHash(),
+	}
+}
+
+// StateHash is a hash of the whole game state, two games with the same hash are identical
+func (g *Game) StateHash() string {
+	data, err := g.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record a player action, called before it's carried out so items & targets still exist
+func (g *Game) recordAction(a Action) {
+	step := ReplayStep{}
+
+	switch a := a.(type) {
+	case *MoveAction:
+		step.Action = "move"
+		step.Dir = directionNames[a.direction]
+	case *AttackAction:
+		step.Action = "attack"
+		if c, ok := a.target.(*creature); ok {
+			step.Target = c.instanceID
+		}
+	case *WaitAction:
+		step.Action = "wait"
+	case *SearchAction:
+		step.Action = "search"
+	case *StairsAction:
+		step.Action = "ascend"
+		if a.down {
+			step.Action = "descend"
+		}
+	case *FurnitureAction:
+		step.Action = furnitureActionNames[a.kind]
+		step.Dir = directionNames[a.direction]
+	case *PickupAction:
+		step.Action = "pickup"
+		step.Item = a.item.instanceID
+	case *DropAction:
+		step.Action = "drop"
+		step.Item = a.item.instanceID
+		step.Count = a.count
+	case *UseAction:
+		step.Action = "use"
+		step.Item = a.item.instanceID
+	case *EquipAction:
+		step.Action = "equip"
+		step.Item = a.item.instanceID
+		if a.slot != slotAny {
+			step.Slot = a.slot.String()
+		}
+	case *PerkAction:
+		step.Action = "perk"
+		step.Target = a.perk
+	case *FireAction:
+		step.Action = "fire"
+		if a.target != nil {
+			step.Target = a.target.instanceID
+		}
+	case *ThrowAction:
+		step.Action = "throw"
+		step.Item = a.item.instanceID
+		if a.target != nil {
+			step.Target = a.target.instanceID
+		}
+	case *CastAction:
+		step.Action = "cast"
+		step.Spell = a.spell
+		if a.target != nil {
+			step.Target = a.target.instanceID
+		}
+	case *ZapAction:
+		step.Action = "zap"
+		step.Item = a.item.instanceID
+		if a.target != nil {
+			step.Target = a.target.instanceID
+		}
+	default:
+		return
+	}
+
+	g.recording = append(g.recording, step)
+}
+
+// Turn a recorded step back into an action against the current game state
+func (g *Game) actionFromStep(step ReplayStep) (Action, error) {
+	for kind, name := range furnitureActionNames {
+		if name == step.Action {
+			dir, err := stepDirection(step)
+			return &FurnitureAction{dir, kind}, err
+		}
+	}
+
+	switch step.Action {
+	case "move":
+		dir, err := stepDirection(step)
+		return NewMoveAction(dir), err
+	case "attack":
+		c, err := g.stepTarget(step)
+		return NewAttackAction(c), err
+	case "fire":
+		c, err := g.stepTarget(step)
+		return NewFireAction(c), err
+	case "cast":
+		c, err := g.stepTarget(step)
+		return &CastAction{step.Spell, c}, err
+	case "wait":
+		return NewWaitAction(), nil
+	case "search":
+		return NewSearchAction(), nil
+	case "descend":
+		return NewDescendAction(), nil
+	case "ascend":
+		return NewAscendAction(), nil
+	case "perk":
+		return &PerkAction{step.Target}, nil
+	}
+
+	item := g.findItem(step.Item)
+	if item == nil {
+		return nil, fmt.Errorf("replay step '%s' uses missing item '%s'", step.Action, step.Item)
+	}
+
+	switch step.Action {
+	case "pickup":
+		return NewPickupAction(item), nil
+	case "drop":
+		return NewDropCountAction(item, step.Count), nil
+	case "use":
+		return NewUseAction(item), nil
+	case "equip":
+		if step.Slot == "" {
+			return NewEquipAction(item), nil
+		}
+
+		slot, ok := parseEquipSlot(step.Slot)
+		if !ok {
+			return nil, fmt.Errorf("replay step '%s' has unknown slot '%s'", step.Action, step.Slot)
+		}
+
+		return NewEquipSlotAction(item, slot), nil
+	case "throw":
+		c, err := g.stepTarget(step)
+		return NewThrowAction(item, c), err
+	case "zap":
+		c, err := g.stepTarget(step)
+		return NewZapAction(item, c), err
+	}
+
+	return nil, fmt.Errorf("replay has unknown action '%s'", step.Action)
+}
+
+func stepDirection(step ReplayStep) (core.Direction, error) {
+	for dir, name := range directionNames {
+		if name == step.Dir {
+			return dir, nil
+		}
+	}
+
+	return core.DirNorth, fmt.Errorf("replay has unknown direction '%s'", step.Dir)
+}
+
+// Find the creature a step was aimed at, on the current level
+// Steps with no target, e.g. a spell cast on the player, give nil
+func (g *Game) stepTarget(step ReplayStep) (*creature, error) {
+	if step.Target == "" {
+		return nil, nil
+	}
+
+	for _, c := range g.gameMap.creatures {
+		if c.instanceID == step.Target {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("replay step '%s' targets missing creature '%s'", step.Action, step.Target)
+}
+
+// Find an item the player could act on, carried, equipped or on the ground
+func (g *Game) findItem(instanceID string) *Item {
+	for _, i := range g.player.Inventory() {
+		if i.instanceID == instanceID {
+			return i
+		}
+	}
+
+	for _, i := range g.player.currentTile.items.AllItems() {
+		if i.instanceID == instanceID {
+			return i
+		}
+	}
+
+	return nil
+}
+
+// ===== Playback =============================================================
+
+// Replayer plays back a replay one step at a time
+type Replayer struct {
+	game   *Game
+	replay *Replay
+	next   int
+}
+
+// NewReplayer starts a new game from the replay's seed, ready to play it back
+func NewReplayer(dataFileDir string, r *Replay, listeners ...EventListener) *Replayer {
+	return &Replayer{
+		game:   NewGame(dataFileDir, r.Seed, r.Class, listeners...),
+		replay: r,
+	}
+}
+
+func (rp *Replayer) Game() *Game {
+	return rp.game
+}
+
+// Done is true when every step has been played
+func (rp *Replayer) Done() bool {
+	return rp.next >= len(rp.replay.Steps)
+}
+
+// Progress returns the number of steps played and the total
+func (rp *Replayer) Progress() (int, int) {
+	return rp.next, len(rp.replay.Steps)
+}
+
+// Step plays the next action in the replay
+func (rp *Replayer) Step() error {
+	if rp.Done() {
+		return nil
+	}
+
+	action, err := rp.game.actionFromStep(rp.replay.Steps[rp.next])
+	if err != nil {
+		return fmt.Errorf("step %d: %w", rp.next+1, err)
+	}
+
+	rp.next++
+	rp.game.ProcessAction(action)
+
+	if err := rp.game.Err(); err != nil {
+		return fmt.Errorf("step %d: %w", rp.next, err)
+	}
+
+	return nil
+}
+
+// Run plays the whole replay without any display, then checks the final
+// state matches the hash stored in the replay, if it has one
+func (rp *Replayer) Run() error {
+	for !rp.Done() {
+		if err := rp.Step(); err != nil {
+			return err
+		}
+	}
+
+	if rp.replay.Hash != "" && rp.replay.Hash != rp.game.StateHash() {
+		return fmt.Errorf("replay finished with state %s, expected %s", rp.game.StateHash(), rp.replay.Hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package engine
+
+// ============================================================================
+// Tests for recording and playing back replays
+// ============================================================================
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run `go test ./engine -run TestReplayFiles -update -note "why"` after a deliberate change to
+// the game rules or generation, to store the new final state hashes of the replays in testdata
+// replayVersion has to be bumped first, so replays recorded before the change are refused
+var (
+	updateReplays = flag.Bool("update", false, "update the hashes stored in testdata replays")
+	updateNote    = flag.String("note", "", "why the testdata replays are being updated")
+)
+
+func TestReplayMatchesGame(t *testing.T) {
+	g := NewGame("../assets/datafiles", 99, "")
+
+	sword := g.itemGen.createItem("sword")
+	g.player.backpack.Add(sword)
+	g.ProcessAction(NewEquipAction(sword))
+	playActions(g, 300)
+
+	data, err := json.Marshal(g.Replay())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadReplay("../assets/datafiles", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sword was added outside of any action, so the replay can't know about it
+	if err := NewReplayer("../assets/datafiles", r).Run(); err == nil {
+		t.Errorf("replay should fail when the game was changed outside of actions")
+	}
+
+	g = NewGame("../assets/datafiles", 99, "")
+	playActions(g, 300)
+	r = g.Replay()
+
+	rp := NewReplayer("../assets/datafiles", r)
+	if err := rp.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if done, total := rp.Progress(); done != total || total == 0 {
+		t.Errorf("replay played %d of %d steps", done, total)
+	}
+}
+
+func TestReplayUnknownClass(t *testing.T) {
+	data, err := json.Marshal(Replay{Version: replayVersion, Seed: 1, Class: "jester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadReplay("../assets/datafiles", data); err == nil {
+		t.Errorf("a replay with a class that doesn't exist shouldn't load")
+	}
+}
+
+func TestReplayStopsWhenGameFails(t *testing.T) {
+	r := &Replay{Version: replayVersion, Seed: 1, Steps: []ReplayStep{{Action: "wait"}, {Action: "wait"}}}
+	rp := NewReplayer("../assets/datafiles", r)
+
+	// As if a script had hung and been stopped
+	_, _ = runScript(rp.Game(), "test", "while(true) {}", nil)
+
+	if err := rp.Step(); err == nil || !strings.Contains(err.Error(), "step 1") {
+		t.Errorf("the replay should stop at the step where the game failed, got %v", err)
+	}
+}
+
+func TestReplayFiles(t *testing.T) {
+	files, _ := filepath.Glob("testdata/replays/*.json")
+	if len(files) == 0 {
+		t.Fatal("no replays found in testdata/replays")
+	}
+
+	if *updateReplays && *updateNote == "" {
+		t.Fatal("say why the replays are being updated with -note")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var recorded Replay
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+
+		if *updateReplays {
+			if recorded.Version == replayVersion {
+				t.Fatalf("%s: bump replayVersion before recording the replays again", file)
+			}
+
+			// Load it as if it was recorded with this version, to play back the same steps
+			recorded.Version = replayVersion
+			data, _ = json.Marshal(recorded)
+		}
+
+		r, err := LoadReplay("../assets/datafiles", data)
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+
+		if *updateReplays {
+			r.Hash = ""
+			r.Note = *updateNote
+		}
+
+		rp := NewReplayer("../assets/datafiles", r)
+		if err := rp.Run(); err != nil {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+
+		if *updateReplays {
+			r.Hash = rp.Game().StateHash()
+			data, _ = json.MarshalIndent(r, "", "  ")
+			if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
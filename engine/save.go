@@ -0,0 +1,564 @@
+package engine
+
+// ============================================================================
+// Saving and loading a running game
+// The whole game is written out as JSON, including the RNG state, so a loaded
+// game carries on exactly as it would have. Items and creatures are saved as
+// their datafile id plus whatever has changed since they were created
+// ============================================================================
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Bump this when the save format changes, older saves will refuse to load
+const saveVersion = 12
+
+type saveGame struct {
+	Version int         `json:"version"`
+	Seed    uint64      `json:"seed"`
+	Ticks   int         `json:"ticks"`
+	RNG     []byte      `json:"rng"`
+	Depth   int         `json:"depth"`
+	Player  savePlayer  `json:"player"`
+	Levels  []saveLevel `json:"levels"`
+
+	Identified []string `json:"identified,omitempty"` // Ids of the items the player knows
+
+	// Needed so a loaded game can still be saved as a replay
+	Steps []ReplayStep `json:"steps"`
+}
+
+type savePlayer struct {
+	Name         string              `json:"name"`
+	Class        string              `json:"class"`
+	Pos          pos                 `json:"pos"`
+	HP           int                 `json:"hp"`
+	MaxHP        int                 `json:"maxHP"` // Base stats, before any equipment
+	Defence      int                 `json:"defence"`
+	AttackDamage int                 `json:"attackDamage"`
+	AttackChance int                 `json:"attackChance"`
+	AttackRoll   string              `json:"attackRoll"`
+	Exp          int                 `json:"exp"`
+	Level        int                 `json:"level"`
+	PendingPerks int                 `json:"pendingPerks,omitempty"`
+	Perks        []string            `json:"perks,omitempty"`
+	Mana         int                 `json:"mana,omitempty"`
+	MaxMana      int                 `json:"maxMana,omitempty"`
+	Spells       []string            `json:"spells,omitempty"`
+	Dead         bool                `json:"dead"`
+	KilledBy     string              `json:"killedBy"`
+	Backpack     []saveItem          `json:"backpack"`
+	Equipped     map[string]saveItem `json:"equipped"`
+	FOVDistance  int                 `json:"fovDistance"`
+	Energy       int                 `json:"energy"`
+	Speed        int                 `json:"speed"`
+	Statuses     []saveStatus        `json:"statuses,omitempty"`
+}
+
+type saveLevel struct {
+	Width            int             `json:"width"`
+	Height           int             `json:"height"`
+	Depth            int             `json:"depth"`
+	Description      string          `json:"description"`
+	GenerationMethod string          `json:"generationMethod"`
+	UpStairs         pos             `json:"upStairs"`
+	DownStairs       pos             `json:"downStairs"`
+	Tiles            []string        `json:"tiles"` // One string per row, see tileChars
+	Seen             []string        `json:"seen"`  // One string per row, 1 if the tile has been seen
+	Items            []saveTileItem  `json:"items"`
+	Creatures        []saveCreature  `json:"creatures"` // In turn order
+	Furniture        []saveFurniture `json:"furniture"`
+	Traps            []saveTrap      `json:"traps"`
+}
+
+type saveTileItem struct {
+	Pos  pos      `json:"pos"`
+	Item saveItem `json:"item"`
+}
+
+type saveItem struct {
+	ID         string `json:"id"`
+	InstanceID string `json:"instanceID"`
+	Dropped    bool   `json:"dropped,omitempty"`
+	Charges    int    `json:"charges,omitempty"`  // Only for wands
+	Quantity   int    `json:"quantity,omitempty"` // How many are in the stack
+}
+
+type saveFurniture struct {
+	ID         string `json:"id"`
+	InstanceID string `json:"instanceID"`
+	Pos        pos    `json:"pos"`
+	Open       bool   `json:"open,omitempty"`
+	HP         int    `json:"hp"`
+	Used       bool   `json:"used,omitempty"`
+}
+
+type saveTrap struct {
+	ID         string `json:"id"`
+	InstanceID string `json:"instanceID"`
+	Pos        pos    `json:"pos"`
+	Hidden     bool   `json:"hidden"`
+}
+
+type saveCreature struct {
+	ID             string       `json:"id"`
+	InstanceID     string       `json:"instanceID"`
+	Pos            pos          `json:"pos"`
+	HP             int          `json:"hp"`
+	MaxHP          int          `json:"maxHP"`
+	Depth          int          `json:"depth"`
+	Energy         int          `json:"energy"`
+	LastSeenPlayer *pos         `json:"lastSeenPlayer,omitempty"`
+	Fleeing        bool         `json:"fleeing,omitempty"`
+	Statuses       []saveStatus `json:"statuses,omitempty"`
+}
+
+// Statuses are saved using their names
+type saveStatus struct {
+	Name     string `json:"name"`
+	Turns    int    `json:"turns"`
+	Strength int    `json:"strength"`
+	ByPlayer bool   `json:"byPlayer,omitempty"`
+}
+
+// Characters used to store each tile type
+var tileChars = map[tileType]byte{
+	tileTypeFloor:      '.',
+	tileTypeWall:       '#',
+	tileTypeStairsUp:   '<',
+	tileTypeStairsDown: '>',
+}
+
+// MarshalJSON saves the whole game, use LoadGame to load it back
+func (g *Game) MarshalJSON() ([]byte, error) {
+	rngState, err := g.rng.state()
+	if err != nil {
+		return nil, err
+	}
+
+	save := saveGame{
+		Version: saveVersion,
+		Seed:    g.seed,
+		Ticks:   g.ticks,
+		RNG:     rngState,
+		Depth:   g.gameMap.depth,
+		Player:  g.player.save(),
+
+		Identified: g.itemGen.ident.knownIDs(),
+
+		Steps: g.recording,
+	}
+
+	for _, m := range g.levels {
+		save.Levels = append(save.Levels, m.save())
+	}
+
+	return json.Marshal(save)
+}
+
+// LoadGame loads a game saved with MarshalJSON, the datafiles are needed
+// to recreate the items and creatures
+func LoadGame(dataFileDir string, data []byte, listeners ...EventListener) (*Game, error) {
+	var save saveGame
+	if err := json.Unmarshal(data, &save); err != nil {
+		return nil, err
+	}
+
+	if save.Version != saveVersion {
+		return nil, fmt.Errorf("save file is version %d, only version %d is supported", save.Version, saveVersion)
+	}
+
+	if save.Depth < 1 || save.Depth > len(save.Levels) {
+		return nil, fmt.Errorf("save file has no level at depth %d", save.Depth)
+	}
+
+	g := &Game{
+		seed:      save.Seed,
+		rng:       newRNG(save.Seed),
+		ticks:     save.Ticks,
+		recording: save.Steps,
+	}
+
+	events = eventManager{}
+	events.addEventListeners(listeners...)
+
+	var err error
+	g.combatRules, err = loadCombatRules(dataFileDir + "/combat.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadGenerators(g, dataFileDir); err != nil {
+		return nil, err
+	}
+
+	for _, id := range save.Identified {
+		g.itemGen.ident.known[id] = true
+	}
+
+	for _, sl := range save.Levels {
+		m, err := g.loadLevel(sl)
+		if err != nil {
+			return nil, err
+		}
+
+		g.levels = append(g.levels, m)
+	}
+
+	g.gameMap = g.levels[save.Depth-1]
+	g.player, err = g.loadPlayer(save.Player)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the RNG last, as creating the creatures above uses it
+	if err := g.rng.restore(save.RNG); err != nil {
+		return nil, err
+	}
+
+	events.new(EventMiscMessage, nil, "Welcome back "+g.player.Name())
+	events.new(EventMiscMessage, nil, fmt.Sprintf("You are on level %d of %s", g.gameMap.depth, g.gameMap.Description()))
+
+	g.updateFOV()
+	return g, nil
+}
+
+// ===== Saving ===============================================================
+
+func (p *Player) save() savePlayer {
+	sp := savePlayer{
+		Name:         p.name,
+		Class:        p.class.id,
+		Pos:          p.pos,
+		HP:           p.hp,
+		MaxHP:        p.base.maxHP,
+		Defence:      p.base.defence,
+		AttackDamage: p.base.attackDamage,
+		AttackChance: p.base.attackChance,
+		AttackRoll:   p.base.attackRoll.String(),
+		Exp:          p.exp,
+		Level:        p.level,
+		PendingPerks: p.pendingPerks,
+		Perks:        p.perks,
+		Mana:         p.mana,
+		MaxMana:      p.maxMana,
+		Spells:       p.spells,
+		Dead:         p.dead,
+		KilledBy:     p.killedBy,
+		Equipped:     make(map[string]saveItem),
+		FOVDistance:  p.fovDistance,
+		Energy:       p.energy,
+		Speed:        p.speed,
+		Statuses:     p.statuses.save(),
+	}
+
+	for _, i := range p.backpack.AllItems() {
+		sp.Backpack = append(sp.Backpack, i.save())
+	}
+
+	for slot, i := range p.equipSlots {
+		if i != nil {
+			sp.Equipped[equipSlot(slot).String()] = i.save()
+		}
+	}
+
+	return sp
+}
+
+func (i *Item) save() saveItem {
+	return saveItem{
+		ID:         i.id,
+		InstanceID: i.instanceID,
+		Dropped:    i.dropped,
+		Charges:    i.charges,
+		Quantity:   i.quantity,
+	}
+}
+
+func (sl statusList) save() []saveStatus {
+	var saved []saveStatus
+	for _, s := range sl {
+		saved = append(saved, saveStatus{statusInfos[s.kind].name, s.turns, s.strength, s.byPlayer})
+	}
+
+	return saved
+}
+
+func (m *GameMap) save() saveLevel {
+	sl := saveLevel{
+		Width:            m.Width,
+		Height:           m.Height,
+		Depth:            m.depth,
+		Description:      m.description,
+		GenerationMethod: m.generationMethod,
+		UpStairs:         m.upStairs,
+		DownStairs:       m.downStairs,
+	}
+
+	for y := 0; y < m.Height; y++ {
+		var tiles, seen strings.Builder
+		for x := 0; x < m.Width; x++ {
+			t := m.Tile(x, y)
+			tiles.WriteByte(tileChars[t.tileType])
+
+			if t.seen {
+				seen.WriteByte('1')
+			} else {
+				seen.WriteByte('0')
+			}
+
+			for _, i := range t.items.AllItems() {
+				sl.Items = append(sl.Items, saveTileItem{t.pos, i.save()})
+			}
+
+			if f := t.furniture; f != nil {
+				sl.Furniture = append(sl.Furniture, saveFurniture{
+					ID:         f.id,
+					InstanceID: f.instanceID,
+					Pos:        t.pos,
+					Open:       f.open,
+					HP:         f.hp,
+					Used:       f.used,
+				})
+			}
+
+			if tr := t.trap; tr != nil {
+				sl.Traps = append(sl.Traps, saveTrap{tr.id, tr.instanceID, t.pos, tr.hidden})
+			}
+		}
+
+		sl.Tiles = append(sl.Tiles, tiles.String())
+		sl.Seen = append(sl.Seen, seen.String())
+	}
+
+	for _, c := range m.creatures {
+		sc := saveCreature{
+			ID:         c.id,
+			InstanceID: c.instanceID,
+			Pos:        *c.pos,
+			HP:         c.hp,
+			MaxHP:      c.maxHP,
+			Depth:      c.depth,
+			Energy:     c.energy,
+			Fleeing:    c.fleeing,
+			Statuses:   c.statuses.save(),
+		}
+
+		if c.lastSeenPlayer != nil {
+			lastSeen := *c.lastSeenPlayer
+			sc.LastSeenPlayer = &lastSeen
+		}
+
+		sl.Creatures = append(sl.Creatures, sc)
+	}
+
+	return sl
+}
+
+// ===== Loading ==============================================================
+
+func (g *Game) loadItem(si saveItem) (*Item, error) {
+	i := g.itemGen.createItem(si.ID)
+	if i == nil {
+		return nil, fmt.Errorf("save file has unknown item '%s'", si.ID)
+	}
+
+	i.instanceID = si.InstanceID
+	i.dropped = si.Dropped
+	if i.IsWand() {
+		i.charges = si.Charges
+	}
+
+	if i.stackable && si.Quantity > 1 {
+		i.quantity = si.Quantity
+	}
+
+	return i, nil
+}
+
+func (g *Game) loadLevel(sl saveLevel) (*GameMap, error) {
+	if len(sl.Tiles) != sl.Height || len(sl.Seen) != sl.Height {
+		return nil, fmt.Errorf("level %d in save file has the wrong number of rows", sl.Depth)
+	}
+
+	m := NewMap(sl.Width, sl.Height, sl.Depth)
+	m.description = sl.Description
+	m.generationMethod = sl.GenerationMethod
+	m.upStairs = sl.UpStairs
+	m.downStairs = sl.DownStairs
+
+	for y := 0; y < m.Height; y++ {
+		if len(sl.Tiles[y]) != m.Width || len(sl.Seen[y]) != m.Width {
+			return nil, fmt.Errorf("level %d in save file has a bad row %d", sl.Depth, y)
+		}
+
+		for x := 0; x < m.Width; x++ {
+			t := m.Tile(x, y)
+			switch sl.Tiles[y][x] {
+			case tileChars[tileTypeFloor]:
+				t.makeFloor()
+			case tileChars[tileTypeStairsUp]:
+				t.makeStairs(tileTypeStairsUp)
+			case tileChars[tileTypeStairsDown]:
+				t.makeStairs(tileTypeStairsDown)
+			}
+
+			t.seen = sl.Seen[y][x] == '1'
+		}
+	}
+
+	for _, ti := range sl.Items {
+		i, err := g.loadItem(ti.Item)
+		if err != nil {
+			return nil, err
+		}
+
+		m.TileAt(ti.Pos).addItem(i)
+	}
+
+	for _, sf := range sl.Furniture {
+		f := g.furnitureGen.createFurniture(sf.ID)
+		if f == nil {
+			return nil, fmt.Errorf("save file has unknown furniture '%s'", sf.ID)
+		}
+
+		f.instanceID = sf.InstanceID
+		f.open = sf.Open
+		f.hp = sf.HP
+		f.used = sf.Used
+
+		if !m.TileAt(sf.Pos).placeFurniture(f) {
+			return nil, fmt.Errorf("furniture '%s' in save file can't be placed at %v", sf.ID, sf.Pos)
+		}
+	}
+
+	for _, st := range sl.Traps {
+		tr := g.trapGen.createTrap(st.ID)
+		if tr == nil {
+			return nil, fmt.Errorf("save file has unknown trap '%s'", st.ID)
+		}
+
+		tr.instanceID = st.InstanceID
+		tr.hidden = st.Hidden
+
+		if !m.TileAt(st.Pos).placeTrap(tr) {
+			return nil, fmt.Errorf("trap '%s' in save file can't be placed at %v", st.ID, st.Pos)
+		}
+	}
+
+	for _, sc := range sl.Creatures {
+		c := g.creatureGen.createCreature(sc.ID)
+		if c == nil {
+			return nil, fmt.Errorf("save file has unknown creature '%s'", sc.ID)
+		}
+
+		c.instanceID = sc.InstanceID
+		c.hp = sc.HP
+		c.maxHP = sc.MaxHP
+		c.depth = sc.Depth
+		c.energy = sc.Energy
+		c.lastSeenPlayer = sc.LastSeenPlayer
+		c.fleeing = sc.Fleeing
+
+		var err error
+		if c.statuses, err = loadStatuses(sc.Statuses); err != nil {
+			return nil, err
+		}
+
+		t := m.TileAt(sc.Pos)
+		if t == nil {
+			return nil, fmt.Errorf("creature '%s' in save file is off the map at %v", sc.ID, sc.Pos)
+		}
+
+		m.placeCreature(c, t)
+	}
+
+	return m, nil
+}
+
+func (g *Game) loadPlayer(sp savePlayer) (*Player, error) {
+	t := g.gameMap.TileAt(sp.Pos)
+	if t == nil {
+		return nil, fmt.Errorf("player in save file is off the map at %v", sp.Pos)
+	}
+
+	// Only the base stats are saved, what the equipment adds is worked out again once it's back on
+	p := &Player{
+		pos:          sp.Pos,
+		currentTile:  t,
+		name:         sp.Name,
+		base:         playerStats{maxHP: sp.MaxHP, defence: sp.Defence, attackDamage: sp.AttackDamage, attackChance: sp.AttackChance},
+		exp:          sp.Exp,
+		level:        sp.Level,
+		pendingPerks: sp.PendingPerks,
+		perks:        sp.Perks,
+		mana:         sp.Mana,
+		maxMana:      sp.MaxMana,
+		spells:       sp.Spells,
+		dead:         sp.Dead,
+		killedBy:     sp.KilledBy,
+		backpack:     NewEntityList(),
+		fovDistance:  sp.FOVDistance,
+		energy:       sp.Energy,
+		speed:        sp.Speed,
+	}
+
+	var err error
+	if p.class, err = g.classes.get(sp.Class); err != nil {
+		return nil, fmt.Errorf("save file has an %s", err)
+	}
+
+	if roll, ok := ParseDiceRoll(sp.AttackRoll); ok {
+		p.base.attackRoll = roll
+	}
+
+	if p.statuses, err = loadStatuses(sp.Statuses); err != nil {
+		return nil, err
+	}
+
+	for _, si := range sp.Backpack {
+		i, err := g.loadItem(si)
+		if err != nil {
+			return nil, err
+		}
+
+		p.backpack.Add(i)
+	}
+
+	for slotName, si := range sp.Equipped {
+		i, err := g.loadItem(si)
+		if err != nil {
+			return nil, err
+		}
+
+		slot, ok := parseEquipSlot(slotName)
+		if !ok {
+			return nil, fmt.Errorf("save file has unknown equipment slot '%s'", slotName)
+		}
+
+		i.equipped = true
+		p.equipSlots[slot] = i
+	}
+
+	p.recomputeStats()
+	p.hp = sp.HP
+
+	return p, nil
+}
+
+func loadStatuses(saved []saveStatus) (statusList, error) {
+	var sl statusList
+	for _, ss := range saved {
+		kind, err := parseStatusKind(ss.Name)
+		if err != nil {
+			return nil, fmt.Errorf("save file has an %s", err)
+		}
+
+		sl = append(sl, &status{kind, ss.Turns, ss.Strength, ss.ByPlayer})
+	}
+
+	return sl, nil
+}
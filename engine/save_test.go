@@ -0,0 +1,86 @@
+package engine
+
+// ============================================================================
+// Tests for saving and loading games
+// ============================================================================
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"roguelike/core"
+	"strings"
+	"testing"
+)
+
+// Play a fixed set of actions, stopping if the player dies
+func playActions(g *Game, count int) {
+	dirs := []core.Direction{core.DirNorth, core.DirEast, core.DirSouth, core.DirWest}
+
+	for i := 0; i < count && !g.IsOver(); i++ {
+		if i%5 == 4 {
+			g.ProcessAction(NewWaitAction())
+			continue
+		}
+
+		g.ProcessAction(NewMoveAction(dirs[(i/3)%len(dirs)]))
+	}
+}
+
+func TestSaveLoadContinues(t *testing.T) {
+	g := NewGame("../assets/datafiles", 77, "")
+	playActions(g, 40)
+
+	saved, err := g.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Carry on playing the original game, then do the same with a loaded copy
+	playActions(g, 200)
+	want, _ := g.MarshalJSON()
+
+	loaded, err := LoadGame("../assets/datafiles", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resaved, _ := loaded.MarshalJSON()
+	if !bytes.Equal(saved, resaved) {
+		t.Fatalf("saving a loaded game gave a different save")
+	}
+
+	playActions(loaded, 200)
+	got, _ := loaded.MarshalJSON()
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("loaded game did not continue the same as the original")
+	}
+}
+
+func TestLoadBadVersion(t *testing.T) {
+	_, err := LoadGame("../assets/datafiles", []byte(`{"version": 999}`))
+	if err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("LoadGame with a bad version gave error %v; want a version error", err)
+	}
+}
+
+func TestLoadMissingDataFile(t *testing.T) {
+	saved, err := NewGame("../assets/datafiles", 6, "").MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("../assets/datafiles")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "traps.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadGame(dir, saved); err == nil || !strings.Contains(err.Error(), "traps.yaml") {
+		t.Errorf("LoadGame with a missing datafile gave error %v; want an error naming the file", err)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+// ============================================================================
+// Energy based turn scheduler, decides who gets to act and when
+// Every actor (the player & creatures) gains energy each tick based on their
+// speed, and once they have built up enough they get to take a turn.
+// The clock only advances when the player acts, so game time is totally
+// independent of the frame rate of whatever frontend is running the game
+// ============================================================================
+
+import "slices"
+
+const (
+	energyPerTurn = 100 // Energy an actor needs to build up before it can act
+	speedNormal   = 10  // Standard speed, which is also the player's starting speed
+)
+
+// ProcessAction executes an action for the player, then runs the game clock
+// forward letting everything else act, until the player is ready to act again
+func (g *Game) ProcessAction(a Action) ActionResult {
+	if g.IsOver() {
+		return ActionResult{false, 0}
+	}
+
+	g.recordAction(a)
+	carrying := g.player.encumbrance()
+
+	g.playerActing = true
+	result := a.Execute(g)
+	g.playerActing = false
+
+	if !result.Success {
+		return result
+	}
+
+	g.checkEncumbrance(carrying)
+
+	g.player.energy -= result.EnergySpent
+	g.runUntilPlayerReady()
+
+	return result
+}
+
+// Turn returns the number of game turns that have passed, a turn being the
+// time it takes an actor at normal speed to build up enough energy to act
+func (g *Game) Turn() int {
+	return g.ticks * speedNormal / energyPerTurn
+}
+
+// Keep ticking the clock until the player has enough energy to act, or has died
+func (g *Game) runUntilPlayerReady() {
+	for g.player.energy < energyPerTurn && !g.IsOver() {
+		g.tick()
+	}
+}
+
+// A single tick of the game clock, all actors gain energy and act if they can
+func (g *Game) tick() {
+	g.ticks++
+
+	// Statuses & mana tick once every game turn, before anyone acts
+	if g.ticks%(energyPerTurn/speedNormal) == 0 {
+		g.tickStatuses()
+		if g.IsOver() {
+			return
+		}
+
+		g.regenMana()
+	}
+
+	g.player.energy += max(g.player.statuses.speed(g.player.speed), 1)
+
+	// Clone the list, as creatures can be removed from the map during a turn
+	for _, c := range slices.Clone(g.gameMap.creatures) {
+		c.energy += max(c.statuses.speed(c.speed), 1)
+
+		for c.energy >= energyPerTurn && c.currentTile != nil && !g.IsOver() {
+			c.energy -= max(c.takeTurn(g), 1)
+		}
+	}
+}
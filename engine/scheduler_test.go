@@ -0,0 +1,71 @@
+package engine
+
+// ============================================================================
+// Tests for the energy based turn scheduler
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+// Count the turns a creature takes while the player waits for some turns
+func creatureTurns(t *testing.T, speed, playerTurns int) int {
+	t.Helper()
+	g := testGame(t)
+
+	turns := 0
+	behaviours["count"] = func(g *Game, c *creature) Action {
+		turns++
+		return nil
+	}
+	defer delete(behaviours, "count")
+
+	c := targetRat(g, 1, 1)
+	c.ai = []string{"count"}
+	c.speed = speed
+	c.energy = 0
+
+	for range playerTurns {
+		if res := g.ProcessAction(NewWaitAction()); !res.Success {
+			t.Fatalf("waiting should always succeed")
+		}
+	}
+
+	return turns
+}
+
+func TestSchedulerSpeeds(t *testing.T) {
+	tests := []struct {
+		name        string
+		speed       int
+		playerTurns int
+		want        int
+	}{
+		{"twice as fast acts twice a turn", speedNormal * 2, 10, 20},
+		{"normal speed acts once a turn", speedNormal, 10, 10},
+		{"half speed acts every other turn", speedNormal / 2, 10, 5},
+		{"slowest acts once in ten turns", 1, 10, 1},
+	}
+
+	for _, test := range tests {
+		if got := creatureTurns(t, test.speed, test.playerTurns); got != test.want {
+			t.Errorf("%s: speed %d took %d turns in %d player turns; want %d", test.name, test.speed, got, test.playerTurns, test.want)
+		}
+	}
+}
+
+func TestFailedActionCostsNothing(t *testing.T) {
+	g := testGame(t)
+	g.player.moveToTile(g.gameMap.Tile(4, 0))
+	energy, ticks := g.player.energy, g.ticks
+
+	// Walking off the edge of the map fails
+	if res := g.ProcessAction(NewMoveAction(core.DirNorth)); res.Success || res.EnergySpent != 0 {
+		t.Fatalf("moving off the map should fail without spending energy")
+	}
+
+	if g.player.energy != energy || g.ticks != ticks {
+		t.Errorf("a failed action shouldn't use energy or let time pass, energy %d ticks %d", g.player.energy, g.ticks)
+	}
+}
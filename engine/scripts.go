@@ -0,0 +1,443 @@
+package engine
+
+// ============================================================================
+// Scriptlets in the datafiles are small bits of JS, run with goja
+// Scripts only see the API objects set up here, never the Go structs, so they
+// can't reach into the engine. The API is:
+//
+//	game     depth(), turn(), seed()
+//	map      width(), height(), isFloor(x, y), isFree(x, y), creatureAt(x, y), itemsAt(x, y)
+//	player   name(), hp(), maxHP(), setHP(hp), heal(amount), damage(amount), x(), y(), teleport(x, y),
+//	         addStatus(name, turns, strength), hasStatus(name), cureStatus(name), level(), exp(), addExp(amount),
+//	         mana(), maxMana(), restoreMana(amount), learnSpell(id)
+//	spawn    creature(id), creatureAt(id, x, y), creatureNear(id, x, y), item(id), itemAt(id, x, y)
+//	effects  teleport(), reveal(), alarm(radius), blast(x, y, radius, damage), identify()
+//	random   chance(percent), int(min, max), dice(roll), pick(array)
+//	message(text)
+//
+// The value of the last statement is the result, a string result is shown as a message
+// Hooks get extra globals, such as creature & furniture, see hooks.go, and
+// so do spells, which get the creature they hit, see spells.go
+// ============================================================================
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"roguelike/core"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Longest a script can run before it's stopped, so a bad loop can't hang the game
+// How far a script gets in that time depends on the machine, so a script that is
+// stopped ends the game, rather than letting it carry on in a way replays can't repeat
+const scriptTimeLimit = 250 * time.Millisecond
+
+// Scripts can set off other scripts, e.g. spawning a creature runs its onSpawn hook
+// This stops them setting each other off forever
+const maxScriptDepth = 8
+
+// Scripts are compiled once per game, as hooks like onTurn are run a lot
+// They're all from the datafiles, so the cache can't grow past what they have
+type scriptCache map[string]*goja.Program
+
+// Compile a script, or get it from the cache if it's been compiled already
+func (sc scriptCache) compile(source string, script string) (*goja.Program, error) {
+	if prg, ok := sc[source+"\n"+script]; ok {
+		return prg, nil
+	}
+
+	prg, err := goja.Compile(source, script, false)
+	if err != nil {
+		se := scriptError{source: source, msg: err.Error()}
+		if m := syntaxLineRegex.FindStringSubmatch(err.Error()); m != nil {
+			se.line, _ = strconv.Atoi(m[1])
+			se.msg = m[2]
+		}
+
+		return nil, se
+	}
+
+	sc[source+"\n"+script] = prg
+	return prg, nil
+}
+
+// A script failing, with where the script came from and the line it failed on
+type scriptError struct {
+	source string // e.g. item 'potion_blue'
+	line   int
+	msg    string
+}
+
+func (e scriptError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("script for %s failed at line %d: %s", e.source, e.line, e.msg)
+	}
+
+	return fmt.Sprintf("script for %s failed: %s", e.source, e.msg)
+}
+
+var syntaxLineRegex = regexp.MustCompile(`Line (\d+):\d+ (.*)`)
+
+// Run a script, the source describes what it belongs to & is used in errors
+// Extra globals can be passed in, e.g. the name of the trap that was set off
+func runScript(g *Game, source string, script string, globals map[string]any) (goja.Value, error) {
+	if g.scriptDepth >= maxScriptDepth {
+		return nil, scriptError{source: source, msg: "too many scripts setting each other off"}
+	}
+
+	prg, err := g.scripts.compile(source, script)
+	if err != nil {
+		return nil, err
+	}
+
+	g.scriptDepth++
+	defer func() { g.scriptDepth-- }()
+
+	vm := newScriptVM(g)
+	for name, value := range globals {
+		_ = vm.Set(name, value)
+	}
+
+	timer := time.AfterFunc(scriptTimeLimit, func() {
+		vm.Interrupt(fmt.Sprintf("ran for longer than %s and was stopped, the game can't continue", scriptTimeLimit))
+	})
+	defer timer.Stop()
+
+	result, err := vm.RunProgram(prg)
+	if err == nil {
+		return result, nil
+	}
+
+	se := scriptError{source: source, msg: err.Error()}
+
+	var interrupted *goja.InterruptedError
+	var exception *goja.Exception
+
+	switch {
+	case errors.As(err, &interrupted):
+		se.msg = fmt.Sprint(interrupted.Value())
+		se.line = stackLine(interrupted.Stack())
+		g.failed = se
+	case errors.As(err, &exception):
+		se.msg = exception.Value().String()
+		se.line = stackLine(exception.Stack())
+	}
+
+	return nil, se
+}
+
+// The line the script was on, skipping frames inside Go functions called by the script
+func stackLine(stack []goja.StackFrame) int {
+	for _, frame := range stack {
+		if line := frame.Position().Line; line > 0 {
+			return line
+		}
+	}
+
+	return 0
+}
+
+// Create a VM for running a scriptlet, with the API objects scripts can use
+func newScriptVM(g *Game) *goja.Runtime {
+	vm := goja.New()
+
+	// Math.random would break replays, so it has to use the game RNG too
+	vm.SetRandSource(g.rng.Float64)
+
+	_ = vm.Set("game", scriptGameAPI(vm, g))
+	_ = vm.Set("map", scriptMapAPI(vm, g))
+	_ = vm.Set("player", scriptPlayerAPI(vm, g))
+	_ = vm.Set("spawn", scriptSpawnAPI(vm, g))
+	_ = vm.Set("effects", scriptEffectsAPI(vm, g))
+	_ = vm.Set("random", scriptRandomAPI(vm, g))
+	_ = vm.Set("message", func(text string) {
+		events.new(EventMiscMessage, nil, text)
+	})
+
+	return vm
+}
+
+// Build a JS object from a set of Go functions
+func scriptObject(vm *goja.Runtime, funcs map[string]any) *goja.Object {
+	obj := vm.NewObject()
+	for name, f := range funcs {
+		_ = obj.Set(name, f)
+	}
+
+	return obj
+}
+
+func scriptGameAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	return scriptObject(vm, map[string]any{
+		"depth": g.Depth,
+		"turn":  g.Turn,
+		"seed":  func() int64 { return int64(g.seed) },
+	})
+}
+
+func scriptMapAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	return scriptObject(vm, map[string]any{
+		"width":  func() int { return g.gameMap.Width },
+		"height": func() int { return g.gameMap.Height },
+		"isFloor": func(x, y int) bool {
+			t := g.gameMap.Tile(x, y)
+			return t != nil && t.tileType != tileTypeWall
+		},
+		"isFree": func(x, y int) bool {
+			t := g.gameMap.Tile(x, y)
+			return t != nil && !t.BlocksMove()
+		},
+		"creatureAt": func(x, y int) any {
+			if t := g.gameMap.Tile(x, y); t != nil && t.creature != nil {
+				return t.creature.id
+			}
+
+			return nil
+		},
+		"itemsAt": func(x, y int) []string {
+			ids := make([]string, 0)
+			if t := g.gameMap.Tile(x, y); t != nil {
+				for _, i := range t.items.AllItems() {
+					ids = append(ids, i.id)
+				}
+			}
+
+			return ids
+		},
+	})
+}
+
+func scriptPlayerAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	p := g.player
+
+	return scriptObject(vm, map[string]any{
+		"name":  p.Name,
+		"hp":    p.HP,
+		"maxHP": p.MaxHP,
+		"level": p.Level,
+		"exp":   p.Exp,
+		"x":     func() int { return p.X },
+		"y":     func() int { return p.Y },
+		"setHP": func(hp int) {
+			p.hp = core.MinInt(hp, p.maxHP)
+		},
+		"heal": func(amount int) int {
+			healed := core.MinInt(p.hp+max(amount, 0), p.maxHP) - p.hp
+			p.hp += healed
+			return healed
+		},
+		"damage": func(amount int) {
+			p.applyDamage(max(amount, 0))
+		},
+		"addExp": func(amount int) {
+			g.gainExp(max(amount, 0))
+		},
+		"mana":    p.Mana,
+		"maxMana": p.MaxMana,
+		"restoreMana": func(amount int) int {
+			restored := core.MinInt(p.mana+max(amount, 0), p.maxMana) - p.mana
+			p.mana += restored
+			return restored
+		},
+		// Returns false if the spell is already known
+		"learnSpell": func(id string) (bool, error) {
+			if _, ok := g.spells[id]; !ok {
+				return false, fmt.Errorf("unknown spell '%s'", id)
+			}
+
+			return g.learnSpell(id), nil
+		},
+		"teleport": func(x, y int) bool {
+			t := g.gameMap.Tile(x, y)
+			if t == nil || t.BlocksMove() {
+				return false
+			}
+
+			p.moveToTile(t)
+			g.updateFOV()
+			return true
+		},
+		// Strength is optional, it's only used by poison & regeneration
+		"addStatus": g.addPlayerStatus,
+		"hasStatus": func(name string) (bool, error) {
+			kind, err := parseStatusKind(name)
+			return p.statuses.has(kind), err
+		},
+		"cureStatus": func(name string) (bool, error) {
+			kind, err := parseStatusKind(name)
+			if err != nil || !p.statuses.remove(kind) {
+				return false, err
+			}
+
+			events.new(EventStatusExpired, nil, fmt.Sprintf("You are no longer %s", statusInfos[kind].adjective))
+			if kind == statusBlindness {
+				g.updateFOV()
+			}
+
+			return true, nil
+		},
+	})
+}
+
+func scriptSpawnAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	// Returns the new creature, or null if it couldn't be placed
+	spawnCreature := func(id string, t *tile) any {
+		c := g.creatureGen.createCreature(id)
+		if c == nil || t == nil || g.occupied(t) {
+			return nil
+		}
+
+		c.depth = g.gameMap.depth
+		if !g.spawnCreature(c, t) {
+			return nil
+		}
+
+		return scriptCreatureAPI(g, c)
+	}
+
+	// A free tile next to a position, nil if there isn't one
+	freeNeighbour := func(p pos) *tile {
+		for _, n := range p.NeighboursAll() {
+			if t := g.gameMap.TileAt(n); t != nil && !g.occupied(t) {
+				return t
+			}
+		}
+
+		return nil
+	}
+
+	spawnItem := func(id string, t *tile) bool {
+		return t != nil && t.addItem(g.itemGen.createItem(id))
+	}
+
+	return scriptObject(vm, map[string]any{
+		// Put a creature on a free tile next to the player
+		"creature": func(id string) any {
+			return spawnCreature(id, freeNeighbour(g.player.pos))
+		},
+		"creatureAt": func(id string, x, y int) any {
+			return spawnCreature(id, g.gameMap.Tile(x, y))
+		},
+		"creatureNear": func(id string, x, y int) any {
+			return spawnCreature(id, freeNeighbour(core.Pos{X: x, Y: y}))
+		},
+		// Put an item at the player's feet
+		"item": func(id string) bool {
+			return spawnItem(id, g.player.currentTile)
+		},
+		"itemAt": func(id string, x, y int) bool {
+			t := g.gameMap.Tile(x, y)
+			return t != nil && t.tileType != tileTypeWall && spawnItem(id, t)
+		},
+	})
+}
+
+func scriptEffectsAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	return scriptObject(vm, map[string]any{
+		// Move the player somewhere random on the level
+		"teleport": func() {
+			g.teleportPlayer()
+			g.updateFOV()
+		},
+		// The player remembers the whole level as if they had seen it
+		"reveal": func() {
+			g.gameMap.enumerateFunc(func(t *tile, x, y int) {
+				t.seen = true
+			})
+		},
+		// Creatures within the radius know where the player is
+		"alarm": g.alertCreatures,
+		// Damage the player & creatures within the radius of a position
+		"blast": func(x, y, radius int, damage string) {
+			dice, ok := ParseDiceRoll(damage)
+			if !ok {
+				panic(vm.NewTypeError("bad dice roll '%s'", damage))
+			}
+
+			g.blast(core.Pos{X: x, Y: y}, radius, dice)
+		},
+		// Identify a random unknown item the player is carrying, returns its name or null
+		"identify": func() any {
+			if i := g.identifyCarried(); i != nil {
+				return i.Name()
+			}
+
+			return nil
+		},
+	})
+}
+
+func scriptRandomAPI(vm *goja.Runtime, g *Game) *goja.Object {
+	return scriptObject(vm, map[string]any{
+		"chance": g.rng.Chance,
+		// A random number from min to max, including both
+		"int": func(min, max int) int {
+			if max <= min {
+				return min
+			}
+
+			return g.rng.IntN(max-min+1) + min
+		},
+		"dice": func(roll string) int {
+			dice, ok := ParseDiceRoll(roll)
+			if !ok {
+				panic(vm.NewTypeError("bad dice roll '%s'", roll))
+			}
+
+			return dice.Roll(g.rng)
+		},
+		"pick": func(values []goja.Value) goja.Value {
+			if len(values) == 0 {
+				return goja.Undefined()
+			}
+
+			return values[g.rng.IntN(len(values))]
+		},
+	})
+}
+
+// The API for a creature, passed to creature hooks as the creature global
+// Built as a map rather than an object so it doesn't need a VM to create
+func scriptCreatureAPI(g *Game, c *creature) map[string]any {
+	return map[string]any{
+		"id":    func() string { return c.id },
+		"name":  c.Name,
+		"hp":    func() int { return c.hp },
+		"maxHP": func() int { return c.maxHP },
+		"x":     func() int { return c.X },
+		"y":     func() int { return c.Y },
+		"setHP": func(hp int) {
+			c.hp = core.MinInt(hp, c.maxHP)
+		},
+		"alive": func() bool { return c.currentTile != nil && c.hp > 0 },
+		"addStatus": func(name string, turns, strength int) error {
+			return g.addCreatureStatus(c, name, turns, strength)
+		},
+		"hasStatus": func(name string) (bool, error) {
+			kind, err := parseStatusKind(name)
+			return c.statuses.has(kind), err
+		},
+	}
+}
+
+// The API for a piece of furniture, passed to furniture hooks as the furniture global
+func scriptFurnitureAPI(g *Game, f *furniture) map[string]any {
+	return map[string]any{
+		"id":     func() string { return f.id },
+		"name":   f.Name,
+		"x":      func() int { return f.pos.X },
+		"y":      func() int { return f.pos.Y },
+		"isOpen": func() bool { return f.open },
+		// Remove the furniture from the map, without dropping any loot
+		"destroy": func() {
+			if t := g.gameMap.TileAt(*f.pos); t != nil && t.furniture == f {
+				t.furniture = nil
+				g.updateFOV()
+			}
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+// ============================================================================
+// Tests for the scripting API
+// ============================================================================
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestScriptErrors(t *testing.T) {
+	g := testGame(t)
+
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"var x = 1\nnoSuchFunction()", []string{"item 'test'", "line 2", "ReferenceError"}},
+		{"var x = 1\n\nif (x {", []string{"item 'test'", "line 3"}},
+		{"random.dice('lots')", []string{"item 'test'", "line 1", "bad dice roll"}},
+	}
+
+	for _, test := range tests {
+		_, err := runScript(g, "item 'test'", test.script, nil)
+		if err == nil {
+			t.Errorf("script %q should fail", test.script)
+			continue
+		}
+
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("script %q error %q should contain %q", test.script, err, want)
+			}
+		}
+	}
+}
+
+func TestScriptTimeLimit(t *testing.T) {
+	g := testGame(t)
+
+	_, err := runScript(g, "trap 'loop'", "var i = 0\nwhile(true) { i++ }", nil)
+	if err == nil || !strings.Contains(err.Error(), "stopped") || !strings.Contains(err.Error(), "trap 'loop'") {
+		t.Errorf("runaway script should be stopped, got %v", err)
+	}
+
+	// How far the script got isn't repeatable, so the game can't go on
+	if g.Err() == nil || !g.IsOver() || g.ProcessAction(NewWaitAction()).Success {
+		t.Errorf("a script being stopped should end the game")
+	}
+}
+
+func TestScriptAPI(t *testing.T) {
+	g := testGame(t)
+	g.player.hp = 10
+
+	messages := 0
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventMiscMessage {
+			messages++
+		}
+	})
+
+	script := `
+		player.heal(5);
+		spawn.creature("rat");
+		spawn.item("sword");
+		message("hello from " + player.name());
+		var roll = random.int(3, 3);
+		[player.hp(), map.creatureAt(player.x(), player.y() - 1) || map.creatureAt(player.x() + 1, player.y() - 1), map.itemsAt(player.x(), player.y())[0], game.depth(), roll]`
+
+	result, err := runScript(g, "item 'test'", script, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := result.Export().([]any)
+	if got[0] != int64(15) {
+		t.Errorf("player should be healed to 15, got %v", got[0])
+	}
+
+	if len(g.gameMap.creatures) != 1 || g.gameMap.creatures[0].id != "rat" {
+		t.Errorf("a rat should have been spawned next to the player")
+	}
+
+	if got[2] != "sword" || got[3] != int64(1) || got[4] != int64(3) {
+		t.Errorf("unexpected script results %v", got)
+	}
+
+	if messages != 1 {
+		t.Errorf("script should send one message, got %d", messages)
+	}
+}
+
+func TestScriptSpawnNotOnPlayer(t *testing.T) {
+	g := testGame(t)
+
+	result, err := runScript(g, "item 'test'", "spawn.creatureAt('rat', player.x(), player.y())", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !goja.IsNull(result) || len(g.gameMap.creatures) != 0 || g.player.currentTile.creature != nil {
+		t.Errorf("a creature shouldn't be spawned on the player, got %v", result)
+	}
+}
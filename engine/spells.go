@@ -0,0 +1,250 @@
+package engine
+
+// ============================================================================
+// Spells the player learns from scrolls and casts using mana, wands cast a
+// spell too but use up one of their charges instead. Spells are loaded from
+// spells.yaml, they can be cast on the player, at a creature or over an area
+// and do damage to what they hit and/or run their onCast script
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Game turns it takes the player to get back one point of mana
+const manaRegenTurns = 5
+
+type spellTarget string
+
+const (
+	spellTargetSelf     spellTarget = "self"     // The player, e.g. healing
+	spellTargetCreature spellTarget = "creature" // Flies like a missile, hitting the first creature in the way
+	spellTargetArea     spellTarget = "area"     // Every creature in a radius, centred on a target or the player
+)
+
+// Spell is a bit of magic the player can learn and cast
+type Spell struct {
+	id          string
+	name        string
+	description string
+	mana        int // Mana it takes to cast
+	target      spellTarget
+	spellRange  int // How far away the target can be, area spells with no range are centred on the player
+	radius      int // Size of an area spell
+	damage      DiceRoll
+	onCast      string // Script run when the spell is cast, once for each creature hit
+}
+
+type yamlSpell struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Mana        int    `yaml:"mana"`
+	Target      string `yaml:"target"`
+	Range       int    `yaml:"range"`
+	Radius      int    `yaml:"radius"`
+	Damage      string `yaml:"damage"`
+	OnCast      string `yaml:"onCast"`
+}
+
+type yamlSpellsFile struct {
+	Spells map[string]yamlSpell `yaml:"spells"`
+}
+
+func loadSpells(dataFile string) (map[string]*Spell, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlSpellsFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	spells := make(map[string]*Spell)
+	for id, entry := range file.Spells {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("spell '%s' %s", id, problems[0])
+		}
+
+		damage, _ := ParseDiceRoll(entry.Damage)
+		spells[id] = &Spell{
+			id:          id,
+			name:        entry.Name,
+			description: entry.Description,
+			mana:        entry.Mana,
+			target:      spellTarget(entry.Target),
+			spellRange:  entry.Range,
+			radius:      entry.Radius,
+			damage:      damage,
+			onCast:      entry.OnCast,
+		}
+	}
+
+	return spells, nil
+}
+
+func (s Spell) ID() string {
+	return s.id
+}
+
+func (s Spell) Name() string {
+	return s.name
+}
+
+func (s Spell) Description() string {
+	return s.description
+}
+
+func (s Spell) Mana() int {
+	return s.mana
+}
+
+func (s Spell) Range() int {
+	return s.spellRange
+}
+
+// NeedsTarget is true if the spell has to be aimed at a creature
+func (s Spell) NeedsTarget() bool {
+	return s.target == spellTargetCreature || (s.target == spellTargetArea && s.spellRange > 0)
+}
+
+// Describe what the spell does, e.g. area 2d6 radius 2, range 6
+func (s Spell) DescribeEffects() string {
+	desc := string(s.target)
+	if s.damage != (DiceRoll{}) {
+		desc += " " + s.damage.String()
+	}
+
+	if s.target == spellTargetArea {
+		desc += fmt.Sprintf(" radius %d", s.radius)
+	}
+
+	if s.spellRange > 0 {
+		desc += fmt.Sprintf(", range %d", s.spellRange)
+	}
+
+	return desc
+}
+
+// ===== Casting ==============================================================
+
+// Cast a spell for the player, at a target creature if the spell needs one
+// Returns false if it couldn't be cast, e.g. the target is out of range
+func (g *Game) castSpell(s *Spell, target *creature) bool {
+	if s.NeedsTarget() && !g.canTarget(target, s.spellRange) {
+		return false
+	}
+
+	events.new(EventSpellCast, nil, fmt.Sprintf("You cast %s", s.name))
+
+	switch s.target {
+	case spellTargetSelf:
+		g.runSpellScript(s, nil)
+
+	case spellTargetCreature:
+		if c := g.missileLanding(target, s.spellRange).creature; c != nil {
+			g.spellHit(s, c)
+		} else {
+			events.new(EventMiscMessage, nil, fmt.Sprintf("The %s fizzles out", s.name))
+		}
+
+	case spellTargetArea:
+		centre := g.player.pos
+		if s.spellRange > 0 {
+			centre = g.missileLanding(target, s.spellRange).pos
+		}
+
+		for _, c := range slices.Clone(g.gameMap.creatures) {
+			if c.currentTile != nil && g.gameMap.canSee(centre, *c.pos, s.radius) && c.pos.Distance(centre) <= float64(s.radius) {
+				g.spellHit(s, c)
+			}
+		}
+	}
+
+	return true
+}
+
+// A spell hitting a creature, damage is done first then the script is run if it's still alive
+func (g *Game) spellHit(s *Spell, c *creature) {
+	if s.damage != (DiceRoll{}) {
+		dmg := max(s.damage.Roll(g.rng), 0)
+		c.applyDamage(dmg)
+		g.playerAttacked(c, CombatResult{
+			Attacker: s.name,
+			Defender: c.Name(),
+			Hit:      true,
+			Damage:   dmg,
+			HPLeft:   c.hp,
+			Killed:   c.hp <= 0,
+		})
+	}
+
+	if c.currentTile != nil {
+		g.runSpellScript(s, c)
+	}
+}
+
+// Run a spell's onCast script, with the creature it hit if there is one
+func (g *Game) runSpellScript(s *Spell, c *creature) {
+	if s.onCast == "" {
+		return
+	}
+
+	var globals map[string]any
+	if c != nil {
+		globals = map[string]any{"creature": scriptCreatureAPI(g, c)}
+	}
+
+	result, err := runScript(g, fmt.Sprintf("spell '%s'", s.id), s.onCast, globals)
+	if err != nil {
+		events.new(EventSystemMsg, nil, err.Error())
+		return
+	}
+
+	if msg, ok := result.Export().(string); ok {
+		events.new(EventSpellCast, c, msg)
+	}
+}
+
+// Teach the player a spell, returns false if it's unknown or they know it already
+func (g *Game) learnSpell(id string) bool {
+	s, ok := g.spells[id]
+	if !ok || slices.Contains(g.player.spells, id) {
+		return false
+	}
+
+	g.player.spells = append(g.player.spells, id)
+	events.new(EventSpellLearned, nil, fmt.Sprintf("You learn the spell %s", s.name))
+	return true
+}
+
+// Mana comes back slowly over time, called once every game turn
+func (g *Game) regenMana() {
+	p := g.player
+	if p.mana < p.maxMana && g.Turn()%manaRegenTurns == 0 {
+		p.mana++
+	}
+}
+
+// KnownSpells lists the spells the player can cast, in the order they were learned
+func (g *Game) KnownSpells() []*Spell {
+	spells := make([]*Spell, 0, len(g.player.spells))
+	for _, id := range g.player.spells {
+		if s, ok := g.spells[id]; ok {
+			spells = append(spells, s)
+		}
+	}
+
+	return spells
+}
+
+// WandSpell is the spell a wand casts, nil if the item isn't a wand
+func (g *Game) WandSpell(i *Item) *Spell {
+	return g.spells[i.spell]
+}
@@ -0,0 +1,177 @@
+package engine
+
+// ============================================================================
+// Tests for spells, wands & learning spells from scrolls
+// ============================================================================
+
+import (
+	"slices"
+	"testing"
+)
+
+// A player who knows a few spells, with plenty of mana
+func mageTestGame(t *testing.T, spells ...string) *Game {
+	g := testGame(t)
+	g.player.mana, g.player.maxMana = 20, 20
+	g.player.spells = spells
+
+	return g
+}
+
+func TestCastSpell(t *testing.T) {
+	g := mageTestGame(t, "magic_missile")
+	rat := targetRat(g, 4, 1)
+
+	if res := g.ProcessAction(NewCastAction(g.spells["magic_missile"], rat)); !res.Success {
+		t.Fatalf("casting a known spell should succeed")
+	}
+
+	if g.player.mana != 17 || rat.hp == rat.maxHP {
+		t.Errorf("casting should use 3 mana and hurt the rat, got mana %d, rat hp %d", g.player.mana, rat.hp)
+	}
+
+	if res := g.ProcessAction(NewCastAction(g.spells["fireball"], rat)); res.Success {
+		t.Errorf("spells which aren't known can't be cast")
+	}
+
+	g.player.mana = 2
+	if res := g.ProcessAction(NewCastAction(g.spells["magic_missile"], rat)); res.Success || g.player.mana != 2 {
+		t.Errorf("casting without enough mana should fail and use none")
+	}
+
+	g.player.mana = 20
+	far := targetRat(g, 4, 8)
+	g.spells["magic_missile"].spellRange = 3
+	if res := g.ProcessAction(NewCastAction(g.spells["magic_missile"], far)); res.Success || g.player.mana != 20 {
+		t.Errorf("casting at a creature out of range should fail and use no mana")
+	}
+}
+
+func TestAreaSpell(t *testing.T) {
+	g := mageTestGame(t, "dazzle", "heal")
+	near := targetRat(g, 4, 3)
+	edge := targetRat(g, 6, 4)
+	far := targetRat(g, 4, 0)
+
+	if res := g.ProcessAction(NewCastAction(g.spells["dazzle"], nil)); !res.Success {
+		t.Fatalf("area spells centred on the player don't need a target")
+	}
+
+	if !near.statuses.has(statusBlindness) || !edge.statuses.has(statusBlindness) || far.statuses.has(statusBlindness) {
+		t.Errorf("only creatures within the radius should be blinded")
+	}
+
+	g.player.hp = 10
+	if res := g.ProcessAction(NewCastAction(g.spells["heal"], nil)); !res.Success || g.player.hp != 30 {
+		t.Errorf("heal should run its script on the player, hp is %d", g.player.hp)
+	}
+}
+
+func TestWandCharges(t *testing.T) {
+	g := testGame(t)
+	rat := targetRat(g, 4, 1)
+
+	wand := g.itemGen.createItem("wand_of_sparks")
+	wand.charges = 2
+	g.player.backpack.Add(wand)
+
+	for i := 0; i < 2; i++ {
+		if res := g.ProcessAction(NewZapAction(wand, rat)); !res.Success {
+			t.Fatalf("zap %d should succeed", i+1)
+		}
+	}
+
+	if res := g.ProcessAction(NewZapAction(wand, rat)); res.Success || wand.Charges() != 0 {
+		t.Errorf("a wand with no charges left can't be zapped")
+	}
+
+	if rat.hp == rat.maxHP || g.player.mana != 0 {
+		t.Errorf("wands should hurt the rat without using mana")
+	}
+}
+
+func TestLearnSpellFromScroll(t *testing.T) {
+	g := mageTestGame(t)
+	scroll := g.itemGen.createItem("scroll_of_healing")
+	again := g.itemGen.createItem("scroll_of_healing")
+	g.player.backpack.Add(scroll)
+	g.player.backpack.Add(again)
+
+	if res := g.ProcessAction(NewUseAction(scroll)); !res.Success {
+		t.Fatalf("reading the scroll should succeed")
+	}
+
+	if g.player.backpack.Contains(scroll) || len(g.KnownSpells()) != 1 || g.KnownSpells()[0].ID() != "heal" {
+		t.Errorf("the scroll should be used up and teach heal, known %v", g.player.spells)
+	}
+
+	if res := g.ProcessAction(NewUseAction(again)); res.Success || !g.player.backpack.Contains(again) {
+		t.Errorf("a scroll for a spell already known shouldn't be used up")
+	}
+}
+
+func TestMageStartsWithSpells(t *testing.T) {
+	g := NewGame("../assets/datafiles", 1, "mage")
+	p := g.player
+
+	if p.MaxMana() != 20 || len(g.KnownSpells()) != 2 {
+		t.Errorf("mage should start with 20 mana and 2 spells, got %d and %v", p.MaxMana(), p.spells)
+	}
+
+	// Gaining a level gives more mana
+	g.gainExp(g.ExpForNextLevel())
+	if p.MaxMana() != 20+g.progression.ManaPerLevel {
+		t.Errorf("levelling up should raise max mana, got %d", p.MaxMana())
+	}
+
+	p.mana = 0
+	for i := 0; i < manaRegenTurns*2; i++ {
+		g.ProcessAction(NewWaitAction())
+	}
+
+	if p.mana != 2 {
+		t.Errorf("mana should come back one point every %d turns, got %d", manaRegenTurns, p.mana)
+	}
+
+	if warrior := NewGame("../assets/datafiles", 1, "warrior"); warrior.player.MaxMana() != 0 || len(warrior.KnownSpells()) != 0 {
+		t.Errorf("warriors don't get any magic")
+	}
+}
+
+func TestSpellSaveAndReplay(t *testing.T) {
+	g := NewGame("../assets/datafiles", 5, "mage")
+	g.player.mana = 7
+
+	wand := g.itemGen.createItem("wand_of_fire")
+	wand.charges = 1
+	g.player.backpack.Add(wand)
+
+	saved, err := g.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGame("../assets/datafiles", saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := loaded.player
+	if p.mana != 7 || p.maxMana != 20 || !slices.Equal(p.spells, g.player.spells) {
+		t.Errorf("mana & spells should be saved, got %d/%d %v", p.mana, p.maxMana, p.spells)
+	}
+
+	if w := loaded.findItem(wand.instanceID); w == nil || w.Charges() != 1 {
+		t.Errorf("wand charges should be saved")
+	}
+
+	g = mageTestGame(t, "dazzle")
+	g.recording = nil
+	g.ProcessAction(NewCastAction(g.spells["dazzle"], nil))
+
+	step := g.recording[0]
+	a, err := g.actionFromStep(step)
+	if cast, ok := a.(*CastAction); err != nil || !ok || cast.spell != "dazzle" || cast.target != nil {
+		t.Errorf("cast step should play back the same spell, got %+v %v %v", step, a, err)
+	}
+}
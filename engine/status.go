@@ -0,0 +1,280 @@
+package engine
+
+// ============================================================================
+// Statuses are timed effects on the player or creatures, e.g. poison or haste
+// They last a number of game turns, tick once every turn and then wear off
+// Potions, traps and any other script can give them out, see scripts.go
+// ============================================================================
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type statusKind int
+
+const (
+	statusPoison       statusKind = iota // Loses strength HP every turn
+	statusRegeneration                   // Gains strength HP every turn
+	statusHaste                          // Gains energy twice as fast
+	statusConfusion                      // Moves in a random direction half the time
+	statusBlindness                      // Can't see anything
+)
+
+// How a status is combined with one that is already there
+type statusStacking int
+
+const (
+	stackRefresh   statusStacking = iota // Keeps the longest duration & strongest effect
+	stackDuration                        // Durations add together
+	stackIntensity                       // Strengths add together, keeping the longest duration
+)
+
+const (
+	maxStatusTurns  = 100 // Longest any status can last
+	confusionChance = 50  // Chance a confused move goes in a random direction
+)
+
+type statusInfo struct {
+	name      string
+	adjective string // Used in messages, e.g. you are poisoned
+	stacking  statusStacking
+	onTick    statusTick // Run every turn the status lasts, nil if it has no effect over time
+}
+
+// What a status does every turn, returns true if whoever has it died
+type statusTick func(g *Game, h statusHolder, s *status) bool
+
+var statusInfos = map[statusKind]statusInfo{
+	statusPoison:       {"poison", "poisoned", stackIntensity, tickPoison},
+	statusRegeneration: {"regeneration", "regenerating", stackRefresh, tickRegeneration},
+	statusHaste:        {"haste", "hasted", stackRefresh, nil},
+	statusConfusion:    {"confusion", "confused", stackDuration, nil},
+	statusBlindness:    {"blindness", "blinded", stackDuration, nil},
+}
+
+// Poison does its strength in damage every turn
+func tickPoison(g *Game, h statusHolder, s *status) bool {
+	h.applyDamage(s.strength)
+	return h.hurtByStatus(g, s)
+}
+
+// Regeneration heals its strength in HP every turn
+func tickRegeneration(g *Game, h statusHolder, s *status) bool {
+	h.heal(s.strength)
+	return false
+}
+
+func parseStatusKind(name string) (statusKind, error) {
+	names := make([]string, 0, len(statusInfos))
+	for kind, info := range statusInfos {
+		if info.name == name {
+			return kind, nil
+		}
+
+		names = append(names, info.name)
+	}
+
+	slices.Sort(names)
+	return 0, fmt.Errorf("unknown status '%s', should be one of %s", name, strings.Join(names, ", "))
+}
+
+type status struct {
+	kind     statusKind
+	turns    int  // Turns left before it wears off
+	strength int  // Only used by some statuses, e.g. damage done by poison
+	byPlayer bool // Given by the player, so they get the experience if it kills a creature
+}
+
+// Statuses on the player or a creature, in the order they were given
+type statusList []*status
+
+func (sl statusList) get(kind statusKind) *status {
+	for _, s := range sl {
+		if s.kind == kind {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (sl statusList) has(kind statusKind) bool {
+	return sl.get(kind) != nil
+}
+
+// Add a status, stacking it with any already there, returns true if it's new
+func (sl *statusList) add(kind statusKind, turns, strength int) bool {
+	turns = min(turns, maxStatusTurns)
+	strength = max(strength, 1)
+
+	s := sl.get(kind)
+	if s == nil {
+		*sl = append(*sl, &status{kind: kind, turns: turns, strength: strength})
+		return true
+	}
+
+	switch statusInfos[kind].stacking {
+	case stackRefresh:
+		s.turns = max(s.turns, turns)
+		s.strength = max(s.strength, strength)
+	case stackDuration:
+		s.turns = min(s.turns+turns, maxStatusTurns)
+	case stackIntensity:
+		s.turns = max(s.turns, turns)
+		s.strength += strength
+	}
+
+	return false
+}
+
+func (sl *statusList) remove(kind statusKind) bool {
+	before := len(*sl)
+	*sl = slices.DeleteFunc(*sl, func(s *status) bool { return s.kind == kind })
+
+	return len(*sl) != before
+}
+
+// Energy gained each tick at a given speed, haste doubles it
+func (sl statusList) speed(base int) int {
+	if sl.has(statusHaste) {
+		return base * 2
+	}
+
+	return base
+}
+
+// Descriptions for the frontends, e.g. poisoned(5)
+func (sl statusList) descriptions() []string {
+	descs := make([]string, 0, len(sl))
+	for _, s := range sl {
+		descs = append(descs, fmt.Sprintf("%s(%d)", statusInfos[s.kind].adjective, s.turns))
+	}
+
+	return descs
+}
+
+// ===== Giving & Ticking Statuses ============================================
+
+// Give the player a status, the name is one of those in statusInfos
+func (g *Game) addPlayerStatus(name string, turns, strength int) error {
+	kind, err := parseStatusKind(name)
+	if err != nil {
+		return err
+	}
+
+	if turns <= 0 {
+		return nil
+	}
+
+	if g.player.statuses.add(kind, turns, strength) {
+		events.new(EventStatusAdded, nil, fmt.Sprintf("You are %s", statusInfos[kind].adjective))
+	}
+
+	if kind == statusBlindness {
+		g.updateFOV()
+	}
+
+	return nil
+}
+
+// Give a creature a status, messages are only shown if the player can see it
+func (g *Game) addCreatureStatus(c *creature, name string, turns, strength int) error {
+	kind, err := parseStatusKind(name)
+	if err != nil {
+		return err
+	}
+
+	if turns <= 0 {
+		return nil
+	}
+
+	if c.statuses.add(kind, turns, strength) && c.inPlayerFOV() {
+		events.new(EventStatusAdded, c, fmt.Sprintf("The %s is %s", c.Name(), statusInfos[kind].adjective))
+	}
+
+	// Whoever added to the status last gets the blame for it
+	c.statuses.get(kind).byPlayer = g.playerActing
+
+	return nil
+}
+
+func (c *creature) inPlayerFOV() bool {
+	return c.currentTile != nil && c.currentTile.inFOV
+}
+
+// The player & creatures both have statuses, which are ticked the same way
+type statusHolder interface {
+	heldStatuses() *statusList
+	applyDamage(amount int)
+	heal(amount int)
+	hurtByStatus(g *Game, s *status) bool // Damage has been done by a status, returns true if it killed them
+	statusExpired(g *Game, kind statusKind)
+}
+
+// Called once every game turn by the scheduler
+func (g *Game) tickStatuses() {
+	g.tickStatusesOf(g.player)
+
+	for _, c := range slices.Clone(g.gameMap.creatures) {
+		if c.currentTile != nil && len(c.statuses) > 0 {
+			g.tickStatusesOf(c)
+		}
+	}
+}
+
+// Run each status' effect then count down the turns it has left, stopping if it kills
+func (g *Game) tickStatusesOf(h statusHolder) {
+	statuses := h.heldStatuses()
+
+	for _, s := range slices.Clone(*statuses) {
+		if tick := statusInfos[s.kind].onTick; tick != nil && tick(g, h, s) {
+			return
+		}
+
+		if s.turns--; s.turns <= 0 {
+			statuses.remove(s.kind)
+			h.statusExpired(g, s.kind)
+		}
+	}
+}
+
+func (p *Player) heldStatuses() *statusList {
+	return &p.statuses
+}
+
+func (p *Player) hurtByStatus(g *Game, s *status) bool {
+	name := statusInfos[s.kind].name
+	events.new(EventPlayerHit, nil, fmt.Sprintf("The %s hurts you for %d damage", name, s.strength))
+
+	if p.hp <= 0 {
+		g.killPlayer(nil, name)
+		return true
+	}
+
+	return false
+}
+
+func (p *Player) statusExpired(g *Game, kind statusKind) {
+	events.new(EventStatusExpired, nil, fmt.Sprintf("You are no longer %s", statusInfos[kind].adjective))
+
+	if kind == statusBlindness {
+		g.updateFOV()
+	}
+}
+
+func (c *creature) heldStatuses() *statusList {
+	return &c.statuses
+}
+
+// A creature killed by a status the player gave it counts as the player's kill
+func (c *creature) hurtByStatus(g *Game, s *status) bool {
+	return c.hp <= 0 && g.killCreature(c, s.byPlayer, fmt.Sprintf("The %s dies from %s", c.Name(), statusInfos[s.kind].name))
+}
+
+func (c *creature) statusExpired(g *Game, kind statusKind) {
+	if c.inPlayerFOV() {
+		events.new(EventStatusExpired, c, fmt.Sprintf("The %s is no longer %s", c.Name(), statusInfos[kind].adjective))
+	}
+}
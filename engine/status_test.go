@@ -0,0 +1,141 @@
+package engine
+
+// ============================================================================
+// Tests for timed statuses on the player & creatures
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+func TestStatusStacking(t *testing.T) {
+	var sl statusList
+
+	sl.add(statusPoison, 5, 1)
+	sl.add(statusPoison, 3, 2)
+	if s := sl.get(statusPoison); s.turns != 5 || s.strength != 3 {
+		t.Errorf("poison should stack strength & keep the longest duration, got %d turns strength %d", s.turns, s.strength)
+	}
+
+	sl.add(statusConfusion, 5, 1)
+	sl.add(statusConfusion, 4, 1)
+	if s := sl.get(statusConfusion); s.turns != 9 {
+		t.Errorf("confusion durations should add up, got %d turns", s.turns)
+	}
+
+	sl.add(statusHaste, 10, 1)
+	sl.add(statusHaste, 4, 1)
+	if s := sl.get(statusHaste); s.turns != 10 {
+		t.Errorf("haste should refresh to the longest duration, got %d turns", s.turns)
+	}
+
+	sl.add(statusConfusion, 500, 1)
+	if s := sl.get(statusConfusion); s.turns != maxStatusTurns {
+		t.Errorf("statuses should be capped at %d turns, got %d", maxStatusTurns, s.turns)
+	}
+}
+
+func TestStatusTicksAndExpires(t *testing.T) {
+	g := testGame(t)
+	g.player.hp = 20
+
+	expired := 0
+	events.addEventListeners(func(e GameEvent) {
+		if e.Type() == EventStatusExpired {
+			expired++
+		}
+	})
+
+	if err := g.addPlayerStatus("poison", 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		g.ProcessAction(NewWaitAction())
+	}
+
+	if g.player.hp != 14 || g.player.statuses.has(statusPoison) || expired != 1 {
+		t.Errorf("3 turns of poison should do 6 damage then wear off, hp %d, expired events %d", g.player.hp, expired)
+	}
+
+	if err := g.addPlayerStatus("gout", 3, 1); err == nil {
+		t.Errorf("unknown status should be an error")
+	}
+
+	rat := g.creatureGen.createCreature("rat")
+	rat.speed = 0
+	g.spawnCreature(rat, g.gameMap.Tile(6, 6))
+	_ = g.addCreatureStatus(rat, "poison", 10, rat.maxHP)
+	g.ProcessAction(NewWaitAction())
+
+	if rat.currentTile != nil || len(g.gameMap.creatures) != 0 {
+		t.Errorf("poison should kill the rat")
+	}
+}
+
+func TestStatusEffects(t *testing.T) {
+	g := testGame(t)
+
+	_ = g.addPlayerStatus("blindness", 2, 1)
+	if len(g.gameMap.fovList) != 1 {
+		t.Errorf("blind player should only see their own tile, sees %d tiles", len(g.gameMap.fovList))
+	}
+
+	g.ProcessAction(NewWaitAction())
+	g.ProcessAction(NewWaitAction())
+	if len(g.gameMap.fovList) <= 1 {
+		t.Errorf("player should see again once blindness wears off")
+	}
+
+	// A hasted player gets two turns for every one a normal speed creature gets
+	rat := g.creatureGen.createCreature("rat")
+	rat.ai = nil
+	g.spawnCreature(rat, g.gameMap.Tile(7, 7))
+	_ = g.addPlayerStatus("haste", 20, 1)
+
+	startTicks := g.ticks
+	for i := 0; i < 10; i++ {
+		g.ProcessAction(NewWaitAction())
+	}
+
+	if turns := (g.ticks - startTicks) * speedNormal / energyPerTurn; turns != 5 {
+		t.Errorf("10 hasted actions should take 5 game turns, took %d", turns)
+	}
+
+	_ = g.addPlayerStatus("confusion", 50, 1)
+	moved := map[core.Pos]bool{}
+	for i := 0; i < 20; i++ {
+		g.player.moveToTile(g.gameMap.Tile(4, 4))
+		g.ProcessAction(NewMoveAction(core.DirNorth))
+		moved[g.player.Pos()] = true
+	}
+
+	if len(moved) < 2 {
+		t.Errorf("confused player should sometimes move the wrong way")
+	}
+}
+
+func TestStatusScripts(t *testing.T) {
+	g := testGame(t)
+	rat := g.creatureGen.createCreature("rat")
+	g.spawnCreature(rat, g.gameMap.Tile(6, 6))
+
+	script := `player.addStatus("regeneration", 10); creature.addStatus("blindness", 4); player.hasStatus("regeneration")`
+	result, err := runScript(g, "item 'test'", script, map[string]any{"creature": scriptCreatureAPI(g, rat)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Export() != true || !rat.statuses.has(statusBlindness) || rat.canSeePlayer(g) {
+		t.Errorf("script should give the player regeneration & blind the rat")
+	}
+
+	if s := g.player.statuses.get(statusRegeneration); s.strength != 1 {
+		t.Errorf("strength should default to 1, got %d", s.strength)
+	}
+
+	if _, err := runScript(g, "item 'test'", `player.addStatus("gout", 10)`, nil); err == nil {
+		t.Errorf("unknown status in a script should be an error")
+	}
+}
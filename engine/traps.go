@@ -0,0 +1,284 @@
+package engine
+
+// ============================================================================
+// Traps are hidden on the map, and go off when the player walks onto them
+// They can be found by searching, or the hard way by stepping on them
+// Creatures know where the traps are and never set them off
+// ============================================================================
+
+import (
+	"fmt"
+	"roguelike/core"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// What happens when a trap goes off
+type trapEffect string
+
+const (
+	trapEffectDart     trapEffect = "dart"     // Damages the player
+	trapEffectPit      trapEffect = "pit"      // Damages and drops the player to the level below
+	trapEffectTeleport trapEffect = "teleport" // Moves the player somewhere random on the level
+	trapEffectAlarm    trapEffect = "alarm"    // Tells nearby creatures where the player is
+	trapEffectSummon   trapEffect = "summon"   // Creatures appear around the player
+)
+
+// How far away a search can find traps
+const searchRadius = 2
+
+type trap struct {
+	entityBase
+
+	hidden     bool
+	effect     trapEffect
+	damage     DiceRoll
+	findChance int    // Chance of finding the trap with each search
+	radius     int    // Range of alarms
+	count      int    // Number of creatures summoned
+	script     string // Script run when the trap goes off, after the effect
+}
+
+func (t trap) Type() entityType {
+	return entityTypeTrap
+}
+
+func (t trap) String() string {
+	return "trap_" + t.id + "_" + t.instanceID
+}
+
+// ===== Trap Generator =======================================================
+
+// The trapGenerator is a kind of factory for creating traps
+type trapGenerator struct {
+	rng          *gameRNG
+	genFunctions map[string](func() *trap)
+	keys         []string
+	place        map[string]int // Max number of each trap placed on a level
+	minDepth     map[string]int // Shallowest level each trap is found on
+}
+
+type yamlTrap struct {
+	Description string `yaml:"description"`
+	Name        string `yaml:"name"`
+	Graphic     string `yaml:"graphic"`
+	Colour      string `yaml:"colour"`
+	Effect      string `yaml:"effect"`
+	Damage      string `yaml:"damage"`
+	FindChance  int    `yaml:"findChance"`
+	Radius      int    `yaml:"radius"`
+	Count       int    `yaml:"count"`
+	Place       int    `yaml:"place"`
+	MinDepth    int    `yaml:"minDepth"`
+	Script      string `yaml:"onTriggerScript"`
+}
+
+type yamlTrapsFile struct {
+	Traps map[string]yamlTrap `yaml:"traps"`
+}
+
+func newTrapGenerator(dataFile string, r *gameRNG) (*trapGenerator, error) {
+	data, err := core.ReadFile(dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var file yamlTrapsFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := trapGenerator{
+		rng:          r,
+		genFunctions: make(map[string](func() *trap)),
+		keys:         make([]string, 0),
+		place:        make(map[string]int),
+		minDepth:     make(map[string]int),
+	}
+
+	for id, entry := range file.Traps {
+		if problems := entry.check(); len(problems) > 0 {
+			return nil, fmt.Errorf("trap '%s' %s", id, problems[0])
+		}
+
+		effect := trapEffect(entry.Effect)
+		damage, _ := ParseDiceRoll(entry.Damage)
+
+		gen.genFunctions[id] = func() *trap {
+			return &trap{
+				entityBase: entityBase{
+					id:         id,
+					instanceID: core.RandId(gen.rng.Rand, 6),
+					desc:       entry.Description,
+					name:       entry.Name,
+					graphicId:  entry.Graphic,
+					colour:     entry.Colour,
+				},
+				hidden:     true,
+				effect:     effect,
+				damage:     damage,
+				findChance: entry.FindChance,
+				radius:     entry.Radius,
+				count:      entry.Count,
+				script:     entry.Script,
+			}
+		}
+
+		gen.keys = append(gen.keys, id)
+		gen.place[id] = entry.Place
+		gen.minDepth[id] = entry.MinDepth
+	}
+
+	// Sort the keys as the map iteration order above is random
+	slices.Sort(gen.keys)
+	return &gen, nil
+}
+
+func (gen trapGenerator) createTrap(id string) *trap {
+	genFunc, ok := gen.genFunctions[id]
+	if !ok {
+		return nil
+	}
+
+	return genFunc()
+}
+
+// Hide traps around a newly generated level, never on the stairs
+func (gen trapGenerator) placeTraps(m *GameMap) {
+	for _, id := range gen.keys {
+		if m.depth < gen.minDepth[id] {
+			continue
+		}
+
+		for i := gen.rng.IntN(gen.place[id] + 1); i > 0; i-- {
+			t := m.randomFloorTile(gen.rng, true)
+			if t == nil || t.trap != nil || t.furniture != nil || t.pos == m.upStairs {
+				continue
+			}
+
+			t.placeTrap(gen.createTrap(id))
+		}
+	}
+}
+
+// ===== Triggering ===========================================================
+
+// Set off a trap the player has stepped on, returns true if the player was moved
+func (t *trap) trigger(g *Game) bool {
+	p := g.player
+	t.hidden = false
+	moved := false
+
+	events.new(EventTrapTriggered, t, fmt.Sprintf("You set off a %s!", t.name))
+
+	if t.damage != (DiceRoll{}) {
+		dmg := max(t.damage.Roll(g.rng), 0)
+		p.applyDamage(dmg)
+		events.new(EventPlayerHit, t, fmt.Sprintf("The %s hits you for %d damage", t.name, dmg))
+
+		if p.hp <= 0 {
+			g.killPlayer(t, "a "+t.name)
+			return false
+		}
+	}
+
+	switch t.effect {
+	case trapEffectPit:
+		events.new(EventTrapTriggered, t, "You fall through the floor!")
+		if g.changeLevel(g.gameMap.depth + 1) {
+			g.teleportPlayer()
+			moved = true
+		}
+
+	case trapEffectTeleport:
+		g.teleportPlayer()
+		events.new(EventTrapTriggered, t, "The world spins around you")
+		moved = true
+
+	case trapEffectAlarm:
+		g.alertCreatures(t.radius)
+
+	case trapEffectSummon:
+		summoned := 0
+		for _, n := range p.currentTile.NeighboursAll() {
+			nt := g.gameMap.TileAt(n)
+			if summoned >= t.count || nt == nil || nt.BlocksMove() {
+				continue
+			}
+
+			if g.spawnCreature(g.creatureGen.createRandomCreature(g.gameMap.depth), nt) {
+				summoned++
+			}
+		}
+
+		if summoned > 0 {
+			events.new(EventTrapTriggered, t, "Creatures appear out of thin air!")
+		}
+	}
+
+	t.runScript(g)
+
+	if moved {
+		g.updateFOV()
+	}
+
+	return moved
+}
+
+// Run the trap's script if it has one, a string result is shown as a message
+func (t *trap) runScript(g *Game) {
+	if t.script == "" {
+		return
+	}
+
+	source := fmt.Sprintf("trap '%s'", t.id)
+	result, err := runScript(g, source, t.script, map[string]any{"trap": t.name})
+	if err != nil {
+		events.new(EventSystemMsg, t, err.Error())
+		return
+	}
+
+	if msgText, ok := result.Export().(string); ok {
+		events.new(EventTrapTriggered, t, msgText)
+	}
+
+	// Scripts can hurt the player too
+	if g.player.hp <= 0 {
+		g.killPlayer(t, "a "+t.name)
+	}
+}
+
+// Let creatures within a radius of the player know where they are
+func (g *Game) alertCreatures(radius int) {
+	for _, c := range g.gameMap.creatures {
+		if c.pos.Distance(g.player.pos) <= float64(radius) {
+			playerPos := g.player.pos
+			c.lastSeenPlayer = &playerPos
+		}
+	}
+}
+
+// Search around the player for hidden traps, returns how many were found
+func (g *Game) searchForTraps() int {
+	found := 0
+	p := g.player
+
+	for y := p.Y - searchRadius; y <= p.Y+searchRadius; y++ {
+		for x := p.X - searchRadius; x <= p.X+searchRadius; x++ {
+			t := g.gameMap.Tile(x, y)
+			if t == nil || t.trap == nil || !t.trap.hidden || !t.inFOV {
+				continue
+			}
+
+			if g.rng.Chance(t.trap.findChance) {
+				t.trap.hidden = false
+				found++
+				events.new(EventTrapFound, t.trap, fmt.Sprintf("You find a %s", t.trap.name))
+			}
+		}
+	}
+
+	return found
+}
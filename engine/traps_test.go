@@ -0,0 +1,66 @@
+package engine
+
+// ============================================================================
+// Tests for traps
+// ============================================================================
+
+import (
+	"roguelike/core"
+	"testing"
+)
+
+func TestTrapTriggersOnMove(t *testing.T) {
+	g := testGame(t)
+	dart := g.trapGen.createTrap("dart")
+	trapTile := g.gameMap.Tile(5, 4)
+	trapTile.placeTrap(dart)
+
+	if trapTile.Appearance().Graphic == "trap" {
+		t.Fatalf("hidden trap should not be visible")
+	}
+
+	hp := g.player.hp
+	g.ProcessAction(NewMoveAction(core.DirEast))
+
+	if dart.hidden || g.player.hp >= hp {
+		t.Errorf("dart trap should be revealed and do damage, hidden = %v, hp %d -> %d", dart.hidden, hp, g.player.hp)
+	}
+
+	if trapTile.Appearance().Graphic != "trap" {
+		t.Errorf("found trap should be visible")
+	}
+}
+
+func TestTrapEffects(t *testing.T) {
+	g := testGame(t)
+
+	summon := g.trapGen.createTrap("summon")
+	g.gameMap.Tile(5, 4).placeTrap(summon)
+	g.ProcessAction(NewMoveAction(core.DirEast))
+
+	if len(g.gameMap.creatures) == 0 {
+		t.Errorf("summon trap should create creatures")
+	}
+
+	g = testGame(t)
+	pit := g.trapGen.createTrap("pit")
+	g.gameMap.Tile(5, 4).placeTrap(pit)
+	g.player.hp = 100
+	g.ProcessAction(NewMoveAction(core.DirEast))
+
+	if g.Depth() != 2 {
+		t.Errorf("pit trap should drop the player to level 2, depth = %d", g.Depth())
+	}
+}
+
+func TestSearchFindsTraps(t *testing.T) {
+	g := testGame(t)
+	alarm := g.trapGen.createTrap("alarm")
+	alarm.findChance = 100
+	g.gameMap.Tile(6, 4).placeTrap(alarm)
+
+	g.ProcessAction(NewSearchAction())
+	if alarm.hidden {
+		t.Errorf("search should find a trap with 100%% find chance")
+	}
+}
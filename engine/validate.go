@@ -0,0 +1,878 @@
+package engine
+
+// ============================================================================
+// Validation of the datafiles, so mistakes are caught rather than quietly
+// turned into defaults. Each kind of entry has a check method, used by the
+// generators when loading and by ValidateDataFiles, which also finds the line
+// of every problem and checks references between files
+// ============================================================================
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"roguelike/core"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"gopkg.in/yaml.v3"
+)
+
+// DataError is a problem found in a datafile
+type DataError struct {
+	File string
+	Line int
+	Key  string // Path to the key with the problem, e.g. items.sword.effects.toHit
+	Msg  string
+}
+
+func (e DataError) Error() string {
+	return fmt.Sprintf("%s:%d: %s: %s", e.File, e.Line, e.Key, e.Msg)
+}
+
+// A problem with one key of a datafile entry, the key is empty if it's the whole entry
+type fieldProblem struct {
+	key string
+	msg string
+}
+
+func (p fieldProblem) String() string {
+	if p.key == "" {
+		return p.msg
+	}
+
+	return fmt.Sprintf("key '%s': %s", p.key, p.msg)
+}
+
+func problem(key string, format string, args ...any) fieldProblem {
+	return fieldProblem{key, fmt.Sprintf(format, args...)}
+}
+
+// ===== Entry Checks =========================================================
+
+// Everything drawn on the map needs a name and a graphic
+func checkAppearance(name, graphic string) []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if name == "" {
+		problems = append(problems, problem("", "missing 'name'"))
+	}
+
+	if graphic == "" {
+		problems = append(problems, problem("", "missing 'graphic'"))
+	}
+
+	return problems
+}
+
+func checkRarityDepth(rarityName string, minDepth, maxDepth int) []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if _, ok := parseRarity(rarityName); !ok {
+		problems = append(problems, problem("rarity", "unknown rarity '%s'", rarityName))
+	}
+
+	if minDepth < 0 {
+		problems = append(problems, problem("minDepth", "can't be negative"))
+	}
+
+	if maxDepth > 0 && maxDepth < minDepth {
+		problems = append(problems, problem("maxDepth", "%d is less than minDepth %d", maxDepth, minDepth))
+	}
+
+	return problems
+}
+
+func checkScript(key, script string) []fieldProblem {
+	if script == "" {
+		return nil
+	}
+
+	if _, err := goja.Compile(key, script, false); err != nil {
+		return []fieldProblem{problem(key, "script doesn't compile: %s", err)}
+	}
+
+	return nil
+}
+
+func (e yamlItem) check() []fieldProblem {
+	problems := checkAppearance(e.Name, e.Graphic)
+	problems = append(problems, checkRarityDepth(e.Rarity, e.MinDepth, e.MaxDepth)...)
+	problems = append(problems, checkScript("onUseScript", e.OnUseScript)...)
+
+	if e.EquipLocation != "" && parseEquipLocation(e.EquipLocation) == EquipLocationNone {
+		problems = append(problems, problem("equipLocation", "unknown equip location '%s'", e.EquipLocation))
+	}
+
+	if e.TwoHanded && parseEquipLocation(e.EquipLocation) != equipLocationWeapon {
+		problems = append(problems, problem("twoHanded", "only weapons can be two-handed"))
+	}
+
+	if e.Weight < 0 {
+		problems = append(problems, problem("weight", "can't be negative"))
+	}
+
+	problems = append(problems, e.checkStack()...)
+
+	problems = append(problems, e.checkRanged()...)
+	problems = append(problems, e.checkMagic()...)
+
+	for _, name := range slices.Sorted(maps.Keys(e.Effects)) {
+		if _, err := newEffect(name, e.Effects[name]); err != nil {
+			problems = append(problems, problem("effects."+name, "%s", err))
+		}
+	}
+
+	return problems
+}
+
+// Only simple items stack, equipment & wands are each different, quantities need a stack to go in
+func (e yamlItem) checkStack() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if e.Stackable && e.EquipLocation != "" {
+		problems = append(problems, problem("stackable", "equipment can't be stacked"))
+	}
+
+	if e.Stackable && e.Charges > 0 {
+		problems = append(problems, problem("stackable", "wands can't be stacked, they each have their own charges"))
+	}
+
+	if e.Quantity == "" {
+		return problems
+	}
+
+	if _, ok := ParseDiceRoll(e.Quantity); !ok {
+		problems = append(problems, problem("quantity", "bad dice roll '%s'", e.Quantity))
+	}
+
+	if !e.Stackable {
+		problems = append(problems, problem("quantity", "only stackable items are found in quantities"))
+	}
+
+	return problems
+}
+
+// Launchers need ammunition, a range & damage, anything with ranged damage but no launcher can be thrown
+func (e yamlItem) checkRanged() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if _, ok := ParseDiceRoll(e.RangedDamage); e.RangedDamage != "" && !ok {
+		problems = append(problems, problem("rangedDamage", "bad dice roll '%s'", e.RangedDamage))
+	}
+
+	if e.Range < 0 {
+		problems = append(problems, problem("range", "can't be negative"))
+	}
+
+	if e.Fires != "" && e.Ammo != "" {
+		problems = append(problems, problem("ammo", "a launcher can't also be ammunition"))
+	}
+
+	isMissile := parseEquipLocation(e.EquipLocation) == equipLocationMissile
+	if e.Fires == "" {
+		if isMissile {
+			problems = append(problems, problem("fires", "missile weapons need the kind of ammunition they fire"))
+		}
+
+		return problems
+	}
+
+	if !isMissile {
+		problems = append(problems, problem("equipLocation", "launchers must be equipped in the missile slot"))
+	}
+
+	if e.Range <= 0 {
+		problems = append(problems, problem("range", "launchers need a range"))
+	}
+
+	if e.RangedDamage == "" {
+		problems = append(problems, problem("rangedDamage", "launchers need a damage roll"))
+	}
+
+	return problems
+}
+
+// Wands need a spell to cast and charges to cast it with, scrolls of learning need to be usable
+func (e yamlItem) checkMagic() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if e.Charges < 0 {
+		problems = append(problems, problem("charges", "can't be negative"))
+	}
+
+	if e.Spell != "" && e.Charges == 0 {
+		problems = append(problems, problem("charges", "wands need some charges"))
+	}
+
+	if e.Spell == "" && e.Charges > 0 {
+		problems = append(problems, problem("spell", "only wands have charges, they need a spell to cast"))
+	}
+
+	if e.Spell != "" && e.LearnSpell != "" {
+		problems = append(problems, problem("learnSpell", "an item can't be a wand and teach a spell"))
+	}
+
+	if e.LearnSpell != "" && !e.Usable {
+		problems = append(problems, problem("usable", "items which teach a spell must be usable"))
+	}
+
+	return problems
+}
+
+func (e yamlSpell) check() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if e.Name == "" {
+		problems = append(problems, problem("", "missing 'name'"))
+	}
+
+	if e.Mana < 0 {
+		problems = append(problems, problem("mana", "can't be negative"))
+	}
+
+	if e.Range < 0 || e.Radius < 0 {
+		problems = append(problems, problem("", "range & radius can't be negative"))
+	}
+
+	switch spellTarget(e.Target) {
+	case spellTargetSelf:
+		if e.Damage != "" {
+			problems = append(problems, problem("damage", "spells cast on the player can't do damage"))
+		}
+
+	case spellTargetCreature:
+		if e.Range <= 0 {
+			problems = append(problems, problem("range", "spells cast at a creature need a range"))
+		}
+
+	case spellTargetArea:
+		if e.Radius <= 0 {
+			problems = append(problems, problem("radius", "area spells need a radius"))
+		}
+
+	default:
+		problems = append(problems, problem("target", "unknown target '%s', should be self, creature or area", e.Target))
+	}
+
+	if _, ok := ParseDiceRoll(e.Damage); e.Damage != "" && !ok {
+		problems = append(problems, problem("damage", "bad dice roll '%s'", e.Damage))
+	}
+
+	if e.Damage == "" && e.OnCast == "" {
+		problems = append(problems, problem("", "needs damage or an onCast script, or it does nothing"))
+	}
+
+	problems = append(problems, checkScript("onCast", e.OnCast)...)
+	return problems
+}
+
+func (e yamlPerk) check() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if e.Name == "" {
+		problems = append(problems, problem("", "missing 'name'"))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(e.Effects)) {
+		if _, err := newEffect(name, e.Effects[name]); err != nil {
+			problems = append(problems, problem("effects."+name, "%s", err))
+		}
+	}
+
+	return problems
+}
+
+func (prog progression) check() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if len(prog.XPCurve) == 0 {
+		problems = append(problems, problem("xpCurve", "needs at least one level"))
+	}
+
+	for i := range prog.XPCurve {
+		if prog.XPCurve[i] <= 0 || (i > 0 && prog.XPCurve[i] <= prog.XPCurve[i-1]) {
+			problems = append(problems, problem("xpCurve", "should go up with every level"))
+			break
+		}
+	}
+
+	if prog.HPPerLevel < 0 || prog.ToHitPerLevel < 0 || prog.ManaPerLevel < 0 {
+		problems = append(problems, problem("", "stats gained per level can't be negative"))
+	}
+
+	return problems
+}
+
+func (e yamlClass) check() []fieldProblem {
+	problems := make([]fieldProblem, 0)
+	if e.Name == "" {
+		problems = append(problems, problem("", "missing 'name'"))
+	}
+
+	if e.HP <= 0 {
+		problems = append(problems, problem("hp", "must be more than zero"))
+	}
+
+	if e.ToHit < 0 || e.ToHit > 100 {
+		problems = append(problems, problem("toHit", "should be a percentage"))
+	}
+
+	if e.Speed <= 0 {
+		problems = append(problems, problem("speed", "must be more than zero"))
+	}
+
+	if e.FOVDistance <= 0 {
+		problems = append(problems, problem("fovDistance", "must be more than zero"))
+	}
+
+	if e.Mana < 0 {
+		problems = append(problems, problem("mana", "can't be negative"))
+	}
+
+	if len(e.Spells) > 0 && e.Mana == 0 {
+		problems = append(problems, problem("spells", "classes with spells need some mana to cast them"))
+	}
+
+	if len(e.Items) > PLAYER_MAX_ITEMS {
+		problems = append(problems, problem("items", "more than the %d items the player can carry", PLAYER_MAX_ITEMS))
+	}
+
+	return problems
+}
+
+func (e yamlCreature) check() []fieldProblem {
+	problems := checkAppearance(e.Name, e.Graphic)
+	problems = append(problems, checkRarityDepth(e.Rarity, e.MinDepth, e.MaxDepth)...)
+
+	if e.Hp <= 0 {
+		problems = append(problems, problem("hp", "must be more than zero"))
+	}
+
+	if _, err := parseAttack(e.Attack); err != nil {
+		problems = append(problems, problem("attack", "%s", err))
+	}
+
+	for _, hook := range []struct{ key, script string }{
+		{"onSpawn", e.OnSpawn}, {"onTurn", e.OnTurn}, {"onHit", e.OnHit}, {"onDeath", e.OnDeath},
+	} {
+		problems = append(problems, checkScript(hook.key, hook.script)...)
+	}
+
+	for _, name := range e.AI {
+		if _, ok := behaviours[name]; !ok {
+			problems = append(problems, problem("ai", "unknown behaviour '%s'", name))
+		}
+	}
+
+	return problems
+}
+
+func (e yamlFurniture) check() []fieldProblem {
+	problems := checkAppearance(e.Name, e.Graphic)
+	problems = append(problems, checkScript("onInteract", e.OnInteract)...)
+
+	if e.HP < 0 {
+		problems = append(problems, problem("hp", "can't be negative"))
+	}
+
+	if e.Loot < 0 {
+		problems = append(problems, problem("loot", "can't be negative"))
+	}
+
+	return problems
+}
+
+func (e yamlTrap) check() []fieldProblem {
+	problems := checkAppearance(e.Name, e.Graphic)
+	problems = append(problems, checkScript("onTriggerScript", e.Script)...)
+
+	switch trapEffect(e.Effect) {
+	case trapEffectDart, trapEffectPit, trapEffectTeleport, trapEffectAlarm, trapEffectSummon:
+	default:
+		problems = append(problems, problem("effect", "unknown effect '%s'", e.Effect))
+	}
+
+	if _, ok := ParseDiceRoll(e.Damage); e.Damage != "" && !ok {
+		problems = append(problems, problem("damage", "bad dice roll '%s'", e.Damage))
+	}
+
+	if e.FindChance < 0 || e.FindChance > 100 {
+		problems = append(problems, problem("findChance", "must be a percentage"))
+	}
+
+	return problems
+}
+
+func (e yamlLootTable) check() []fieldProblem {
+	if e.Chance != nil && (*e.Chance < 0 || *e.Chance > 100) {
+		return []fieldProblem{problem("chance", "must be a percentage")}
+	}
+
+	return nil
+}
+
+// ===== Validating Files =====================================================
+
+type dataEntry interface {
+	check() []fieldProblem
+}
+
+type validator struct {
+	errors      []DataError
+	file        string          // File currently being checked
+	sprites     map[string]bool // Sprite ids in sprites.yaml, nil if it couldn't be loaded
+	paletteSize int             // Number of colours in the smallest palette, zero if unknown
+	lootTables  map[string]bool // Loot tables in items.yaml, nil if it couldn't be loaded
+	items       *yaml.Node      // The items section of items.yaml, nil if it couldn't be loaded
+	spells      map[string]bool // Spell ids in spells.yaml, nil if it couldn't be loaded
+}
+
+// ValidateDataFiles checks the datafiles in an assets directory, returning every problem found
+// It checks the same things the game does when loading, and also that graphics & colours
+// exist in sprites.yaml & palettes.yaml, and that loot tables & spells used elsewhere exist
+func ValidateDataFiles(assetsDir string) []DataError {
+	v := &validator{}
+	v.loadSprites(assetsDir + "/sprites.yaml")
+	v.loadPalettes(assetsDir + "/palettes.yaml")
+
+	dataFileDir := assetsDir + "/datafiles"
+	if root := v.parse(dataFileDir + "/spells.yaml"); root != nil {
+		validateSection[yamlSpell](v, root, "spells")
+
+		_, spells := mapValue(root, "spells")
+		v.spells = make(map[string]bool)
+		for i := 0; spells != nil && i+1 < len(spells.Content); i += 2 {
+			v.spells[spells.Content[i].Value] = true
+		}
+	}
+
+	if root := v.parse(dataFileDir + "/items.yaml"); root != nil {
+		validateSection[yamlItem](v, root, "items")
+		_, v.items = mapValue(root, "items")
+		v.checkLootTables(root)
+		v.checkAmmo()
+		v.checkAppearances(root)
+	}
+
+	if root := v.parse(dataFileDir + "/creatures.yaml"); root != nil {
+		validateSection[yamlCreature](v, root, "creatures")
+	}
+
+	if root := v.parse(dataFileDir + "/furniture.yaml"); root != nil {
+		validateSection[yamlFurniture](v, root, "furniture")
+	}
+
+	if root := v.parse(dataFileDir + "/traps.yaml"); root != nil {
+		validateSection[yamlTrap](v, root, "traps")
+	}
+
+	if root := v.parse(dataFileDir + "/levels.yaml"); root != nil {
+		validateSection[yamlLevel](v, root, "levels")
+
+		_, levels := mapValue(root, "levels")
+		for i := 0; levels != nil && i+1 < len(levels.Content); i += 2 {
+			key := levels.Content[i]
+			if _, err := parseLevelKey(key.Value); err != nil && key.Value != "default" {
+				v.add(key.Line, "levels."+key.Value, "should be a depth or 'default'")
+			}
+		}
+	}
+
+	if root := v.parse(dataFileDir + "/classes.yaml"); root != nil {
+		validateSection[yamlClass](v, root, "classes")
+
+		_, classes := mapValue(root, "classes")
+		for i := 0; classes != nil && i+1 < len(classes.Content); i += 2 {
+			_, list := mapValue(classes.Content[i+1], "items")
+			v.checkItemList(list, "classes."+classes.Content[i].Value+".items")
+
+			_, list = mapValue(classes.Content[i+1], "spells")
+			v.checkSpellList(list, "classes."+classes.Content[i].Value+".spells")
+		}
+
+		if _, def := mapValue(root, "defaultClass"); def == nil {
+			v.add(root.Line, "defaultClass", "missing 'defaultClass'")
+		} else if keyNode, _ := mapValue(classes, def.Value); keyNode == nil {
+			v.add(def.Line, "defaultClass", "class '%s' is not in the classes section", def.Value)
+		}
+	}
+
+	if root := v.parse(dataFileDir + "/progression.yaml"); root != nil {
+		validateSection[yamlPerk](v, root, "perks")
+
+		if keyNode, node := mapValue(root, "progression"); node == nil {
+			v.add(root.Line, "progression", "missing 'progression' section")
+		} else {
+			var prog progression
+			if v.decode(node, &prog) {
+				v.checkUnknownKeys(node, reflect.TypeOf(prog), "progression")
+
+				for _, p := range prog.check() {
+					key, line := "progression", keyNode.Line
+					if p.key != "" {
+						key += "." + p.key
+						if n := findKey(node, p.key); n != nil {
+							line = n.Line
+						}
+					}
+
+					v.add(line, key, "%s", p.msg)
+				}
+			}
+		}
+	}
+
+	if root := v.parse(dataFileDir + "/combat.yaml"); root != nil {
+		if _, node := mapValue(root, "combat"); node == nil {
+			v.add(root.Line, "combat", "missing 'combat' section")
+		} else {
+			var rules CombatRules
+			if v.decode(node, &rules) {
+				v.checkUnknownKeys(node, reflect.TypeOf(rules), "combat")
+			}
+		}
+	}
+
+	return v.errors
+}
+
+func (v *validator) add(line int, key string, format string, args ...any) {
+	v.errors = append(v.errors, DataError{v.file, line, key, fmt.Sprintf(format, args...)})
+}
+
+var yamlLineRegex = regexp.MustCompile(`line (\d+): `)
+
+// Errors from the yaml package have the line in the message, move it into the DataError
+func (v *validator) addYAMLError(msg string, key string) {
+	line := 0
+	if m := yamlLineRegex.FindStringSubmatch(msg); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		msg = strings.Replace(msg, m[0], "", 1)
+	}
+
+	v.add(line, key, "%s", strings.TrimPrefix(msg, "yaml: "))
+}
+
+// Read and parse a datafile, returns the top level mapping or nil if it's broken
+func (v *validator) parse(file string) *yaml.Node {
+	v.file = file
+
+	data, err := core.ReadFile(file)
+	if err != nil {
+		v.add(0, "", "%s", err)
+		return nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.addYAMLError(err.Error(), "")
+		return nil
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		v.add(1, "", "file should be a mapping of sections")
+		return nil
+	}
+
+	return doc.Content[0]
+}
+
+// Decode a node, reporting any type errors, returns false if it failed
+func (v *validator) decode(node *yaml.Node, out any) bool {
+	err := node.Decode(out)
+	if err == nil {
+		return true
+	}
+
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		for _, msg := range typeErr.Errors {
+			v.addYAMLError(msg, "")
+		}
+	} else {
+		v.addYAMLError(err.Error(), "")
+	}
+
+	return false
+}
+
+// Check every entry in one section of a datafile, e.g. all the items
+func validateSection[T dataEntry](v *validator, root *yaml.Node, section string) {
+	_, node := mapValue(root, section)
+	if node == nil || node.Kind != yaml.MappingNode {
+		v.add(root.Line, section, "missing '%s' section", section)
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		idNode, entryNode := node.Content[i], node.Content[i+1]
+		path := section + "." + idNode.Value
+
+		var entry T
+		if !v.decode(entryNode, &entry) {
+			continue
+		}
+
+		v.checkUnknownKeys(entryNode, reflect.TypeOf(entry), path)
+		v.checkReferences(entryNode, path)
+
+		for _, p := range entry.check() {
+			key, line := path, idNode.Line
+			if p.key != "" {
+				key = path + "." + p.key
+				if keyNode := findKey(entryNode, p.key); keyNode != nil {
+					line = keyNode.Line
+				}
+			}
+
+			v.add(line, key, "%s", p.msg)
+		}
+	}
+}
+
+// Report keys which don't match any field, these are usually typos
+func (v *validator) checkUnknownKeys(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fields[name] = t.Field(i).Type
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			v.add(key.Line, path+"."+key.Value, "unknown key '%s'", key.Value)
+			continue
+		}
+
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if fieldType.Kind() == reflect.Struct {
+			v.checkUnknownKeys(node.Content[i+1], fieldType, path+"."+key.Value)
+		}
+	}
+}
+
+// Check graphics, colours, loot tables & spells refer to things which exist in other files
+func (v *validator) checkReferences(node *yaml.Node, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := path + "." + key.Value
+
+		switch {
+		case value.Kind == yaml.MappingNode:
+			v.checkReferences(value, keyPath)
+
+		case key.Value == "graphic" && v.sprites != nil && !v.sprites[value.Value]:
+			v.add(value.Line, keyPath, "graphic '%s' is not in sprites.yaml", value.Value)
+
+		case key.Value == "colour" && v.paletteSize > 0:
+			if c, err := strconv.Atoi(value.Value); err != nil || c < 0 || c >= v.paletteSize {
+				v.add(value.Line, keyPath, "colour '%s' should be a palette index from 0 to %d", value.Value, v.paletteSize-1)
+			}
+
+		case key.Value == "lootTable" && v.lootTables != nil && !v.lootTables[value.Value]:
+			v.add(value.Line, keyPath, "loot table '%s' is not in items.yaml", value.Value)
+
+		case (key.Value == "spell" || key.Value == "learnSpell") && v.spells != nil && !v.spells[value.Value]:
+			v.add(value.Line, keyPath, "spell '%s' is not in spells.yaml", value.Value)
+		}
+	}
+}
+
+// Loot tables can only list items which exist
+func (v *validator) checkLootTables(root *yaml.Node) {
+	_, tables := mapValue(root, "lootTables")
+	if tables == nil {
+		return
+	}
+
+	v.lootTables = make(map[string]bool)
+	for i := 0; i+1 < len(tables.Content); i += 2 {
+		nameNode, tableNode := tables.Content[i], tables.Content[i+1]
+		path := "lootTables." + nameNode.Value
+		v.lootTables[nameNode.Value] = true
+
+		var table yamlLootTable
+		if !v.decode(tableNode, &table) {
+			continue
+		}
+
+		v.checkUnknownKeys(tableNode, reflect.TypeOf(table), path)
+
+		for _, p := range table.check() {
+			line := nameNode.Line
+			if keyNode := findKey(tableNode, p.key); keyNode != nil {
+				line = keyNode.Line
+			}
+
+			v.add(line, path+"."+p.key, "%s", p.msg)
+		}
+
+		_, list := mapValue(tableNode, "items")
+		v.checkItemList(list, path+".items")
+	}
+}
+
+// Lists of item ids can only have items which exist in items.yaml
+func (v *validator) checkItemList(list *yaml.Node, path string) {
+	if list == nil || v.items == nil {
+		return
+	}
+
+	for _, itemNode := range list.Content {
+		if keyNode, _ := mapValue(v.items, itemNode.Value); keyNode == nil {
+			v.add(itemNode.Line, path, "unknown item '%s'", itemNode.Value)
+		}
+	}
+}
+
+// Lists of spell ids can only have spells which exist in spells.yaml
+func (v *validator) checkSpellList(list *yaml.Node, path string) {
+	if list == nil || v.spells == nil {
+		return
+	}
+
+	for _, spellNode := range list.Content {
+		if !v.spells[spellNode.Value] {
+			v.add(spellNode.Line, path, "unknown spell '%s'", spellNode.Value)
+		}
+	}
+}
+
+// Unidentified items need an appearance, there must be enough of each kind for every item using it
+func (v *validator) checkAppearances(root *yaml.Node) {
+	_, kinds := mapValue(root, "appearances")
+	used := make(map[string]int)
+
+	for i := 0; v.items != nil && i+1 < len(v.items.Content); i += 2 {
+		_, node := mapValue(v.items.Content[i+1], "appearance")
+		if node == nil {
+			continue
+		}
+
+		if keyNode, _ := mapValue(kinds, node.Value); keyNode == nil {
+			v.add(node.Line, "items."+v.items.Content[i].Value+".appearance", "appearance '%s' is not in the appearances section", node.Value)
+		}
+
+		used[node.Value]++
+	}
+
+	for i := 0; kinds != nil && i+1 < len(kinds.Content); i += 2 {
+		kindNode, looksNode := kinds.Content[i], kinds.Content[i+1]
+		path := "appearances." + kindNode.Value
+
+		var looks []appearance
+		if !v.decode(looksNode, &looks) {
+			continue
+		}
+
+		for n, lookNode := range looksNode.Content {
+			v.checkUnknownKeys(lookNode, reflect.TypeOf(appearance{}), path)
+			v.checkReferences(lookNode, path)
+
+			if looks[n].Name == "" {
+				v.add(lookNode.Line, path, "missing 'name'")
+			}
+		}
+
+		if used[kindNode.Value] > len(looks) {
+			v.add(kindNode.Line, path, "%d appearances for %d items, every item needs its own", len(looks), used[kindNode.Value])
+		}
+	}
+}
+
+// Launchers are no use unless some item is the ammunition they fire
+func (v *validator) checkAmmo() {
+	if v.items == nil {
+		return
+	}
+
+	ammo := make(map[string]bool)
+	for i := 1; i < len(v.items.Content); i += 2 {
+		if _, node := mapValue(v.items.Content[i], "ammo"); node != nil {
+			ammo[node.Value] = true
+		}
+	}
+
+	for i := 0; i+1 < len(v.items.Content); i += 2 {
+		if _, node := mapValue(v.items.Content[i+1], "fires"); node != nil && !ammo[node.Value] {
+			v.add(node.Line, "items."+v.items.Content[i].Value+".fires", "no item is '%s' ammunition", node.Value)
+		}
+	}
+}
+
+func (v *validator) loadSprites(file string) {
+	root := v.parse(file)
+	if root == nil {
+		return
+	}
+
+	var bank struct {
+		Sprites []struct {
+			ID string `yaml:"id"`
+		} `yaml:"sprites"`
+	}
+
+	if !v.decode(root, &bank) {
+		return
+	}
+
+	v.sprites = make(map[string]bool)
+	for _, s := range bank.Sprites {
+		v.sprites[s.ID] = true
+	}
+}
+
+func (v *validator) loadPalettes(file string) {
+	root := v.parse(file)
+	if root == nil {
+		return
+	}
+
+	var set struct {
+		Palettes map[string]struct {
+			Colours []string `yaml:"colours"`
+		} `yaml:"palettes"`
+	}
+
+	if !v.decode(root, &set) {
+		return
+	}
+
+	for _, p := range set.Palettes {
+		if v.paletteSize == 0 || len(p.Colours) < v.paletteSize {
+			v.paletteSize = len(p.Colours)
+		}
+	}
+}
+
+// Find the key & value nodes for a key in a mapping node
+func mapValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+// Find the key node for a dotted path of keys, e.g. effects.toHit
+func findKey(node *yaml.Node, path string) *yaml.Node {
+	var keyNode *yaml.Node
+	for _, key := range strings.Split(path, ".") {
+		keyNode, node = mapValue(node, key)
+		if keyNode == nil {
+			return nil
+		}
+	}
+
+	return keyNode
+}
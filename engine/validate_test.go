@@ -0,0 +1,189 @@
+package engine
+
+// ============================================================================
+// Tests for datafile validation
+// ============================================================================
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateRealDataFiles(t *testing.T) {
+	for _, err := range ValidateDataFiles("../assets") {
+		t.Error(err)
+	}
+}
+
+const badItems = `items:
+  sword:
+    name: sword
+    graphic: sword
+    colour: 99
+    equipLocation: hand
+    effects:
+      toHit: lots
+      attak: 1d6
+
+  potion:
+    name: potion
+    graphic: no_such_sprite
+    rarity: mythic
+    appearance: flask
+    onUseScript: |
+      player.SetHP(
+
+  bow:
+    name: bow
+    graphic: bow
+    equipLocation: missile
+    fires: bolt
+    range: 8
+    rangedDamage: 1d6
+    quantity: 2d4
+    twoHanded: true
+
+  wand:
+    name: wand
+    graphic: staff
+    spell: lightning
+
+lootTables:
+  level:
+    items: [sword, shield]
+
+  supplies:
+    chance: 120
+`
+
+const badCreatures = `creatures:
+  rat:
+    name: rat
+    graphic: rat
+    hp: 5
+    attack: lots
+    ai: [hunt, dance]
+    lootTable: nowhere
+    speeed: 10
+`
+
+const badClasses = `defaultClass: knight
+classes:
+  warrior:
+    name: Warrior
+    hp: 0
+    toHit: 70
+    speed: 10
+    fovDistance: 6
+    items: [sword, axe]
+    spells: [bolt, lightning]
+    mana: 5
+`
+
+const badSpells = `spells:
+  bolt:
+    name: Bolt
+    target: creature
+    mana: 2
+    damage: 2d
+  glow:
+    name: Glow
+    target: everyone
+`
+
+func TestValidateReportsProblems(t *testing.T) {
+	dir := t.TempDir()
+	copyFile := func(from, to string) {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, to), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "datafiles"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	copyFile("../assets/sprites.yaml", "sprites.yaml")
+	copyFile("../assets/palettes.yaml", "palettes.yaml")
+	for _, name := range []string{"furniture.yaml", "traps.yaml", "levels.yaml", "combat.yaml", "progression.yaml"} {
+		copyFile("../assets/datafiles/"+name, "datafiles/"+name)
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, "datafiles/items.yaml"), []byte(badItems), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "datafiles/creatures.yaml"), []byte(badCreatures), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "datafiles/classes.yaml"), []byte(badClasses), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "datafiles/spells.yaml"), []byte(badSpells), 0o644)
+
+	errors := ValidateDataFiles(dir)
+
+	expected := []struct {
+		file string
+		line int
+		key  string
+	}{
+		{"items.yaml", 5, "items.sword.colour"},
+		{"items.yaml", 6, "items.sword.equipLocation"},
+		{"items.yaml", 8, "items.sword.effects.toHit"},
+		{"items.yaml", 9, "items.sword.effects.attak"},
+		{"items.yaml", 13, "items.potion.graphic"},
+		{"items.yaml", 14, "items.potion.rarity"},
+		{"items.yaml", 15, "items.potion.appearance"},
+		{"items.yaml", 16, "items.potion.onUseScript"},
+		{"items.yaml", 23, "items.bow.fires"},
+		{"items.yaml", 26, "items.bow.quantity"},
+		{"items.yaml", 27, "items.bow.twoHanded"},
+		{"items.yaml", 29, "items.wand.charges"},
+		{"items.yaml", 32, "items.wand.spell"},
+		{"items.yaml", 36, "lootTables.level.items"},
+		{"items.yaml", 39, "lootTables.supplies.chance"},
+		{"creatures.yaml", 6, "creatures.rat.attack"},
+		{"creatures.yaml", 7, "creatures.rat.ai"},
+		{"creatures.yaml", 8, "creatures.rat.lootTable"},
+		{"creatures.yaml", 9, "creatures.rat.speeed"},
+		{"classes.yaml", 1, "defaultClass"},
+		{"classes.yaml", 5, "classes.warrior.hp"},
+		{"classes.yaml", 9, "classes.warrior.items"},
+		{"classes.yaml", 10, "classes.warrior.spells"},
+		{"spells.yaml", 2, "spells.bolt.range"},
+		{"spells.yaml", 6, "spells.bolt.damage"},
+		{"spells.yaml", 7, "spells.glow"},
+		{"spells.yaml", 9, "spells.glow.target"},
+	}
+
+	for _, exp := range expected {
+		found := false
+		for _, err := range errors {
+			if strings.HasSuffix(err.File, exp.file) && err.Line == exp.line && err.Key == exp.key {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("expected a problem with %s at %s:%d", exp.key, exp.file, exp.line)
+		}
+	}
+
+	if len(errors) != len(expected) {
+		for _, err := range errors {
+			t.Log(err)
+		}
+
+		t.Errorf("expected %d problems, got %d", len(expected), len(errors))
+	}
+
+	if _, err := newItemGenerator(filepath.Join(dir, "datafiles/items.yaml"), newRNG(1)); err == nil {
+		t.Errorf("loading bad items should fail")
+	}
+
+	if _, err := loadSpells(filepath.Join(dir, "datafiles/spells.yaml")); err == nil {
+		t.Errorf("loading bad spells should fail")
+	}
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
 	"math/rand/v2"
+	"os"
 	"roguelike/core"
 	"roguelike/engine"
+	"slices"
+	"strconv"
+	"strings"
 
 	"atomicgo.dev/keyboard"
 	"atomicgo.dev/keyboard/keys"
@@ -13,6 +21,14 @@ import (
 var basePath string = "./"
 var game *engine.Game
 var viewPort core.Rect
+var facing core.Direction // Last direction moved, used for furniture actions
+
+// Aiming at a creature to fire, throw, zap or cast at, the target is an index into the visible hostiles
+// At most one of the item to throw, wand to zap or spell to cast is set, none means fire the launcher
+var targeting bool
+var target int
+var throwItem, zapItem *engine.Item
+var spell *engine.Spell
 
 const (
 	VP_ROWS       = 16 // Number of rows of tiles in the viewport
@@ -21,12 +37,38 @@ const (
 )
 
 func main() {
-	seed := rand.Uint64()
-	game = engine.NewGame(basePath+"assets/datafiles", seed, 6)
+	var seed uint64
+	var recordFile, replayFile, class string
+	var headless bool
+	flag.Uint64Var(&seed, "seed", 0, "Seed for the game world")
+	flag.StringVar(&recordFile, "record", "", "Save a replay of the game to this file when it ends")
+	flag.StringVar(&replayFile, "replay", "", "Play back a replay file")
+	flag.StringVar(&class, "class", "", "Class to play as, e.g. warrior, rogue or mage")
+	flag.BoolVar(&headless, "headless", false, "Run the replay with no display and check its final state hash")
+	flag.Parse()
+
+	if replayFile != "" {
+		playReplay(replayFile, headless)
+		return
+	}
+
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	if class != "" && !classExists(class) {
+		log.Fatalf("Unknown class '%s'", class)
+	}
+
+	game = engine.NewGame(basePath+"assets/datafiles", seed, class)
 	viewPort = game.GetViewPort(VP_COLS, VP_ROWS)
+	if recordFile != "" {
+		defer saveReplay(recordFile)
+	}
+
 	area, _ := pterm.DefaultArea.WithFullscreen().Start()
 
-	drawScreen(area)
+	drawScreen(area, statusLine())
 
 	// Listen for key presses loops like a game loop
 	_ = keyboard.Listen(func(key keys.Key) (stop bool, err error) {
@@ -34,30 +76,252 @@ func main() {
 			return true, nil // Stop listener by returning true on Ctrl+C
 		}
 
-		var move *engine.MoveAction
-		switch key.Code {
-		case keys.Right:
-			move = engine.NewMoveAction(core.DirEast)
-		case keys.Left:
-			move = engine.NewMoveAction(core.DirWest)
-		case keys.Up:
-			move = engine.NewMoveAction(core.DirNorth)
-		case keys.Down:
-			move = engine.NewMoveAction(core.DirSouth)
+		var action engine.Action
+		if targeting {
+			action = updateTargeting(key)
+		} else {
+			action = keyAction(key)
+		}
+
+		if move, ok := action.(*engine.MoveAction); ok {
+			facing = move.Direction()
 		}
 
-		if move != nil {
-			move.Execute(*game)
+		if action != nil {
+			game.ProcessAction(action)
 			viewPort = game.GetViewPort(VP_COLS, VP_ROWS)
 		}
 
-		drawScreen(area)
+		if targeting {
+			drawScreen(area, targetLine())
+		} else {
+			drawScreen(area, statusLine())
+		}
+
+		// Game over, so stop listening for keys
+		if game.IsOver() {
+			p := game.Player()
+			if err := game.Err(); err != nil {
+				pterm.Error.Printfln("The game had to stop after %d turns: %s", game.Turn(), err)
+				return true, nil
+			}
+
+			pterm.Error.Printfln("%s was killed by %s after %d turns", p.Name(), p.KilledBy(), game.Turn())
+			return true, nil
+		}
 
 		return false, nil
 	})
 }
 
-func drawScreen(area *pterm.AreaPrinter) {
+// The action for a key pressed while playing, nil if it doesn't do anything
+func keyAction(key keys.Key) engine.Action {
+	var action engine.Action
+	switch key.Code {
+	case keys.Right:
+		action = engine.NewMoveAction(core.DirEast)
+	case keys.Left:
+		action = engine.NewMoveAction(core.DirWest)
+	case keys.Up:
+		action = engine.NewMoveAction(core.DirNorth)
+	case keys.Down:
+		action = engine.NewMoveAction(core.DirSouth)
+	case keys.RuneKey:
+		switch key.String() {
+		case "<":
+			action = engine.NewAscendAction()
+		case ">":
+			action = engine.NewDescendAction()
+		case "e":
+			action = engine.NewInteractAction(facing)
+		case "c":
+			action = engine.NewCloseAction(facing)
+		case "b":
+			action = engine.NewBashAction(facing)
+		case "s":
+			action = engine.NewSearchAction()
+		case "f":
+			action = startTargeting(nil, nil, nil)
+		case "t":
+			if item := throwable(); item != nil {
+				action = startTargeting(item, nil, nil)
+			}
+		case "z":
+			if wand := firstWand(); wand != nil {
+				action = startTargeting(nil, wand, nil)
+			}
+		case "m":
+			if s := castable(); s != nil {
+				action = startTargeting(nil, nil, s)
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Pick one of the perks listed under the map
+			perks := game.AvailablePerks()
+			if n, _ := strconv.Atoi(key.String()); game.Player().PendingPerks() > 0 && n <= len(perks) {
+				action = engine.NewPerkAction(perks[n-1])
+			}
+		}
+	}
+
+	return action
+}
+
+func classExists(id string) bool {
+	classes, err := engine.LoadClasses(basePath + "assets/datafiles")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, c := range classes {
+		if c.ID() == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Start aiming at the nearest hostile creature, with nothing to aim at the action is
+// tried anyway so the game explains why, with no item or spell the launcher is fired
+// Spells & wands which don't need aiming are cast straight away
+func startTargeting(throw, zap *engine.Item, cast *engine.Spell) engine.Action {
+	throwItem, zapItem, spell = throw, zap, cast
+
+	hostiles := game.VisibleHostiles()
+	if len(hostiles) == 0 || (throw == nil && zap == nil && cast == nil && game.Player().FireRange() == 0) ||
+		(zap != nil && !game.WandSpell(zap).NeedsTarget()) || (cast != nil && !cast.NeedsTarget()) {
+		return aimedAction(len(hostiles))
+	}
+
+	targeting, target = true, 0
+	return nil
+}
+
+// Tab cycles through the targets, f, t, z, m or enter fires and escape stops aiming
+func updateTargeting(key keys.Key) engine.Action {
+	targets := game.VisibleHostiles()
+
+	switch {
+	case key.Code == keys.Escape:
+		targeting = false
+	case key.Code == keys.Tab || key.String() == "n":
+		target = (target + 1) % len(targets)
+	case key.Code == keys.Enter || slices.Contains([]string{"f", "t", "z", "m"}, key.String()):
+		targeting = false
+		return aimedAction(target)
+	}
+
+	return nil
+}
+
+// The action for whatever is being aimed at one of the visible hostiles, past the end of them is no target
+func aimedAction(index int) engine.Action {
+	target := append(game.VisibleHostiles(), nil)[index]
+
+	switch {
+	case throwItem != nil:
+		return engine.NewThrowAction(throwItem, target)
+	case zapItem != nil:
+		return engine.NewZapAction(zapItem, target)
+	case spell != nil:
+		return engine.NewCastAction(spell, target)
+	}
+
+	return engine.NewFireAction(target)
+}
+
+// The first item in the backpack which can be thrown
+func throwable() *engine.Item {
+	for _, item := range game.Player().Inventory() {
+		if item.Throwable() && !item.IsEquipped() {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// The first wand in the backpack with charges left
+func firstWand() *engine.Item {
+	for _, item := range game.Player().Inventory() {
+		if item.IsWand() && item.Charges() > 0 {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// The first spell the player knows which they have the mana to cast
+func castable() *engine.Spell {
+	for _, s := range game.KnownSpells() {
+		if s.Mana() <= game.Player().Mana() {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// Shown instead of the status line while aiming
+func targetLine() string {
+	targets := game.VisibleHostiles()
+	verb := "Fire"
+	switch {
+	case throwItem != nil:
+		verb = "Throw " + throwItem.Name()
+	case zapItem != nil:
+		verb = "Zap " + zapItem.Name()
+	case spell != nil:
+		verb = "Cast " + spell.Name()
+	}
+
+	return pterm.Yellow(fmt.Sprintf("%s at %s (%d/%d)", verb, targets[target].Name(), target+1, len(targets))) +
+		"  tab: next  enter: " + strings.ToLower(verb) + "  esc: cancel"
+}
+
+// Save everything the player did as a replay file
+func saveReplay(file string) {
+	data, err := json.MarshalIndent(game.Replay(), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	pterm.Info.Printfln("Replay saved to %s", file)
+}
+
+// Player stats & statuses, shown under the map, with any perks waiting to be picked
+func statusLine() string {
+	p := game.Player()
+	line := fmt.Sprintf("%s  L%d  Lvl %d  HP %d/%d  XP %d/%d", p.Name(), game.Depth(), p.Level(), p.HP(), p.MaxHP(), p.Exp(), game.ExpForNextLevel())
+	if p.MaxMana() > 0 {
+		line += fmt.Sprintf("  MP %d/%d", p.Mana(), p.MaxMana())
+	}
+
+	if statuses := p.Statuses(); len(statuses) > 0 {
+		line += "  " + pterm.Magenta(strings.Join(statuses, " "))
+	}
+
+	if burden := p.Encumbrance(); burden != "" {
+		line += "  " + pterm.Yellow(burden)
+	}
+
+	if p.PendingPerks() > 0 {
+		line += "\n" + pterm.Yellow("Pick a perk:")
+		for i, perk := range game.AvailablePerks() {
+			line += fmt.Sprintf("  %d) %s %s", i+1, perk.Name(), perk.DescribeEffects())
+		}
+	}
+
+	return line
+}
+
+// Draw the map, with an optional footer line under it
+func drawScreen(area *pterm.AreaPrinter, footer string) {
 	gameMap := game.Map()
 	p := game.Player()
 
@@ -97,11 +361,58 @@ func drawScreen(area *pterm.AreaPrinter) {
 					symColor = pterm.FgGreen
 				}
 
+				if appear.Graphic == "stairs_up" {
+					symbol = "<"
+				}
+
+				if appear.Graphic == "stairs_down" {
+					symbol = ">"
+				}
+
+				switch appear.Graphic {
+				case "door":
+					symbol = "+"
+					symColor = pterm.FgYellow
+				case "door_open":
+					symbol = "'"
+					symColor = pterm.FgYellow
+				case "barrel", "crate":
+					symbol = "="
+					symColor = pterm.FgYellow
+				case "statue":
+					symbol = "&"
+				case "trap":
+					symbol = "^"
+					symColor = pterm.FgMagenta
+				case "bow":
+					symbol = "}"
+					symColor = pterm.FgYellow
+				case "arrow":
+					symbol = "/"
+				case "staff":
+					symbol = "\\"
+					symColor = pterm.FgCyan
+				case "scroll":
+					symbol = "?"
+					symColor = pterm.FgCyan
+				}
+
 				if appear.Graphic == "sword" {
 					symbol = "!"
 					symColor = pterm.FgRed
 				}
 
+				// Creatures are shown using the first letter of their name
+				if c := tile.Creature(); c != nil && appear.InFOV {
+					symbol = c.Name()[:1]
+					symColor = pterm.FgLightRed
+
+					if targeting && c == game.VisibleHostiles()[target] {
+						screen += pterm.NewStyle(pterm.BgLightRed, pterm.FgBlack).Sprint(symbol)
+						continue
+					}
+				}
+
 				if appear.InFOV {
 					screen += symColor.Sprint(symbol)
 				} else {
@@ -113,5 +424,5 @@ func drawScreen(area *pterm.AreaPrinter) {
 	}
 
 	// Update the area with the current time.
-	area.Update(screen)
+	area.Update(screen + footer)
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"roguelike/engine"
+	"sync"
+	"time"
+
+	"atomicgo.dev/keyboard"
+	"atomicgo.dev/keyboard/keys"
+	"github.com/pterm/pterm"
+)
+
+// Delays between replay steps, the speed keys move through these
+var replaySpeeds = []time.Duration{800 * time.Millisecond, 400 * time.Millisecond, 200 * time.Millisecond, 100 * time.Millisecond, 25 * time.Millisecond}
+
+// Play back a replay file, with no display when headless
+func playReplay(file string, headless bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		pterm.Fatal.Println(err)
+	}
+
+	replay, err := engine.LoadReplay(basePath+"assets/datafiles", data)
+	if err != nil {
+		pterm.Fatal.Println(err)
+	}
+
+	replayer := engine.NewReplayer(basePath+"assets/datafiles", replay)
+	game = replayer.Game()
+
+	if headless {
+		if err := replayer.Run(); err != nil {
+			pterm.Error.Println(err)
+			os.Exit(1)
+		}
+
+		steps, _ := replayer.Progress()
+		pterm.Success.Printfln("Replay of %d steps finished OK, state hash %s", steps, game.StateHash())
+		return
+	}
+
+	viewPort = game.GetViewPort(VP_COLS, VP_ROWS)
+	area, _ := pterm.DefaultArea.WithFullscreen().Start()
+
+	var lock sync.Mutex
+	paused := false
+	speed := 2
+
+	// Draw the game with a line showing the replay controls & progress
+	draw := func() {
+		done, total := replayer.Progress()
+		state := "playing"
+		if paused {
+			state = "paused"
+		}
+
+		drawScreen(area, fmt.Sprintf("Replay %d/%d %s  [space] pause [n] step [+/-] speed %d [ctrl+c] quit", done, total, state, speed+1))
+	}
+
+	// Play the next step, returns false when the replay is over
+	step := func() bool {
+		if err := replayer.Step(); err != nil {
+			pterm.Error.Println(err)
+			return false
+		}
+
+		viewPort = game.GetViewPort(VP_COLS, VP_ROWS)
+		draw()
+
+		return !replayer.Done() && !game.IsOver()
+	}
+
+	go func() {
+		for {
+			lock.Lock()
+			delay := replaySpeeds[speed]
+			if !paused && !step() {
+				lock.Unlock()
+				return
+			}
+			lock.Unlock()
+
+			time.Sleep(delay)
+		}
+	}()
+
+	draw()
+	_ = keyboard.Listen(func(key keys.Key) (stop bool, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch key.Code {
+		case keys.CtrlC:
+			return true, nil
+		case keys.Space:
+			paused = !paused
+		case keys.RuneKey:
+			switch key.String() {
+			case "n":
+				if paused {
+					step()
+				}
+			case "+", "=":
+				speed = min(speed+1, len(replaySpeeds)-1)
+			case "-":
+				speed = max(speed-1, 0)
+			}
+		}
+
+		draw()
+		return false, nil
+	})
+}
@@ -16,21 +16,47 @@ const (
 	Select
 	Save
 	Info
+	Wait
+	Ascend
+	Descend
+	Record
+	Interact
+	Close
+	Bash
+	Search
+	Character
+	Fire
+	Throw
+	NextTarget
+	Spellbook
 )
 
 // TODO: Move this to some sort of config file
 var controls = map[control][]ebiten.Key{
-	Up:        {ebiten.KeyW, ebiten.KeyUp},
-	Down:      {ebiten.KeyS, ebiten.KeyDown},
-	Left:      {ebiten.KeyA, ebiten.KeyLeft},
-	Right:     {ebiten.KeyD, ebiten.KeyRight},
-	Inventory: {ebiten.KeyI},
-	Drop:      {ebiten.KeyD},
-	Get:       {ebiten.KeyG},
-	Escape:    {ebiten.KeyEscape},
-	Select:    {ebiten.KeyEnter, ebiten.KeySpace},
-	Save:      {ebiten.KeyP},
-	Info:      {ebiten.KeyL},
+	Up:         {ebiten.KeyW, ebiten.KeyUp},
+	Down:       {ebiten.KeyS, ebiten.KeyDown},
+	Left:       {ebiten.KeyA, ebiten.KeyLeft},
+	Right:      {ebiten.KeyD, ebiten.KeyRight},
+	Inventory:  {ebiten.KeyI},
+	Drop:       {ebiten.KeyD},
+	Get:        {ebiten.KeyG},
+	Escape:     {ebiten.KeyEscape},
+	Select:     {ebiten.KeyEnter, ebiten.KeySpace},
+	Save:       {ebiten.KeyP},
+	Info:       {ebiten.KeyL},
+	Wait:       {ebiten.KeyZ},
+	Ascend:     {ebiten.KeyComma},
+	Descend:    {ebiten.KeyPeriod},
+	Record:     {ebiten.KeyR},
+	Interact:   {ebiten.KeyE},
+	Close:      {ebiten.KeyC},
+	Bash:       {ebiten.KeyB},
+	Search:     {ebiten.KeyF},
+	Character:  {ebiten.KeyX},
+	Fire:       {ebiten.KeyT},
+	Throw:      {ebiten.KeyT},
+	NextTarget: {ebiten.KeyTab},
+	Spellbook:  {ebiten.KeyM},
 }
 
 func (c control) Keys() []ebiten.Key {
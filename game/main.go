@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"image"
@@ -38,13 +39,19 @@ const (
 	PAL_INDEX_PLAYER = 10       // Colour of the player
 	VP_ROWS          = 17       // Number of rows of tiles in the viewport, +1 for status bar
 	VP_COLS          = 26       // Number of columns of tiles in the viewport
-	VIEW_DIST        = 6        // View distance in tiles
 	MAX_EVENT_AGE    = 7        // Events older than this are removed from the display
 	MAX_EVENTS       = 5        // Max number of events to display
+	ACTION_DELAY     = 6        // Frames to wait between player actions, stops held keys repeating too fast
 	INITIAL_SCALE    = 4        // When the window is first opened, only applies to non-web builds
 	ASSETS_DIR       = "assets" // Directory where all the game assets are stored
 )
 
+// Where the game and replays are saved, in the working directory
+const (
+	SAVE_FILE   = "savegame.json"
+	REPLAY_FILE = "replay.json"
+)
+
 // gameState is an enum for the different global states the game can be in
 type gameState int
 
@@ -52,6 +59,10 @@ const (
 	gameStateTitle     gameState = iota // Title screen
 	gameStatePlaying                    // Playing the game
 	gameStateInventory                  // Viewing the inventory
+	gameStateCharacter                  // Viewing the character sheet
+	gameStateSpellbook                  // Viewing the spells the player knows
+	gameStateGameOver                   // Player has died
+	gameStateReplay                     // Playing back a replay
 )
 
 // GameStateHander is an interface for handlers for each game state
@@ -85,6 +96,7 @@ type EbitenGame struct {
 	events   []*engine.GameEvent
 	eventLog []string
 	seed     uint64
+	class    string // ID of the class picked on the title screen, empty for the default
 
 	frameCount int64
 	flashCount int
@@ -134,7 +146,9 @@ func (g *EbitenGame) Layout(outsideWidth, outsideHeight int) (screenWidth, scree
 }
 
 func (g *EbitenGame) StartNewGame() {
-	g.game = engine.NewGame(basePath+"assets/datafiles", g.seed, VIEW_DIST, g.EventListener)
+	g.events = nil
+	g.eventLog = nil
+	g.game = engine.NewGame(basePath+"assets/datafiles", g.seed, g.class, g.EventListener)
 	g.viewPort = g.game.GetViewPort(VP_COLS, VP_ROWS)
 
 	// Debug only - dump the map to a PNG file
@@ -143,6 +157,63 @@ func (g *EbitenGame) StartNewGame() {
 	g.state = gameStatePlaying
 }
 
+// Save the current game to SAVE_FILE
+func (g *EbitenGame) SaveGame() error {
+	data, err := g.game.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(SAVE_FILE, data, 0644)
+}
+
+// Load the game saved in SAVE_FILE and carry on playing it
+func (g *EbitenGame) LoadSavedGame() error {
+	data, err := os.ReadFile(SAVE_FILE)
+	if err != nil {
+		return err
+	}
+
+	g.events = nil
+	g.eventLog = nil
+	game, err := engine.LoadGame(basePath+"assets/datafiles", data, g.EventListener)
+	if err != nil {
+		return err
+	}
+
+	g.game = game
+	g.seed = game.Seed()
+	g.viewPort = g.game.GetViewPort(VP_COLS, VP_ROWS)
+	g.state = gameStatePlaying
+
+	return nil
+}
+
+// Save everything the player has done as a replay in REPLAY_FILE
+func (g *EbitenGame) SaveReplay() error {
+	data, err := json.MarshalIndent(g.game.Replay(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(REPLAY_FILE, data, 0644)
+}
+
+// Age the events shown on screen, and remove old ones
+func (g *EbitenGame) ageEvents() {
+	for _, e := range g.events {
+		e.Age++
+	}
+
+	// Remove old events in a separate loop
+	for i := 0; i < len(g.events); i++ {
+		e := g.events[i]
+		if e.Age > MAX_EVENT_AGE {
+			g.events = append(g.events[:i], g.events[i+1:]...)
+		}
+	}
+}
+
 func (g *EbitenGame) EventListener(e engine.GameEvent) {
 	var lastEvent *engine.GameEvent = nil
 	if len(g.events) > 0 {
@@ -159,10 +230,19 @@ func (g *EbitenGame) EventListener(e engine.GameEvent) {
 		}
 	}
 
-	if e.Type() == engine.EventCreatureKilled {
+	if e.Type() == engine.EventCreatureKilled || e.Type() == engine.EventPlayerHit {
 		g.sfxPlayer.Play("hurt")
 	}
 
+	if e.Type() == engine.EventTrapTriggered {
+		g.sfxPlayer.Play("hurt")
+		g.flashCount = 2
+	}
+
+	if e.Type() == engine.EventPlayerDied {
+		g.flashCount = 4
+	}
+
 	if e.Type() == engine.EventItemPickup {
 		g.sfxPlayer.Play("pickup")
 	}
@@ -178,9 +258,11 @@ func main() {
 	var seed uint64
 	var disableAudio bool
 	var quickStart bool
+	var replayFile string
 	flag.Uint64Var(&seed, "seed", 0, "Seed for the game world")
 	flag.BoolVar(&disableAudio, "noaudio", false, "Disable audio")
 	flag.BoolVar(&quickStart, "quickstart", false, "Skip the title screen")
+	flag.StringVar(&replayFile, "replay", "", "Play back a replay file")
 	flag.Parse()
 
 	// Window icon uses embedded bytes
@@ -245,6 +327,28 @@ func main() {
 		gameStateInventory: &InventoryState{
 			EbitenGame: ebitenGame,
 		},
+
+		gameStateCharacter: &CharacterState{
+			EbitenGame: ebitenGame,
+		},
+
+		gameStateSpellbook: &SpellbookState{
+			EbitenGame: ebitenGame,
+		},
+
+		gameStateGameOver: &GameOverState{
+			EbitenGame: ebitenGame,
+		},
+
+		gameStateReplay: &ReplayState{
+			EbitenGame: ebitenGame,
+		},
+	}
+
+	if replayFile != "" {
+		if err := ebitenGame.handlers[gameStateReplay].(*ReplayState).Start(replayFile); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// Finally start the ebiten game loop
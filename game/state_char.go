@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"roguelike/core"
+	"roguelike/engine"
+	"roguelike/game/controls"
+	"roguelike/game/graphics"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Width of the XP progress bar, in characters
+const xpBarWidth = 20
+
+type CharacterState struct {
+	// Neatly encapsulate the state of the game
+	*EbitenGame
+
+	// Internal vars for this state
+	cursor int
+	perks  []*engine.Perk
+}
+
+func (s *CharacterState) Init() {
+	s.cursor = 0
+	s.perks = s.game.AvailablePerks()
+}
+
+func (s *CharacterState) PassEvent(e engine.GameEvent) {
+}
+
+func (s *CharacterState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
+	choosing := s.game.Player().PendingPerks() > 0 && len(s.perks) > 0
+
+	for _, key := range tappedKeys {
+		if controls.Escape.IsKey(key) || controls.Character.IsKey(key) {
+			s.state = gameStatePlaying
+			return
+		}
+
+		if !choosing {
+			continue
+		}
+
+		if controls.Up.IsKey(key) && s.cursor > 0 {
+			s.cursor--
+		}
+
+		if controls.Down.IsKey(key) && s.cursor < len(s.perks)-1 {
+			s.cursor++
+		}
+
+		if controls.Select.IsKey(key) {
+			if result := s.game.ProcessAction(engine.NewPerkAction(s.perks[s.cursor])); result.Success {
+				s.Init()
+				return
+			}
+		}
+	}
+}
+
+func (s *CharacterState) Draw(screen *ebiten.Image) {
+	p := s.game.Player()
+
+	graphics.BgColour = graphics.ColourInv
+	graphics.FgColour = graphics.ColourWhite
+
+	graphics.DrawBox(screen, 0, 1, VP_COLS-2, 2)
+	graphics.DrawBox(screen, 2, 1, VP_COLS-2, VP_ROWS-2)
+
+	graphics.BgColour = graphics.ColourTrans
+	graphics.DrawTextRow(screen, fmt.Sprintf("   %s the %s, level %d", p.Name(), p.ClassName(), p.Level()), 1)
+
+	// Progress towards the next level, as a bar
+	next := s.game.ExpForNextLevel()
+	filled := core.MinInt(p.Exp()*xpBarWidth/max(next, 1), xpBarWidth)
+	bar := strings.Repeat("■", filled) + strings.Repeat("□", xpBarWidth-filled)
+
+	lines := []string{
+		fmt.Sprintf("XP:      %d / %d  %s", p.Exp(), next, bar),
+		fmt.Sprintf("HP:      %d / %d", p.HP(), p.MaxHP()),
+		fmt.Sprintf("To hit:  %d%%", p.StatHitChance()),
+		fmt.Sprintf("Attack:  %s+%d", p.StatAttackRoll().String(), p.StatBaseDamage()),
+		fmt.Sprintf("Defence: %d", p.StatDefence()),
+		fmt.Sprintf("Speed:   %d", p.Speed()),
+	}
+
+	if statuses := p.Statuses(); len(statuses) > 0 {
+		lines = append(lines, "Status:  "+strings.Join(statuses, " "))
+	}
+
+	lines = append(lines, "")
+	for _, perk := range s.game.ChosenPerks() {
+		lines = append(lines, fmt.Sprintf("Perk:    %s %s", perk.Name(), perk.DescribeEffects()))
+	}
+
+	for i, line := range lines {
+		graphics.DrawTextRow(screen, "     "+line, i+3)
+	}
+
+	if p.PendingPerks() == 0 || len(s.perks) == 0 {
+		return
+	}
+
+	row := len(lines) + 4
+	graphics.DrawTextRow(screen, fmt.Sprintf("     Choose a perk (%d to pick)", p.PendingPerks()), row)
+
+	for i, perk := range s.perks {
+		graphics.FgColour = graphics.ColourWhite
+		graphics.DrawTextRow(screen, fmt.Sprintf("       %s %s- %s", perk.Name(), perk.DescribeEffects(), perk.Description()), row+i+2)
+	}
+
+	graphics.FgColour = graphics.ColourCursor
+	graphics.DrawTextRow(screen, "   ⌦", row+s.cursor+2)
+}
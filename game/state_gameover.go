@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"roguelike/core"
+	"roguelike/engine"
+	"roguelike/game/controls"
+	"roguelike/game/graphics"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type GameOverState struct {
+	// Neatly encapsulate the state of the game
+	*EbitenGame
+}
+
+func (s *GameOverState) Init() {
+	if err := s.game.Err(); err != nil {
+		log.Printf("Game stopped: %s", err)
+	}
+}
+
+func (s *GameOverState) PassEvent(e engine.GameEvent) {
+}
+
+func (s *GameOverState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
+	for _, key := range tappedKeys {
+		if controls.Select.IsKey(key) {
+			s.restart()
+		}
+
+		if controls.Escape.IsKey(key) {
+			s.game = nil
+			s.state = gameStateTitle
+			s.handlers[s.state].Init()
+		}
+	}
+
+	if s.DidTapIn(core.NewRect(0, 0, s.scrWidth, s.scrHeight)) {
+		s.restart()
+	}
+}
+
+// Start a new game with a fresh seed, so it's a new world
+func (s *GameOverState) restart() {
+	s.seed = rand.Uint64N(100000000)
+	log.Printf("Starting new game with seed: %d", s.seed)
+	s.StartNewGame()
+}
+
+func (s *GameOverState) Draw(screen *ebiten.Image) {
+	p := s.game.Player()
+
+	graphics.FgColour = graphics.ColourWhite
+	graphics.BgColour = graphics.ColourStatusRed
+	graphics.DrawBox(screen, 2, 1, VP_COLS-2, VP_ROWS-4)
+
+	graphics.BgColour = graphics.ColourTrans
+	if s.game.Err() != nil {
+		graphics.DrawTextRow(screen, fmt.Sprintf("%sGAME STOPPED", core.MakeStr(19, " ")), 4)
+		graphics.DrawTextRow(screen, "   A script in the datafiles went wrong", 6)
+	} else {
+		graphics.DrawTextRow(screen, fmt.Sprintf("%sYOU HAVE DIED", core.MakeStr(19, " ")), 4)
+		graphics.DrawTextRow(screen, fmt.Sprintf("   %s was killed by %s", p.Name(), p.KilledBy()), 6)
+	}
+
+	graphics.DrawTextRow(screen, fmt.Sprintf("   on level %d, after %d turns", s.game.Map().Depth(), s.game.Turn()), 7)
+	graphics.DrawTextRow(screen, fmt.Sprintf("   Experience: %d", p.Exp()), 9)
+
+	graphics.FgColour = graphics.ColourCursor
+	graphics.DrawTextRow(screen, "   Press enter for a new game", 12)
+	graphics.DrawTextRow(screen, "   Press escape to return to the title", 13)
+}
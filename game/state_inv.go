@@ -19,6 +19,8 @@ type InventoryState struct {
 	inv            []*engine.Item
 	item           *engine.Item
 	describingItem bool
+	pickingSlot    bool // Asking which slot to equip an item in
+	slotCursor     int
 }
 
 func (s *InventoryState) Init() {
@@ -31,6 +33,7 @@ func (s *InventoryState) Init() {
 	}
 
 	s.describingItem = false
+	s.pickingSlot = false
 }
 
 func (s *InventoryState) PassEvent(e engine.GameEvent) {
@@ -38,6 +41,11 @@ func (s *InventoryState) PassEvent(e engine.GameEvent) {
 
 func (s *InventoryState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 	for _, key := range tappedKeys {
+		if s.pickingSlot {
+			s.updatePickingSlot(key)
+			continue
+		}
+
 		if controls.Escape.IsKey(key) || controls.Inventory.IsKey(key) {
 			s.state = gameStatePlaying
 		}
@@ -68,9 +76,14 @@ func (s *InventoryState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key)
 			continue
 		}
 
-		if controls.Drop.IsKey(key) {
-			a := engine.NewDropAction(s.item)
-			_ = a.Execute(*s.game)
+		// Drop one from a stack, or the whole stack with shift
+		if controls.Drop.IsKey(key) && s.item != nil {
+			a := engine.NewDropCountAction(s.item, 1)
+			if ebiten.IsKeyPressed(ebiten.KeyShift) {
+				a = engine.NewDropAction(s.item)
+			}
+
+			_ = s.game.ProcessAction(a)
 			s.state = gameStatePlaying
 		}
 
@@ -78,6 +91,37 @@ func (s *InventoryState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key)
 			s.describingItem = true
 		}
 
+		// Go back to the map to pick what to throw the item at
+		if controls.Throw.IsKey(key) && s.item != nil && s.item.Throwable() && !s.item.IsEquipped() {
+			s.state = gameStatePlaying
+			play := s.handlers[s.state].(*PlayingState)
+			if action := play.startTargeting(aim{throwItem: s.item}); action != nil {
+				play.doAction(action)
+			}
+		}
+
+		// Wands which need aiming go back to the map too
+		if controls.Select.IsKey(key) && s.item != nil && s.item.IsWand() {
+			s.state = gameStatePlaying
+			play := s.handlers[s.state].(*PlayingState)
+			action := engine.Action(engine.NewZapAction(s.item, nil))
+			if spell := s.game.WandSpell(s.item); spell != nil && spell.NeedsTarget() {
+				action = play.startTargeting(aim{zapItem: s.item})
+			}
+
+			if action != nil {
+				play.doAction(action)
+			}
+
+			continue
+		}
+
+		// Items which could go in more than one slot ask which one first
+		if controls.Select.IsKey(key) && s.item != nil && !s.item.IsEquipped() && len(s.game.Player().EquipSlotsFor(s.item)) > 1 {
+			s.pickingSlot, s.slotCursor = true, 0
+			continue
+		}
+
 		if controls.Select.IsKey(key) {
 			var a engine.Action
 			if s.item.IsEquipment() {
@@ -90,14 +134,39 @@ func (s *InventoryState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key)
 				continue
 			}
 
-			result := a.Execute(*s.game)
-			if result.Success {
-				s.state = gameStatePlaying
-			}
+			s.doAction(a)
 		}
 	}
 }
 
+// Up & down pick a slot for the item, select equips it there & escape goes back to the list
+func (s *InventoryState) updatePickingSlot(key ebiten.Key) {
+	slots := s.game.Player().EquipSlotsFor(s.item)
+
+	switch {
+	case controls.Escape.IsKey(key):
+		s.pickingSlot = false
+	case controls.Up.IsKey(key) && s.slotCursor > 0:
+		s.slotCursor--
+	case controls.Down.IsKey(key) && s.slotCursor < len(slots)-1:
+		s.slotCursor++
+	case controls.Select.IsKey(key):
+		s.pickingSlot = false
+		s.doAction(engine.NewEquipSlotAction(s.item, slots[s.slotCursor]))
+	}
+}
+
+// Carry out an action on the selected item, going back to the map if it worked
+func (s *InventoryState) doAction(a engine.Action) {
+	result := s.game.ProcessAction(a)
+	if s.game.IsOver() {
+		s.state = gameStateGameOver
+		s.handlers[s.state].Init()
+	} else if result.Success {
+		s.state = gameStatePlaying
+	}
+}
+
 func (s *InventoryState) Draw(screen *ebiten.Image) {
 	// Draw the inventory screen
 	graphics.BgColour = graphics.ColourInv
@@ -114,11 +183,30 @@ func (s *InventoryState) Draw(screen *ebiten.Image) {
 		graphics.DrawTextRow(screen, fmt.Sprintf("   %d/%d %s", s.cursor+1, countCarried, s.item.NameTitle()), 1)
 		text := "Type: " + s.item.ItemType() + "\n"
 		text += "Rarity: " + s.item.Rarity().String() + "\n"
+		text += fmt.Sprintf("Weight: %d", s.item.Weight())
+		if s.item.Quantity() > 1 {
+			text += fmt.Sprintf(" each, %d for all %d", s.item.StackWeight(), s.item.Quantity())
+		}
+
+		text += "\n"
 		if s.item.IsEquipment() {
-			text += "Equip Location: " + s.item.EquipLocation().String() + "\n"
+			text += "Equip Location: " + s.item.EquipLocation().String()
+			if s.item.TwoHanded() {
+				text += ", two-handed"
+			}
+
+			text += "\n"
 			text += "Effects: " + s.item.DescribeEffects() + "\n"
 		}
 
+		if ranged := s.item.DescribeRanged(); ranged != "" {
+			text += "Ranged: " + ranged + "\n"
+		}
+
+		if spell := s.game.WandSpell(s.item); spell != nil {
+			text += fmt.Sprintf("Casts: %s %s, %d charges\n", spell.Name(), spell.DescribeEffects(), s.item.Charges())
+		}
+
 		text += "\n" + s.item.Description()
 		graphics.DrawWrappedText(screen, text, 3, 3, VP_COLS+18)
 
@@ -126,8 +214,18 @@ func (s *InventoryState) Draw(screen *ebiten.Image) {
 		return
 	}
 
-	countMax := s.game.Player().BackpackSize()
-	graphics.DrawTextRow(screen, fmt.Sprintf("   Backpack (%d/%d)", countCarried, countMax), 1)
+	p := s.game.Player()
+	if s.pickingSlot {
+		s.drawPickingSlot(screen)
+		return
+	}
+
+	header := fmt.Sprintf("   Backpack (%d/%d)  Weight %d/%d", countCarried, p.BackpackSize(), p.CarriedWeight(), p.CarryCapacity())
+	if burden := p.Encumbrance(); burden != "" {
+		header += " " + burden
+	}
+
+	graphics.DrawTextRow(screen, header, 1)
 
 	// Draw the player's inventory item by item
 	for i, item := range s.inv {
@@ -141,20 +239,47 @@ func (s *InventoryState) Draw(screen *ebiten.Image) {
 		extra1 := ""
 		if item.IsEquipment() {
 			graphics.FgColour = color.RGBA{76, 147, 230, 255}
-			if s.game.Player().IsEquipped(item) {
-				extra1 = fmt.Sprintf("[%s]", item.EquipLocation())
+			if slot, ok := s.game.Player().EquippedSlot(item); ok {
+				extra1 = fmt.Sprintf("[%s]", slot)
 			}
 		}
 
 		extra2 := item.DescribeEffects()
+		if item.IsWand() {
+			extra2 = fmt.Sprintf("(%d charges)", item.Charges())
+		}
 
 		sprite := s.bank.Sprite(item.Graphic())
 		if sprite != nil {
 			sprite.Draw(screen, 30, (i+3)*12, graphics.FgColour, true, false, false)
 		}
 
-		graphics.DrawTextRow(screen, fmt.Sprintf("       %s %s %s", item.NameTitle(), extra1, extra2), i+3)
+		name := item.NameTitle()
+		if item.Quantity() > 1 {
+			name += fmt.Sprintf(" x%d", item.Quantity())
+		}
+
+		graphics.DrawTextRow(screen, fmt.Sprintf("       %s %s %s", name, extra1, extra2), i+3)
 		graphics.FgColour = graphics.ColourCursor
 		graphics.DrawTextRow(screen, "   ⌦", 3+s.cursor)
 	}
 }
+
+// List the slots the item could go in, with whatever is in them now
+func (s *InventoryState) drawPickingSlot(screen *ebiten.Image) {
+	p := s.game.Player()
+	graphics.DrawTextRow(screen, fmt.Sprintf("   Where should the %s go?", s.item.Name()), 1)
+
+	for i, slot := range p.EquipSlotsFor(s.item) {
+		current := "empty"
+		if in := p.EquippedIn(slot); in != nil {
+			current = in.Name()
+		}
+
+		graphics.FgColour = graphics.ColourWhite
+		graphics.DrawTextRow(screen, fmt.Sprintf("       %-12s %s", slot.String(), current), i+3)
+	}
+
+	graphics.FgColour = graphics.ColourCursor
+	graphics.DrawTextRow(screen, "   ⌦", 3+s.slotCursor)
+}
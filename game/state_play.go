@@ -12,6 +12,7 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type PlayingState struct {
@@ -21,7 +22,18 @@ type PlayingState struct {
 	// Internal vars for this state
 	pickUpItem  bool
 	playerLeft  bool
+	facing      core.Direction // Last direction moved, furniture here is used first
 	delayFrames int
+	targeting   bool // Picking a creature to fire, throw, zap or cast at
+	target      int  // Index of the creature being aimed at, in the visible hostiles
+	aiming      aim
+}
+
+// What the player is aiming when targeting, at most one is set, none means fire the launcher
+type aim struct {
+	throwItem *engine.Item
+	zapItem   *engine.Item
+	spell     *engine.Spell
 }
 
 func (s *PlayingState) Init() {
@@ -31,6 +43,15 @@ func (s *PlayingState) PassEvent(e engine.GameEvent) {
 }
 
 func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
+	// Aiming takes over the controls until something is fired or it's cancelled
+	if s.targeting {
+		if action := s.updateTargeting(tappedKeys); action != nil {
+			s.doAction(action)
+		}
+
+		return
+	}
+
 	var action engine.Action
 	player := s.game.Player()
 	currTile := player.Tile()
@@ -112,7 +133,7 @@ func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 				if index < len(items) {
 					item := items[index]
 					a := engine.NewPickupAction(&item)
-					res := a.Execute(*s.game)
+					res := s.game.ProcessAction(a)
 
 					// Last item picked up, switch out of pickup mode
 					if res.Success && len(items) == 1 {
@@ -125,11 +146,37 @@ func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 		}
 
 		if controls.Save.IsKey(key) {
-			b, err := s.game.MarshalJSON()
-			if err != nil {
-				fmt.Println(err)
+			if err := s.SaveGame(); err != nil {
+				log.Printf("Failed to save game: %s", err)
+				s.flashCount = 2
+			} else {
+				log.Printf("Game saved to %s", SAVE_FILE)
+			}
+		}
+
+		if controls.Record.IsKey(key) {
+			if err := s.SaveReplay(); err != nil {
+				log.Printf("Failed to save replay: %s", err)
+				s.flashCount = 2
+			} else {
+				log.Printf("Replay saved to %s", REPLAY_FILE)
 			}
-			log.Println(string(b))
+		}
+
+		if controls.Wait.IsKey(key) {
+			action = engine.NewWaitAction()
+		}
+
+		if controls.Search.IsKey(key) {
+			action = engine.NewSearchAction()
+		}
+
+		if controls.Ascend.IsKey(key) {
+			action = engine.NewAscendAction()
+		}
+
+		if controls.Descend.IsKey(key) {
+			action = engine.NewDescendAction()
 		}
 
 		if controls.Inventory.IsKey(key) {
@@ -137,16 +184,44 @@ func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 			s.handlers[s.state].Init()
 		}
 
-		if controls.Get.IsKey(key) {
-			appear := currTile.Appearance()
+		if controls.Character.IsKey(key) {
+			s.state = gameStateCharacter
+			s.handlers[s.state].Init()
+		}
+
+		if controls.Fire.IsKey(key) {
+			action = s.startTargeting(aim{})
+		}
+
+		if controls.Spellbook.IsKey(key) {
+			s.state = gameStateSpellbook
+			s.handlers[s.state].Init()
+		}
 
-			// If it's not floor, player must be on one or more items
-			// as we can't stand on walls or creatures
-			if appear.Graphic != "floor" {
+		if controls.Get.IsKey(key) {
+			if len(currTile.ListItems()) > 0 {
 				s.pickUpItem = true
 			}
 		}
 
+		if controls.Interact.IsKey(key) {
+			if dir, ok := s.furnitureDir(); ok {
+				action = engine.NewInteractAction(dir)
+			}
+		}
+
+		if controls.Close.IsKey(key) {
+			if dir, ok := s.furnitureDir(); ok {
+				action = engine.NewCloseAction(dir)
+			}
+		}
+
+		if controls.Bash.IsKey(key) {
+			if dir, ok := s.furnitureDir(); ok {
+				action = engine.NewBashAction(dir)
+			}
+		}
+
 		if controls.Up.IsKey(key) {
 			tappedDir = core.DirNorth
 		}
@@ -162,6 +237,7 @@ func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 	}
 
 	if tappedDir >= 0 {
+		s.facing = tappedDir
 		if tappedDir == core.DirWest {
 			s.playerLeft = true
 		}
@@ -179,45 +255,138 @@ func (s *PlayingState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 		}
 	}
 
-	// This stops the whole game from running too fast
+	// This stops held keys from repeating too fast, it doesn't affect the game clock
 	if s.delayFrames > 0 {
 		s.delayFrames--
 		return
 	}
 
 	if action != nil {
-		result := action.Execute(*s.game)
+		s.doAction(action)
+	}
+}
 
-		// TODO: This logic is probably not right long term
-		if !result.Success {
-			return
-		}
+// Carry out an action for the player, and move on to game over if it killed them
+func (s *PlayingState) doAction(action engine.Action) {
+	result := s.game.ProcessAction(action)
+	if s.game.IsOver() {
+		s.state = gameStateGameOver
+		s.handlers[s.state].Init()
+		return
+	}
+
+	if !result.Success {
+		return
+	}
+
+	s.delayFrames = ACTION_DELAY
+
+	// Play sound effects for walking
+	if _, ok := action.(*engine.MoveAction); ok {
+		s.sfxPlayer.Play("walk")
+	}
 
-		// We translate the energy spent into frames to delay the game
-		if result.EnergySpent > 0 {
-			s.delayFrames = result.EnergySpent
+	s.viewPort = s.game.GetViewPort(VP_COLS, VP_ROWS)
+	s.ageEvents()
+}
+
+// Start aiming at the nearest hostile creature, to fire, throw, zap or cast at it
+// If there's nothing to aim at or fire, the action is tried anyway so the game explains why
+func (s *PlayingState) startTargeting(a aim) engine.Action {
+	s.aiming = a
+	hostiles := s.game.VisibleHostiles()
+	if len(hostiles) == 0 || (a == aim{} && s.game.Player().FireRange() == 0) {
+		return s.aimedAction(len(hostiles))
+	}
+
+	s.targeting = true
+	s.target = 0
+	return nil
+}
+
+// Cycle through the creatures the player can see, and fire at the one picked
+func (s *PlayingState) updateTargeting(tappedKeys []ebiten.Key) engine.Action {
+	targets := s.game.VisibleHostiles()
+
+	for _, key := range tappedKeys {
+		if controls.Escape.IsKey(key) {
+			s.targeting = false
+			return nil
 		}
 
-		// Play sound effects for walking
-		if _, ok := action.(*engine.MoveAction); ok {
-			s.sfxPlayer.Play("walk")
+		if controls.NextTarget.IsKey(key) || controls.Right.IsKey(key) || controls.Down.IsKey(key) {
+			s.target = (s.target + 1) % len(targets)
 		}
 
-		s.viewPort = s.game.GetViewPort(VP_COLS, VP_ROWS)
+		if controls.Left.IsKey(key) || controls.Up.IsKey(key) {
+			s.target = (s.target + len(targets) - 1) % len(targets)
+		}
 
-		// Handle events and age them
-		for _, e := range s.events {
-			e.Age++
+		if controls.Select.IsKey(key) || controls.Fire.IsKey(key) {
+			s.targeting = false
+			return s.aimedAction(s.target)
 		}
+	}
 
-		// Remove old events in a separate loop
-		for i := 0; i < len(s.events); i++ {
-			e := s.events[i]
-			if e.Age > MAX_EVENT_AGE {
-				s.events = append(s.events[:i], s.events[i+1:]...)
-			}
+	return nil
+}
+
+// The action for whatever is being aimed, at one of the visible hostiles
+// An index past the end of them aims at nothing, which the game will refuse
+func (s *PlayingState) aimedAction(index int) engine.Action {
+	target := append(s.game.VisibleHostiles(), nil)[index]
+
+	switch {
+	case s.aiming.throwItem != nil:
+		return engine.NewThrowAction(s.aiming.throwItem, target)
+	case s.aiming.zapItem != nil:
+		return engine.NewZapAction(s.aiming.zapItem, target)
+	case s.aiming.spell != nil:
+		return engine.NewCastAction(s.aiming.spell, target)
+	}
+
+	return engine.NewFireAction(target)
+}
+
+// Range of whatever is being aimed
+func (s *PlayingState) targetRange() int {
+	switch {
+	case s.aiming.throwItem != nil:
+		return s.aiming.throwItem.Range()
+	case s.aiming.zapItem != nil:
+		return s.game.WandSpell(s.aiming.zapItem).Range()
+	case s.aiming.spell != nil:
+		return s.aiming.spell.Range()
+	}
+
+	return s.game.Player().FireRange()
+}
+
+// What's being done when targeting, e.g. Throw dagger
+func (s *PlayingState) targetVerb() string {
+	switch {
+	case s.aiming.throwItem != nil:
+		return "Throw " + s.aiming.throwItem.Name()
+	case s.aiming.zapItem != nil:
+		return "Zap " + s.aiming.zapItem.Name()
+	case s.aiming.spell != nil:
+		return "Cast " + s.aiming.spell.Name()
+	}
+
+	return "Fire"
+}
+
+// Find furniture next to the player, checking the way they are facing first
+func (s *PlayingState) furnitureDir() (core.Direction, bool) {
+	currTile := s.game.Player().Tile()
+
+	for _, dir := range append([]core.Direction{s.facing}, core.Directions...) {
+		if s.game.Map().AdjacentTile(currTile, dir).Furniture() != nil {
+			return dir, true
 		}
 	}
+
+	return s.facing, false
 }
 
 func (s *PlayingState) Draw(screen *ebiten.Image) {
@@ -288,7 +457,23 @@ func (s *PlayingState) Draw(screen *ebiten.Image) {
 
 	// Draw the status bar, it was at row VP_ROWS-1 but we added a row for the status bar
 	attackStr := fmt.Sprintf("%d%% ↦ %s+%d", p.StatHitChance(), p.StatAttackRoll().String(), p.StatBaseDamage())
-	statusText := fmt.Sprintf("%s    ♥%d/%d   ⌘%d   ⚃%d  ⊗%s", p.Name(), p.HP(), p.MaxHP(), p.Exp(), p.StatDefence(), attackStr)
+	statusText := fmt.Sprintf("%s  L%d  ★%d  ♥%d/%d   ⌘%d/%d   ⚃%d  ⊗%s", p.Name(), s.game.Depth(), p.Level(), p.HP(), p.MaxHP(), p.Exp(), s.game.ExpForNextLevel(), p.StatDefence(), attackStr)
+	if p.MaxMana() > 0 {
+		statusText += fmt.Sprintf("  ♦%d/%d", p.Mana(), p.MaxMana())
+	}
+
+	if p.PendingPerks() > 0 {
+		statusText += "  ⇧perk"
+	}
+
+	if burden := p.Encumbrance(); burden != "" {
+		statusText += "  " + burden
+	}
+
+	if statuses := p.Statuses(); len(statuses) > 0 {
+		statusText += "  " + strings.Join(statuses, " ")
+	}
+
 	graphics.BgColour = graphics.ColourStatus
 	graphics.DrawTextRow(screen, statusText, VP_ROWS)
 
@@ -311,4 +496,33 @@ func (s *PlayingState) Draw(screen *ebiten.Image) {
 		bodyText = strings.Trim(bodyText, "\n")
 		graphics.DrawDialogBox(screen, "Pickup an item, using number keys", bodyText)
 	}
+
+	if s.targeting {
+		s.drawTargeting(screen)
+	}
+}
+
+// Show the path a missile would take and the creature being aimed at
+func (s *PlayingState) drawTargeting(screen *ebiten.Image) {
+	targets := s.game.VisibleHostiles()
+	if s.target >= len(targets) {
+		return
+	}
+
+	target := targets[s.target]
+	size := float32(s.spSize)
+	offsetX := float32(s.viewPort.X * s.spSize)
+	offsetY := float32(s.viewPort.Y * s.spSize)
+
+	for _, pos := range s.game.ProjectilePath(target.Pos(), s.targetRange()) {
+		x, y := float32(pos.X)*size-offsetX, float32(pos.Y)*size-offsetY
+		vector.DrawFilledRect(screen, x+size/2-1, y+size/2-1, 2, 2, graphics.ColourCursor, false)
+	}
+
+	x, y := float32(target.Pos().X)*size-offsetX, float32(target.Pos().Y)*size-offsetY
+	vector.StrokeRect(screen, x, y, size, size, 1, graphics.ColourCursor, false)
+
+	verb := s.targetVerb()
+	graphics.BgColour = graphics.ColourDialog
+	graphics.DrawTextRow(screen, fmt.Sprintf("%s at %s (%d/%d)  ⇥ next  ⏎ %s", verb, target.Name(), s.target+1, len(targets), strings.ToLower(verb)), VP_ROWS-1)
 }
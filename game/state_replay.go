@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"roguelike/engine"
+	"roguelike/game/controls"
+	"roguelike/game/graphics"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Frames to wait between replay steps, the speed controls move through these
+var replaySpeeds = []int{48, 24, 12, 6, 1}
+
+type ReplayState struct {
+	// Neatly encapsulate the state of the game
+	*EbitenGame
+
+	replayer   *engine.Replayer
+	paused     bool
+	speed      int
+	delayFrame int
+}
+
+func (s *ReplayState) Init() {
+}
+
+func (s *ReplayState) PassEvent(e engine.GameEvent) {
+}
+
+// Load a replay file and switch to playing it back
+func (s *ReplayState) Start(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	replay, err := engine.LoadReplay(basePath+"assets/datafiles", data)
+	if err != nil {
+		return err
+	}
+
+	s.events = nil
+	s.eventLog = nil
+	s.replayer = engine.NewReplayer(basePath+"assets/datafiles", replay, s.EventListener)
+	s.game = s.replayer.Game()
+	s.seed = s.game.Seed()
+	s.viewPort = s.game.GetViewPort(VP_COLS, VP_ROWS)
+	s.paused = false
+	s.speed = 2
+	s.state = gameStateReplay
+
+	return nil
+}
+
+func (s *ReplayState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
+	step := false
+
+	for _, key := range tappedKeys {
+		if controls.Select.IsKey(key) {
+			s.paused = !s.paused
+		}
+
+		if controls.Right.IsKey(key) && s.paused {
+			step = true
+		}
+
+		if controls.Up.IsKey(key) {
+			s.speed = min(s.speed+1, len(replaySpeeds)-1)
+		}
+
+		if controls.Down.IsKey(key) {
+			s.speed = max(s.speed-1, 0)
+		}
+
+		if controls.Escape.IsKey(key) {
+			s.game = nil
+			s.replayer = nil
+			s.state = gameStateTitle
+			s.handlers[s.state].Init()
+			return
+		}
+	}
+
+	if !s.paused {
+		s.delayFrame++
+		if s.delayFrame >= replaySpeeds[s.speed] {
+			s.delayFrame = 0
+			step = true
+		}
+	}
+
+	if !step || s.replayer.Done() || s.game.IsOver() {
+		return
+	}
+
+	if err := s.replayer.Step(); err != nil {
+		log.Printf("Replay failed: %s", err)
+		s.paused = true
+		s.flashCount = 2
+		return
+	}
+
+	s.viewPort = s.game.GetViewPort(VP_COLS, VP_ROWS)
+	s.ageEvents()
+}
+
+func (s *ReplayState) Draw(screen *ebiten.Image) {
+	// Drawing the game is exactly the same as when playing
+	s.handlers[gameStatePlaying].Draw(screen)
+
+	done, total := s.replayer.Progress()
+	status := "▶"
+	if s.paused {
+		status = "PAUSED"
+	}
+
+	graphics.FgColour = graphics.ColourWhite
+	graphics.BgColour = graphics.ColourStatus
+	graphics.DrawTextRow(screen, fmt.Sprintf("REPLAY %d/%d %s  speed %d", done, total, status, s.speed+1), VP_ROWS-1)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"roguelike/engine"
+	"roguelike/game/controls"
+	"roguelike/game/graphics"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type SpellbookState struct {
+	// Neatly encapsulate the state of the game
+	*EbitenGame
+
+	// Internal vars for this state
+	cursor int
+	spells []*engine.Spell
+}
+
+func (s *SpellbookState) Init() {
+	s.cursor = 0
+	s.spells = s.game.KnownSpells()
+}
+
+func (s *SpellbookState) PassEvent(e engine.GameEvent) {
+}
+
+func (s *SpellbookState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
+	for _, key := range tappedKeys {
+		if controls.Escape.IsKey(key) || controls.Spellbook.IsKey(key) {
+			s.state = gameStatePlaying
+			return
+		}
+
+		if controls.Up.IsKey(key) && s.cursor > 0 {
+			s.cursor--
+		}
+
+		if controls.Down.IsKey(key) && s.cursor < len(s.spells)-1 {
+			s.cursor++
+		}
+
+		// Cast the spell, going back to the map to pick a target if it needs one
+		if controls.Select.IsKey(key) && len(s.spells) > 0 {
+			spell := s.spells[s.cursor]
+			s.state = gameStatePlaying
+			play := s.handlers[s.state].(*PlayingState)
+
+			action := engine.Action(engine.NewCastAction(spell, nil))
+			if spell.NeedsTarget() {
+				action = play.startTargeting(aim{spell: spell})
+			}
+
+			if action != nil {
+				play.doAction(action)
+			}
+
+			return
+		}
+	}
+}
+
+func (s *SpellbookState) Draw(screen *ebiten.Image) {
+	p := s.game.Player()
+
+	graphics.BgColour = graphics.ColourInv
+	graphics.FgColour = graphics.ColourWhite
+
+	graphics.DrawBox(screen, 0, 1, VP_COLS-2, 2)
+	graphics.DrawBox(screen, 2, 1, VP_COLS-2, VP_ROWS-2)
+
+	graphics.BgColour = graphics.ColourTrans
+	graphics.DrawTextRow(screen, fmt.Sprintf("   Spellbook, mana %d / %d", p.Mana(), p.MaxMana()), 1)
+
+	if len(s.spells) == 0 {
+		graphics.DrawTextRow(screen, "     You don't know any spells, scrolls can teach you some", 3)
+		return
+	}
+
+	for i, spell := range s.spells {
+		graphics.FgColour = graphics.ColourWhite
+		// Greyed out if there isn't enough mana to cast it
+		if spell.Mana() > p.Mana() {
+			graphics.FgColour = color.RGBA{140, 140, 140, 255}
+		}
+
+		graphics.DrawTextRow(screen, fmt.Sprintf("       %-14s %2d mana  %s", spell.Name(), spell.Mana(), spell.DescribeEffects()), i*2+3)
+		graphics.DrawTextRow(screen, "         "+spell.Description(), i*2+4)
+	}
+
+	graphics.FgColour = graphics.ColourCursor
+	graphics.DrawTextRow(screen, "   ⌦", s.cursor*2+3)
+}
@@ -9,6 +9,7 @@ import (
 	"roguelike/engine"
 	"roguelike/game/controls"
 	"roguelike/game/graphics"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -19,10 +20,60 @@ type TitleState struct {
 
 	cursor     int
 	quickStart bool
+
+	// Picking a class is the step after choosing a new game
+	classes      []*engine.Class
+	pickingClass bool
 }
 
 func (s *TitleState) Init() {
 	s.cursor = 0
+	s.pickingClass = false
+}
+
+// Move on to picking a class, if they can't be loaded the game starts with the default class
+func (s *TitleState) pickClass() {
+	classes, err := engine.LoadClasses(basePath + "assets/datafiles")
+	if err != nil {
+		log.Printf("Failed to load classes: %s", err)
+		s.StartNewGame()
+		return
+	}
+
+	s.classes = classes
+	s.pickingClass = true
+	s.cursor = 0
+}
+
+func (s *TitleState) updateClassPicker(tappedKeys []ebiten.Key) {
+	for _, key := range tappedKeys {
+		if controls.Escape.IsKey(key) {
+			s.Init()
+			return
+		}
+
+		if controls.Up.IsKey(key) && s.cursor > 0 {
+			s.cursor--
+		}
+
+		if controls.Down.IsKey(key) && s.cursor < len(s.classes)-1 {
+			s.cursor++
+		}
+
+		if controls.Select.IsKey(key) {
+			s.class = s.classes[s.cursor].ID()
+			s.StartNewGame()
+			return
+		}
+	}
+
+	for i, class := range s.classes {
+		if s.DidTapIn(core.NewRect(0, (i+9)*s.spSize, s.scrWidth, s.spSize)) {
+			s.class = class.ID()
+			s.StartNewGame()
+			return
+		}
+	}
 }
 
 func (s *TitleState) PassEvent(e engine.GameEvent) {
@@ -36,6 +87,11 @@ func (s *TitleState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 		return
 	}
 
+	if s.pickingClass {
+		s.updateClassPicker(tappedKeys)
+		return
+	}
+
 	for _, key := range tappedKeys {
 		if controls.Up.IsKey(key) {
 			s.cursor--
@@ -56,13 +112,18 @@ func (s *TitleState) Update(heldKeys []ebiten.Key, tappedKeys []ebiten.Key) {
 		if controls.Select.IsKey(key) {
 			switch s.cursor {
 			case 0:
-				s.StartNewGame()
+				s.pickClass()
+			case 1:
+				if err := s.LoadSavedGame(); err != nil {
+					log.Printf("Failed to load saved game: %s", err)
+					s.flashCount = 2
+				}
 			}
 		}
 	}
 
 	if newGameRect := core.NewRect(120, 9*s.spSize, 60, s.spSize); s.DidTapIn(newGameRect) {
-		s.StartNewGame()
+		s.pickClass()
 	}
 }
 
@@ -78,6 +139,11 @@ func (s *TitleState) Draw(screen *ebiten.Image) {
 	graphics.BgColour = graphics.ColourTrans
 	graphics.DrawTextRow(screen, fmt.Sprintf("%sGo WASM Roguelike", core.MakeStr(17, " ")), 6)
 
+	if s.pickingClass {
+		s.drawClassPicker(screen)
+		return
+	}
+
 	graphics.DrawTextRow(screen, fmt.Sprintf("%sNEW GAME", core.MakeStr(20, " ")), 9)
 	graphics.DrawTextRow(screen, fmt.Sprintf("%sLOAD GAME", core.MakeStr(20, " ")), 10)
 	graphics.DrawTextRow(screen, fmt.Sprintf("%sQUIT", core.MakeStr(20, " ")), 11)
@@ -96,3 +162,16 @@ func (s *TitleState) Draw(screen *ebiten.Image) {
 	graphics.FgColour = graphics.ColourCursor
 	graphics.DrawTextRow(screen, fmt.Sprintf("%s⌦", core.MakeStr(18, " ")), s.cursor+9)
 }
+
+func (s *TitleState) drawClassPicker(screen *ebiten.Image) {
+	graphics.DrawTextRow(screen, fmt.Sprintf("%sCHOOSE A CLASS", core.MakeStr(17, " ")), 8)
+
+	for i, class := range s.classes {
+		graphics.DrawTextRow(screen, fmt.Sprintf("%s%s", core.MakeStr(20, " "), strings.ToUpper(class.Name())), i+9)
+	}
+
+	graphics.DrawWrappedText(screen, s.classes[s.cursor].Description(), len(s.classes)+10, 6, VP_COLS+18)
+
+	graphics.FgColour = graphics.ColourCursor
+	graphics.DrawTextRow(screen, fmt.Sprintf("%s⌦", core.MakeStr(18, " ")), s.cursor+9)
+}
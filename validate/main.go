@@ -0,0 +1,30 @@
+package main
+
+// ============================================================================
+// Checks the game datafiles for mistakes, run this before committing changes
+// to them. Every problem is listed with its file, line and key
+// ============================================================================
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"roguelike/engine"
+)
+
+func main() {
+	assetsDir := flag.String("assets", "assets", "Directory with the datafiles, sprites & palettes")
+	flag.Parse()
+
+	errors := engine.ValidateDataFiles(*assetsDir)
+	for _, err := range errors {
+		fmt.Println(err)
+	}
+
+	if len(errors) > 0 {
+		fmt.Printf("Found %d problems in the datafiles\n", len(errors))
+		os.Exit(1)
+	}
+
+	fmt.Println("All datafiles are OK")
+}